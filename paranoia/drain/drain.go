@@ -0,0 +1,148 @@
+package drain
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AbortGrace is the time handlers get to return after their contexts are canceled
+const AbortGrace = time.Second
+
+// Drain tracks the in-flight work of a server for a graceful stop:
+// readiness fails, new work is rejected, in-flight work is waited for up to a deadline and then canceled.
+type Drain struct {
+	notReady atomic.Bool
+	closed   atomic.Bool
+
+	mu       sync.Mutex
+	inFlight int64
+	idle     chan struct{} // closed when in-flight work reaches zero after Close
+
+	abort       context.Context
+	cancelAbort context.CancelFunc
+	aborted     atomic.Int64
+}
+
+func New() *Drain {
+	t := &Drain{}
+	t.abort, t.cancelAbort = context.WithCancel(context.Background())
+
+	return t
+}
+
+// Begin registers a unit of work. It returns the context of the work, canceled when the drain deadline passes,
+// and the func to call when the work is done. ok is false once the drain is closed, the work must be rejected.
+func (t *Drain) Begin(c context.Context) (context.Context, func(), bool) {
+	t.mu.Lock()
+
+	if t.closed.Load() {
+		t.mu.Unlock()
+		return c, func() {}, false
+	}
+
+	t.inFlight++
+	t.mu.Unlock()
+
+	c, cancel := context.WithCancel(c)
+	stop := context.AfterFunc(t.abort, cancel)
+
+	return c, func() {
+		stop()
+		cancel()
+
+		t.mu.Lock()
+		t.inFlight--
+
+		if t.inFlight == 0 && t.idle != nil {
+			close(t.idle)
+			t.idle = nil
+		}
+
+		t.mu.Unlock()
+	}, true
+}
+
+// Aborted reports whether c was canceled by the drain deadline
+func (t *Drain) Aborted(c context.Context) bool {
+	return c.Err() != nil && t.abort.Err() != nil
+}
+
+// Ready is false once the drain starts, the server must not get new work from load balancers
+func (t *Drain) Ready() bool {
+	return !t.notReady.Load() && !t.closed.Load()
+}
+
+// SetNotReady fails readiness while the work is still accepted, e.g. for the load balancer to notice
+func (t *Drain) SetNotReady() {
+	t.notReady.Store(true)
+}
+
+// Close stops accepting work, Begin returns false afterwards
+func (t *Drain) Close() {
+	t.mu.Lock()
+	t.closed.Store(true)
+	t.mu.Unlock()
+}
+
+// InFlight returns the number of running units of work
+func (t *Drain) InFlight() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.inFlight
+}
+
+// Wait closes the drain and waits for the in-flight work until c is done. The remaining work is counted
+// as aborted, its contexts are canceled and it gets AbortGrace to return. It returns the aborted count.
+func (t *Drain) Wait(c context.Context) int64 {
+	t.Close()
+
+	if t.wait(c) {
+		return 0
+	}
+
+	t.mu.Lock()
+	aborted := t.inFlight
+	t.mu.Unlock()
+
+	t.aborted.Add(aborted)
+	t.cancelAbort()
+
+	grace, cancel := context.WithTimeout(context.Background(), AbortGrace)
+	defer cancel()
+
+	t.wait(grace)
+
+	return aborted
+}
+
+// AbortedCount returns the number of units of work aborted by Wait
+func (t *Drain) AbortedCount() int64 {
+	return t.aborted.Load()
+}
+
+// wait returns true when no work is in flight before c is done
+func (t *Drain) wait(c context.Context) bool {
+	t.mu.Lock()
+
+	if t.inFlight == 0 {
+		t.mu.Unlock()
+		return true
+	}
+
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-c.Done():
+		return false
+	}
+}
@@ -0,0 +1,59 @@
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain_Wait(t *testing.T) {
+	d := New()
+	assert.True(t, d.Ready())
+
+	_, fast, ok := d.Begin(context.Background())
+	assert.True(t, ok)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fast()
+	}()
+
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.Equal(t, int64(0), d.Wait(c))
+	assert.False(t, d.Ready())
+
+	_, _, ok = d.Begin(context.Background())
+	assert.False(t, ok, "work is rejected after close")
+}
+
+func TestDrain_Abort(t *testing.T) {
+	d := New()
+
+	d.SetNotReady()
+	assert.False(t, d.Ready())
+
+	work, done, ok := d.Begin(context.Background())
+	assert.True(t, ok, "work is accepted until close")
+
+	returned := make(chan struct{})
+
+	go func() {
+		<-work.Done()
+		assert.True(t, d.Aborted(work))
+		done()
+		close(returned)
+	}()
+
+	c, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, int64(1), d.Wait(c))
+	assert.Equal(t, int64(1), d.AbortedCount())
+	assert.Equal(t, int64(0), d.InFlight())
+
+	<-returned
+}
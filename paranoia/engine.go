@@ -5,18 +5,24 @@ import (
 	"fmt"
 	"gitlab.com/devpro_studio/Paranoia/paranoia/config/yaml"
 	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/logger"
 	"gitlab.com/devpro_studio/Paranoia/paranoia/telemetry"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sync/atomic"
 )
 
 type Engine struct {
 	name string
 
-	starting bool
+	starting atomic.Bool
+	stopping atomic.Bool
 
 	config         interfaces.IConfig
 	logger         interfaces.ILogger
 	metricExporter interfaces.IMetrics
 	trace          interfaces.ITrace
+	diagnostics    interfaces.IDiagnostics
 
 	task task
 
@@ -28,7 +34,6 @@ type Engine struct {
 func New(name string, configName string) *Engine {
 	t := &Engine{}
 
-	t.starting = false
 	t.name = name
 	t.config = yaml.New(yaml.AutoConfig{FName: configName})
 
@@ -74,6 +79,22 @@ func (t *Engine) SetTrace(c interfaces.ITrace) {
 	t.trace = c
 }
 
+func (t *Engine) SetDiagnostics(c interfaces.IDiagnostics) {
+	if t.diagnostics != nil {
+		_ = t.diagnostics.Stop()
+	}
+
+	t.diagnostics = c
+}
+
+func (t *Engine) Meter(module string) interfaces.IMeter {
+	return telemetry.NewAppMeter(t.name, module)
+}
+
+func (t *Engine) Tracer(module string) trace.Tracer {
+	return telemetry.NewAppTracer(t.name, module)
+}
+
 func (t *Engine) PushPkg(c interfaces.IPkg) interfaces.IEngine {
 	if c == nil {
 		panic("nil package")
@@ -97,6 +118,11 @@ func (t *Engine) PushPkg(c interfaces.IPkg) interfaces.IEngine {
 			return t
 		}
 
+		if _, ok := c.(interfaces.ILogStage); ok {
+			t.pushLogStage(convertedLogger)
+			return t
+		}
+
 		for {
 			if l.Parent() == nil {
 				break
@@ -122,6 +148,34 @@ func (t *Engine) PushPkg(c interfaces.IPkg) interfaces.IEngine {
 	return t
 }
 
+// pushLogStage inserts a stage after the stages already at the head of the logger chain
+func (t *Engine) pushLogStage(stage interfaces.ILogger) {
+	if _, ok := t.logger.(interfaces.ILogStage); !ok {
+		stage.SetParent(t.logger)
+		t.logger = stage
+		return
+	}
+
+	l := t.logger
+
+	for {
+		next, ok := l.Parent().(interfaces.ILogger)
+
+		if !ok || next == nil {
+			break
+		}
+
+		if _, ok := next.(interfaces.ILogStage); !ok {
+			stage.SetParent(next)
+			break
+		}
+
+		l = next
+	}
+
+	l.SetParent(stage)
+}
+
 func (t *Engine) GetPkg(typePkg string, key string) interfaces.IPkg {
 	if p, ok := t.pkg[typePkg]; ok {
 		if pkg, ok := p[key]; ok {
@@ -191,7 +245,7 @@ func (t *Engine) GetTask(key string) interfaces.ITask {
 
 func (t *Engine) PushTask(b interfaces.ITask) interfaces.IEngine {
 
-	t.task.PushTask(b, t.starting)
+	t.task.PushTask(b, t.starting.Load())
 
 	return t
 }
@@ -222,6 +276,20 @@ func (t *Engine) Init() error {
 		}
 	}
 
+	if t.logger != nil {
+		// libraries logging through slog (database and broker drivers) write into the logger chain
+		slog.SetDefault(slog.New(logger.NewSlogHandler(t.logger, nil)))
+	}
+
+	if cfg := t.config.GetConfigItem("log_levels", ""); len(cfg) > 0 {
+		err = logger.ConfigureLevels(cfg)
+
+		if err != nil {
+			t.logger.Fatal(context.Background(), fmt.Errorf("failed to init log levels: %w", err))
+			return err
+		}
+	}
+
 	if t.metricExporter == nil {
 		cfg := t.config.GetConfigItem("metrics", "")
 
@@ -237,6 +305,13 @@ func (t *Engine) Init() error {
 			t.logger.Fatal(context.Background(), fmt.Errorf("failed to init metrics %s: %w", t.metricExporter.Name(), err))
 			return err
 		}
+
+		err = telemetry.StartRuntimeMetrics()
+
+		if err != nil {
+			t.logger.Fatal(context.Background(), fmt.Errorf("failed to start runtime metrics: %w", err))
+			return err
+		}
 	}
 
 	if t.trace == nil {
@@ -290,6 +365,23 @@ func (t *Engine) Init() error {
 		}
 	}
 
+	if t.diagnostics == nil {
+		cfg := t.config.GetConfigItem("diagnostics", "")
+
+		if len(cfg) > 0 {
+			t.diagnostics = telemetry.NewDiagnostics(cfg)
+		}
+	}
+
+	if t.diagnostics != nil {
+		err = t.diagnostics.Init(t, t.config.GetConfigItem("diagnostics", t.diagnostics.Name()))
+
+		if err != nil {
+			t.logger.Fatal(context.Background(), fmt.Errorf("failed to init diagnostics %s: %w", t.diagnostics.Name(), err))
+			return err
+		}
+	}
+
 	t.task.Start()
 
 	if servers, ok := t.pkg[interfaces.PkgServer]; ok {
@@ -321,15 +413,41 @@ func (t *Engine) Init() error {
 		}
 	}
 
-	t.starting = true
+	if t.diagnostics != nil {
+		err = t.diagnostics.Start()
+
+		if err != nil {
+			t.logger.Fatal(context.Background(), fmt.Errorf("failed to start diagnostics %s: %w", t.diagnostics.Name(), err))
+			return err
+		}
+	}
+
+	t.starting.Store(true)
 
 	return err
 }
 
+// Ready reports whether the engine is started, not stopping and the servers implementing IReadiness are ready
+func (t *Engine) Ready() bool {
+	if !t.starting.Load() || t.stopping.Load() {
+		return false
+	}
+
+	for _, server := range t.pkg[interfaces.PkgServer] {
+		if r, ok := server.(interfaces.IReadiness); ok && !r.Ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (t *Engine) Stop() error {
 	var err error = nil
 
-	t.starting = false
+	// readiness fails before the servers start draining
+	t.stopping.Store(true)
+	t.starting.Store(false)
 
 	if servers, ok := t.pkg[interfaces.PkgServer]; ok {
 		for _, server := range servers {
@@ -378,6 +496,15 @@ func (t *Engine) Stop() error {
 		}
 	}
 
+	if t.diagnostics != nil {
+		err = t.diagnostics.Stop()
+
+		if err != nil {
+			t.logger.Fatal(context.Background(), fmt.Errorf("failed to stop diagnostics %s: %w", t.diagnostics.Name(), err))
+			return err
+		}
+	}
+
 	if t.metricExporter != nil {
 		err = t.metricExporter.Stop()
 
@@ -0,0 +1,60 @@
+package paranoia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+)
+
+type chainLogger struct {
+	name   string
+	parent interfaces.ILogger
+}
+
+func (t *chainLogger) Init(map[string]interface{}) error       { return nil }
+func (t *chainLogger) Stop() error                             { return nil }
+func (t *chainLogger) Name() string                            { return t.name }
+func (t *chainLogger) Type() string                            { return interfaces.PkgLogger }
+func (t *chainLogger) Debug(context.Context, ...interface{})   {}
+func (t *chainLogger) Info(context.Context, ...interface{})    {}
+func (t *chainLogger) Warn(context.Context, ...interface{})    {}
+func (t *chainLogger) Message(context.Context, ...interface{}) {}
+func (t *chainLogger) Error(context.Context, error)            {}
+func (t *chainLogger) Fatal(context.Context, error)            {}
+func (t *chainLogger) Panic(context.Context, error)            {}
+func (t *chainLogger) SetParent(parent interface{})            { t.parent = parent.(interfaces.ILogger) }
+func (t *chainLogger) Parent() interface{} {
+	if t.parent == nil {
+		return nil
+	}
+
+	return t.parent
+}
+
+type chainStage struct {
+	chainLogger
+}
+
+func (t *chainStage) LogStage() {}
+
+func TestEngine_PushLogStage(t *testing.T) {
+	app := &Engine{}
+
+	app.PushPkg(&chainLogger{name: "std"})
+	app.PushPkg(&chainStage{chainLogger{name: "redact"}})
+	app.PushPkg(&chainLogger{name: "sentry"})
+	app.PushPkg(&chainStage{chainLogger{name: "sample"}})
+
+	var names []string
+
+	for l := app.GetLogger(); l != nil; {
+		names = append(names, l.Name())
+
+		p, _ := l.Parent().(interfaces.ILogger)
+		l = p
+	}
+
+	assert.Equal(t, []string{"redact", "sample", "std", "sentry"}, names)
+}
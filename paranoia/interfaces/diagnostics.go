@@ -0,0 +1,8 @@
+package interfaces
+
+type IDiagnostics interface {
+	Init(app IEngine, cfg map[string]interface{}) error
+	Start() error
+	Stop() error
+	Name() string
+}
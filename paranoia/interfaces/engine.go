@@ -1,5 +1,7 @@
 package interfaces
 
+import "go.opentelemetry.io/otel/trace"
+
 const (
 	PkgCache      = "cache"
 	PkgDatabase   = "database"
@@ -23,10 +25,18 @@ const (
 type IEngine interface {
 	Init() error
 	Stop() error
+	// Ready reports whether the engine is started, not stopping and its servers are ready
+	Ready() bool
 	GetLogger() ILogger
 	GetConfig() IConfig
 	SetMetrics(c IMetrics)
 	SetTrace(c ITrace)
+	SetDiagnostics(c IDiagnostics)
+
+	// Meter returns a meter scoped to the service and the given module name
+	Meter(module string) IMeter
+	// Tracer returns a tracer scoped to the service and the given module name
+	Tracer(module string) trace.Tracer
 
 	PushPkg(c IPkg) IEngine
 	GetPkg(typePkg string, key string) IPkg
@@ -19,3 +19,10 @@ type ILogger interface {
 	Parent() interface{}
 	SetParent(interface{})
 }
+
+// ILogStage is a logger transforming or filtering records for the loggers after it (sampling, redaction).
+// The engine keeps stages at the head of the chain, ahead of the output loggers.
+type ILogStage interface {
+	ILogger
+	LogStage()
+}
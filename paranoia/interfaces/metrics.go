@@ -1,8 +1,51 @@
 package interfaces
 
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
 type IMetrics interface {
 	Init(cfg map[string]interface{}) error
 	Start() error
 	Stop() error
 	Name() string
 }
+
+// IMeter is an OpenTelemetry meter scoped to the service and module: instrument names
+// are prefixed with "<service>.<module>." and carry service, instance and module attributes.
+type IMeter interface {
+	metric.Meter
+
+	// Timer creates a histogram recording operation durations in seconds
+	Timer(name string, options ...metric.Float64HistogramOption) (ITimer, error)
+
+	// ErrorCounter creates a counter of errors grouped by their kind
+	ErrorCounter(name string, options ...metric.Int64CounterOption) (IErrorCounter, error)
+
+	// Gauge registers a gauge whose value is read from callback on every collection
+	Gauge(name string, callback func(ctx context.Context) int64, options ...metric.Int64ObservableGaugeOption) error
+}
+
+type ITimer interface {
+	// Start begins timing an operation, the returned function records its duration
+	Start(ctx context.Context, attrs ...attribute.KeyValue) func()
+
+	// Time runs fn and records its duration with an "error" attribute set when fn fails
+	Time(ctx context.Context, fn func(ctx context.Context) error, attrs ...attribute.KeyValue) error
+
+	// Record records an already measured duration
+	Record(ctx context.Context, d time.Duration, attrs ...attribute.KeyValue)
+}
+
+type IErrorCounter interface {
+	// Add counts err under its kind: the Kind() of the first error in the chain
+	// implementing it, otherwise the type of the innermost wrapped error
+	Add(ctx context.Context, err error, attrs ...attribute.KeyValue)
+
+	// AddKind counts an error of an explicit kind
+	AddKind(ctx context.Context, kind string, attrs ...attribute.KeyValue)
+}
@@ -7,3 +7,8 @@ type IServer interface {
 	Name() string
 	Type() string
 }
+
+// IReadiness is implemented by servers reporting whether they take new work, it fails while they drain on stop
+type IReadiness interface {
+	Ready() bool
+}
@@ -0,0 +1,323 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// Config describes one listener of a server
+type Config struct {
+	Name    string `yaml:"name"`    // used to restrict routes, default the network and address
+	Network string `yaml:"network"` // tcp, tcp4, tcp6, unix or systemd, default tcp
+	Address string `yaml:"address"` // host:port, [::1]:port, socket path or systemd socket name (LISTEN_FDNAMES) or index
+	Mode    string `yaml:"mode"`    // octal file mode of a unix socket, e.g. 0660
+}
+
+// Listener is a named listener, its connections carry the name
+type Listener struct {
+	net.Listener
+	Name string
+}
+
+// Conn is an accepted connection of a named Listener
+type Conn struct {
+	net.Conn
+	Listener string
+}
+
+func (t *Listener) Accept() (net.Conn, error) {
+	conn, err := t.Listener.Accept()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{Conn: conn, Listener: t.Name}, nil
+}
+
+// Pop removes the listeners key from a server config and decodes its items
+func Pop(cfg map[string]interface{}) ([]Config, error) {
+	v, ok := cfg["listeners"]
+
+	if !ok {
+		return nil, nil
+	}
+
+	delete(cfg, "listeners")
+
+	switch items := v.(type) {
+	case nil:
+		return nil, nil
+
+	case []Config:
+		return items, nil
+
+	case []map[string]interface{}:
+		res := make([]Config, len(items))
+
+		for i, item := range items {
+			if err := decode.Decode(item, &res[i], "yaml", decode.DecoderStrongFoundDst); err != nil {
+				return nil, fmt.Errorf("listeners[%d]: %w", i, err)
+			}
+		}
+
+		return res, nil
+
+	case []interface{}:
+		res := make([]Config, len(items))
+
+		for i, item := range items {
+			if err := decode.Decode(item, &res[i], "yaml", decode.DecoderStrongFoundDst); err != nil {
+				return nil, fmt.Errorf("listeners[%d]: %w", i, err)
+			}
+		}
+
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("listeners: unexpected type %T", v)
+}
+
+// Defaults returns cfg or the single TCP listener of port when cfg is empty
+func Defaults(cfg []Config, port string) []Config {
+	if len(cfg) > 0 {
+		return cfg
+	}
+
+	return []Config{{Name: "default", Network: "tcp", Address: ":" + port}}
+}
+
+// Listen opens the listener described by cfg
+func Listen(cfg Config) (*Listener, error) {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = cfg.Network + ":" + cfg.Address
+	}
+
+	var l net.Listener
+	var err error
+
+	switch cfg.Network {
+	case "tcp", "tcp4", "tcp6":
+		l, err = net.Listen(cfg.Network, cfg.Address)
+
+	case "unix":
+		l, err = listenUnix(cfg)
+
+	case "systemd":
+		l, err = listenSystemd(cfg.Address)
+
+	default:
+		err = fmt.Errorf("unknown network %q", cfg.Network)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", cfg.Name, err)
+	}
+
+	return &Listener{Listener: l, Name: cfg.Name}, nil
+}
+
+// ListenAll opens all listeners, the opened ones are closed when one fails
+func ListenAll(cfg []Config) ([]*Listener, error) {
+	res := make([]*Listener, 0, len(cfg))
+	names := make(map[string]bool, len(cfg))
+
+	for _, c := range cfg {
+		l, err := Listen(c)
+
+		if err == nil && names[l.Name] {
+			_ = l.Close()
+			err = fmt.Errorf("listener %s: duplicate name", l.Name)
+		}
+
+		if err != nil {
+			for _, v := range res {
+				_ = v.Close()
+			}
+
+			return nil, err
+		}
+
+		names[l.Name] = true
+		res = append(res, l)
+	}
+
+	return res, nil
+}
+
+func listenUnix(cfg Config) (net.Listener, error) {
+	// a socket left by a previous process blocks the bind
+	if fi, err := os.Lstat(cfg.Address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", cfg.Address); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("socket %s is in use", cfg.Address)
+		}
+
+		_ = os.Remove(cfg.Address)
+	}
+
+	l, err := net.Listen("unix", cfg.Address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Mode != "" {
+		mode, err := strconv.ParseUint(cfg.Mode, 8, 32)
+
+		if err == nil {
+			err = os.Chmod(cfg.Address, os.FileMode(mode))
+		}
+
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("mode %s: %w", cfg.Mode, err)
+		}
+	}
+
+	return l, nil
+}
+
+// systemd passes sockets as descriptors from 3 on, they can be taken only once per process.
+// The descriptors are duplicated by net.FileListener and the originals closed.
+var systemd struct {
+	once  sync.Once
+	files []*os.File
+	names []string
+	used  map[int]bool
+	mu    sync.Mutex
+}
+
+const listenFdsStart = 3
+
+func systemdFiles() {
+	systemd.used = make(map[int]bool)
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+
+	if err != nil || n <= 0 {
+		return
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		name := ""
+
+		if i < len(names) {
+			name = names[i]
+		}
+
+		systemd.files = append(systemd.files, os.NewFile(uintptr(fd), name))
+		systemd.names = append(systemd.names, name)
+	}
+
+	// child processes must not take the sockets
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+}
+
+func listenSystemd(address string) (net.Listener, error) {
+	systemd.once.Do(systemdFiles)
+
+	systemd.mu.Lock()
+	defer systemd.mu.Unlock()
+
+	if len(systemd.files) == 0 {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+
+	idx := -1
+	named := false
+
+	for i, name := range systemd.names {
+		if name == address && address != "" {
+			named = true
+
+			if !systemd.used[i] {
+				idx = i
+				break
+			}
+		}
+	}
+
+	if idx == -1 && !named {
+		if address == "" {
+			address = "0"
+		}
+
+		if i, err := strconv.Atoi(address); err == nil && i >= 0 && i < len(systemd.files) {
+			idx = i
+		}
+	}
+
+	if idx == -1 && !named {
+		return nil, fmt.Errorf("systemd socket %q not found", address)
+	}
+
+	if idx == -1 || systemd.used[idx] {
+		return nil, fmt.Errorf("systemd socket %q is already used", address)
+	}
+
+	l, err := net.FileListener(systemd.files[idx])
+
+	if err != nil {
+		return nil, err
+	}
+
+	systemd.used[idx] = true
+	_ = systemd.files[idx].Close()
+
+	return l, nil
+}
+
+// NameOf returns the listener name of a connection accepted by a Listener, TLS connections are unwrapped
+func NameOf(conn net.Conn) string {
+	for conn != nil {
+		if c, ok := conn.(*Conn); ok {
+			return c.Listener
+		}
+
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+
+		if !ok {
+			return ""
+		}
+
+		conn = nc.NetConn()
+	}
+
+	return ""
+}
+
+type contextKey struct{}
+
+// WithName returns a context carrying the listener name
+func WithName(c context.Context, name string) context.Context {
+	return context.WithValue(c, contextKey{}, name)
+}
+
+// FromContext returns the listener name carried by c, "" without one
+func FromContext(c context.Context) string {
+	name, _ := c.Value(contextKey{}).(string)
+
+	return name
+}
@@ -0,0 +1,109 @@
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenAll(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "admin.sock")
+
+	listeners, err := ListenAll([]Config{
+		{Name: "public", Address: "127.0.0.1:0"},
+		{Name: "admin", Network: "unix", Address: sock, Mode: "0600"},
+		{Network: "tcp6", Address: "[::1]:0"},
+	})
+
+	if err != nil && len(listeners) == 0 {
+		// hosts without IPv6
+		listeners, err = ListenAll([]Config{
+			{Name: "public", Address: "127.0.0.1:0"},
+			{Name: "admin", Network: "unix", Address: sock, Mode: "0600"},
+		})
+	}
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+
+	fi, err := os.Stat(sock)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	}
+
+	for _, l := range listeners {
+		go func(l *Listener) {
+			conn, err := net.Dial(l.Addr().Network(), l.Addr().String())
+
+			if err == nil {
+				_ = conn.Close()
+			}
+		}(l)
+
+		conn, err := l.Accept()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, l.Name, NameOf(conn))
+			assert.Equal(t, l.Name, NameOf(tls.Server(conn, &tls.Config{})))
+			_ = conn.Close()
+		}
+	}
+
+	if len(listeners) == 3 {
+		assert.Equal(t, "tcp6:[::1]:0", listeners[2].Name)
+	}
+}
+
+func TestListenAll_Errors(t *testing.T) {
+	busy, err := Listen(Config{Address: "127.0.0.1:0"})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer busy.Close()
+
+	sock := filepath.Join(t.TempDir(), "stale.sock")
+	stale, _ := net.Listen("unix", sock)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	tests := map[string][]Config{
+		"duplicate name":  {{Name: "a", Address: "127.0.0.1:0"}, {Name: "a", Address: "127.0.0.1:0"}},
+		"address in use":  {{Name: "a", Address: "127.0.0.1:0"}, {Address: busy.Addr().String()}},
+		"unknown network": {{Network: "udp", Address: ":0"}},
+		"invalid mode":    {{Network: "unix", Address: filepath.Join(t.TempDir(), "m.sock"), Mode: "rw"}},
+		"without systemd": {{Network: "systemd", Address: "http"}},
+	}
+
+	for name, cfg := range tests {
+		_, err := ListenAll(cfg)
+		assert.Error(t, err, name)
+	}
+
+	// a socket file without a process is replaced
+	l, err := Listen(Config{Network: "unix", Address: sock})
+
+	if assert.NoError(t, err) {
+		_ = l.Close()
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+	assert.Equal(t, "admin", FromContext(WithName(context.Background(), "admin")))
+	assert.Equal(t, []Config{{Name: "default", Network: "tcp", Address: ":80"}}, Defaults(nil, "80"))
+}
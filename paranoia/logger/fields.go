@@ -0,0 +1,54 @@
+package logger
+
+import "context"
+
+// FieldsKey is the context key holding structured log fields as alternating key/value pairs.
+// Logger packages read it by this string key, so they do not depend on this package.
+const FieldsKey = "log_fields"
+
+// Field is a structured attribute passed among the args of Debug, Info, Warn and Message.
+// Logger packages recognise it by the LogField method and exclude it from the message text.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F creates a structured field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+func (t Field) LogField() (string, interface{}) {
+	return t.Key, t.Value
+}
+
+// WithFields returns a copy of ctx carrying kv in addition to the fields already in it.
+func WithFields(ctx context.Context, kv ...interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	prev := Fields(ctx)
+	fields := make([]interface{}, 0, len(prev)+len(kv))
+	fields = append(fields, prev...)
+	fields = append(fields, kv...)
+
+	return context.WithValue(ctx, FieldsKey, fields)
+}
+
+// Fields returns the key/value pairs carried in ctx
+func Fields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	if fields, ok := ctx.Value(FieldsKey).([]interface{}); ok {
+		return fields
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels of the logger packages
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarning
+	LevelMessage
+	LevelError
+	LevelCritical
+)
+
+// LevelKey is the context key holding the level override of a named logger.
+// Logger packages use it instead of their configured level, the configured level stays the global default.
+const LevelKey = "log_level"
+
+var levelNames = []string{"DEBUG", "INFO", "WARNING", "MESSAGE", "ERROR", "CRITICAL"}
+
+// ParseLevel converts a level name (debug, info, warning, message, error, critical) to its value
+func ParseLevel(s string) (int, error) {
+	s = strings.ToUpper(s)
+
+	if s == "WARN" {
+		s = "WARNING"
+	}
+
+	for i, name := range levelNames {
+		if name == s {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level: %s", s)
+}
+
+// LevelName returns the name of a level value
+func LevelName(level int) string {
+	if level < 0 || level >= len(levelNames) {
+		return ""
+	}
+
+	return levelNames[level]
+}
+
+type levelOverride struct {
+	level   int
+	expires time.Time      // zero for permanent overrides
+	prev    *levelOverride // restored when the override expires
+}
+
+// LevelOverride describes an active override of a logger name
+type LevelOverride struct {
+	Level   string    `json:"level"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+var levels = struct {
+	sync.RWMutex
+	items map[string]*levelOverride
+}{items: map[string]*levelOverride{}}
+
+// active returns the override in effect for exactly name, dropping expired ones
+func active(o *levelOverride, now time.Time) *levelOverride {
+	for o != nil && !o.expires.IsZero() && now.After(o.expires) {
+		o = o.prev
+	}
+
+	return o
+}
+
+// SetLevel overrides the level of the named logger and its children.
+// A positive ttl reverts the override to the previous state once expired.
+func SetLevel(name string, level int, ttl time.Duration) {
+	levels.Lock()
+	defer levels.Unlock()
+
+	o := &levelOverride{level: level}
+
+	if ttl > 0 {
+		o.expires = time.Now().Add(ttl)
+		o.prev = active(levels.items[name], time.Now())
+	}
+
+	levels.items[name] = o
+}
+
+// RemoveLevel removes the override of name, its children fall back to the closest parent override
+func RemoveLevel(name string) {
+	levels.Lock()
+	defer levels.Unlock()
+
+	delete(levels.items, name)
+}
+
+// LevelFor returns the override for a logger name: its own or the one of the closest parent,
+// "repository.orders" inherits from "repository".
+func LevelFor(name string) (int, bool) {
+	levels.RLock()
+	defer levels.RUnlock()
+
+	if len(levels.items) == 0 {
+		return 0, false
+	}
+
+	now := time.Now()
+
+	for {
+		if o := active(levels.items[name], now); o != nil {
+			return o.level, true
+		}
+
+		i := strings.LastIndexByte(name, '.')
+
+		if i < 0 {
+			return 0, false
+		}
+
+		name = name[:i]
+	}
+}
+
+// LevelOverrides returns the active overrides by logger name
+func LevelOverrides() map[string]LevelOverride {
+	levels.Lock()
+	defer levels.Unlock()
+
+	now := time.Now()
+	res := make(map[string]LevelOverride, len(levels.items))
+
+	for name, o := range levels.items {
+		o = active(o, now)
+
+		if o == nil {
+			delete(levels.items, name)
+			continue
+		}
+
+		levels.items[name] = o
+		res[name] = LevelOverride{Level: LevelName(o.level), Expires: o.expires}
+	}
+
+	return res
+}
+
+// ConfigureLevels applies the "levels" map of a log_levels config item:
+//
+//	engine:
+//	  - type: log_levels
+//	    name: levels
+//	    levels:
+//	      repository: warning
+//	      repository.orders: debug
+func ConfigureLevels(cfg map[string]interface{}) error {
+	raw, ok := cfg["levels"]
+
+	if !ok {
+		return nil
+	}
+
+	// read by hand, decode treats dots in keys as nesting
+	items, ok := raw.(map[string]interface{})
+
+	if !ok {
+		return fmt.Errorf("log levels must be a map of logger name to level")
+	}
+
+	for name, value := range items {
+		level, err := ParseLevel(fmt.Sprint(value))
+		if err != nil {
+			return fmt.Errorf("log level %s: %w", name, err)
+		}
+
+		SetLevel(name, level, 0)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// LevelsHandler is the admin endpoint for level overrides:
+//
+//	GET                                        - list active overrides
+//	PUT ?name=repository.orders&level=debug&ttl=10m - set an override, ttl is optional
+//	DELETE ?name=repository.orders             - remove an override
+func LevelsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		name := query.Get("name")
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPut, http.MethodPost:
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+
+			level, err := ParseLevel(query.Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var ttl time.Duration
+
+			if s := query.Get("ttl"); s != "" {
+				ttl, err = time.ParseDuration(s)
+				if err != nil || ttl < 0 {
+					http.Error(w, "invalid ttl", http.StatusBadRequest)
+					return
+				}
+			}
+
+			SetLevel(name, level, ttl)
+
+		case http.MethodDelete:
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+
+			RemoveLevel(name)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(LevelOverrides())
+	})
+}
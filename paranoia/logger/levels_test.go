@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetLevels() {
+	levels.Lock()
+	levels.items = map[string]*levelOverride{}
+	levels.Unlock()
+}
+
+func TestLevelFor(t *testing.T) {
+	resetLevels()
+	defer resetLevels()
+
+	assert.NoError(t, ConfigureLevels(map[string]interface{}{
+		"type": "log_levels",
+		"name": "levels",
+		"levels": map[string]interface{}{
+			"repository":        "warning",
+			"repository.orders": "debug",
+		},
+	}))
+
+	level, ok := LevelFor("repository.orders.cache")
+	assert.True(t, ok)
+	assert.Equal(t, LevelDebug, level)
+
+	level, ok = LevelFor("repository.users")
+	assert.True(t, ok)
+	assert.Equal(t, LevelWarning, level)
+
+	_, ok = LevelFor("service")
+	assert.False(t, ok)
+
+	assert.Error(t, ConfigureLevels(map[string]interface{}{"levels": map[string]interface{}{"a": "verbose"}}))
+}
+
+func TestSetLevel_TTL(t *testing.T) {
+	resetLevels()
+	defer resetLevels()
+
+	SetLevel("repository", LevelError, 0)
+	SetLevel("repository", LevelDebug, 50*time.Millisecond)
+
+	level, _ := LevelFor("repository")
+	assert.Equal(t, LevelDebug, level)
+
+	time.Sleep(60 * time.Millisecond)
+
+	level, _ = LevelFor("repository")
+	assert.Equal(t, LevelError, level)
+
+	SetLevel("service", LevelDebug, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := LevelFor("service")
+	assert.False(t, ok)
+	assert.NotContains(t, LevelOverrides(), "service")
+}
+
+func TestNamed(t *testing.T) {
+	resetLevels()
+	defer resetLevels()
+
+	c := &captureLogger{}
+	l := Named(c, "repository").Named("orders")
+
+	assert.Equal(t, "repository.orders", l.LoggerName())
+
+	l.Debug(context.Background(), "default")
+	assert.Len(t, c.args, 1)
+	assert.Nil(t, c.levels[0])
+	assert.Equal(t, []interface{}{"logger", "repository.orders"}, c.fields[0])
+
+	SetLevel("repository", LevelWarning, 0)
+
+	l.Info(context.Background(), "filtered")
+	l.Warn(context.Background(), "passed")
+
+	assert.Len(t, c.args, 2)
+	assert.Equal(t, LevelWarning, c.levels[1])
+}
+
+func TestLevelsHandler(t *testing.T) {
+	resetLevels()
+	defer resetLevels()
+
+	h := LevelsHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/log/levels?name=repository.orders&level=debug&ttl=1m", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var res map[string]LevelOverride
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "DEBUG", res["repository.orders"].Level)
+	assert.False(t, res["repository.orders"].Expires.IsZero())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/log/levels?name=a&level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/debug/log/levels?name=repository.orders", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, LevelOverrides())
+}
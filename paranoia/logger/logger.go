@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"context"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+)
+
+// Logger wraps a logger chain and attaches its fields to every record.
+// A named logger also applies the level override configured for its name.
+type Logger struct {
+	interfaces.ILogger
+	name   string
+	fields []interface{}
+}
+
+// With returns l carrying the given key/value pairs
+func With(l interfaces.ILogger, kv ...interface{}) *Logger {
+	if w, ok := l.(*Logger); ok {
+		return w.With(kv...)
+	}
+
+	return &Logger{
+		ILogger: l,
+		fields:  kv,
+	}
+}
+
+// Named returns l named name, see SetLevel for per name levels
+func Named(l interfaces.ILogger, name string) *Logger {
+	if w, ok := l.(*Logger); ok {
+		return w.Named(name)
+	}
+
+	return &Logger{
+		ILogger: l,
+		name:    name,
+	}
+}
+
+// With returns a child logger carrying the fields of t and kv
+func (t *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(t.fields)+len(kv))
+	fields = append(fields, t.fields...)
+	fields = append(fields, kv...)
+
+	return &Logger{
+		ILogger: t.ILogger,
+		name:    t.name,
+		fields:  fields,
+	}
+}
+
+// Named returns a child logger, its name is appended to the name of t with a dot
+func (t *Logger) Named(name string) *Logger {
+	if t.name != "" && name != "" {
+		name = t.name + "." + name
+	} else if name == "" {
+		name = t.name
+	}
+
+	return &Logger{
+		ILogger: t.ILogger,
+		name:    name,
+		fields:  t.fields,
+	}
+}
+
+// LoggerName returns the name given by Named
+func (t *Logger) LoggerName() string {
+	return t.name
+}
+
+// context returns ctx with the fields and the level override of t, false if level is filtered out
+func (t *Logger) context(ctx context.Context, level int) (context.Context, bool) {
+	if t.name == "" {
+		return WithFields(ctx, t.fields...), true
+	}
+
+	override, ok := LevelFor(t.name)
+
+	if ok && level < override {
+		return ctx, false
+	}
+
+	ctx = WithFields(ctx, append([]interface{}{"logger", t.name}, t.fields...)...)
+
+	if ok {
+		ctx = context.WithValue(ctx, LevelKey, override)
+	}
+
+	return ctx, true
+}
+
+func (t *Logger) Debug(ctx context.Context, args ...interface{}) {
+	if c, ok := t.context(ctx, LevelDebug); ok {
+		t.ILogger.Debug(c, args...)
+	}
+}
+
+func (t *Logger) Info(ctx context.Context, args ...interface{}) {
+	if c, ok := t.context(ctx, LevelInfo); ok {
+		t.ILogger.Info(c, args...)
+	}
+}
+
+func (t *Logger) Warn(ctx context.Context, args ...interface{}) {
+	if c, ok := t.context(ctx, LevelWarning); ok {
+		t.ILogger.Warn(c, args...)
+	}
+}
+
+func (t *Logger) Message(ctx context.Context, args ...interface{}) {
+	if c, ok := t.context(ctx, LevelMessage); ok {
+		t.ILogger.Message(c, args...)
+	}
+}
+
+func (t *Logger) Error(ctx context.Context, err error) {
+	if c, ok := t.context(ctx, LevelError); ok {
+		t.ILogger.Error(c, err)
+	}
+}
+
+func (t *Logger) Fatal(ctx context.Context, err error) {
+	if c, ok := t.context(ctx, LevelCritical); ok {
+		t.ILogger.Fatal(c, err)
+	}
+}
+
+func (t *Logger) Panic(ctx context.Context, err error) {
+	if c, ok := t.context(ctx, LevelCritical); ok {
+		t.ILogger.Panic(c, err)
+	}
+}
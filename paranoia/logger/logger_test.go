@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type captureLogger struct {
+	fields [][]interface{}
+	args   [][]interface{}
+	levels []interface{}
+}
+
+func (t *captureLogger) Init(map[string]interface{}) error { return nil }
+func (t *captureLogger) Stop() error                       { return nil }
+func (t *captureLogger) Name() string                      { return "capture" }
+func (t *captureLogger) Type() string                      { return "logger" }
+func (t *captureLogger) Parent() interface{}               { return nil }
+func (t *captureLogger) SetParent(interface{})             {}
+
+func (t *captureLogger) push(ctx context.Context, args ...interface{}) {
+	t.fields = append(t.fields, Fields(ctx))
+	t.levels = append(t.levels, ctx.Value(LevelKey))
+	t.args = append(t.args, args)
+}
+
+func (t *captureLogger) Debug(ctx context.Context, args ...interface{})   { t.push(ctx, args...) }
+func (t *captureLogger) Info(ctx context.Context, args ...interface{})    { t.push(ctx, args...) }
+func (t *captureLogger) Warn(ctx context.Context, args ...interface{})    { t.push(ctx, args...) }
+func (t *captureLogger) Message(ctx context.Context, args ...interface{}) { t.push(ctx, args...) }
+func (t *captureLogger) Error(ctx context.Context, err error)             { t.push(ctx, err) }
+func (t *captureLogger) Fatal(ctx context.Context, err error)             { t.push(ctx, err) }
+func (t *captureLogger) Panic(ctx context.Context, err error)             { t.push(ctx, err) }
+
+func TestWith(t *testing.T) {
+	c := &captureLogger{}
+
+	l := With(c, "service", "orders")
+	child := l.With("user_id", 10)
+
+	ctx := WithFields(context.Background(), "path", "/api")
+
+	child.Info(ctx, "done", F("status", 200))
+	l.Error(context.Background(), errors.New("failed"))
+
+	assert.Equal(t, []interface{}{"path", "/api", "service", "orders", "user_id", 10}, c.fields[0])
+	assert.Equal(t, []interface{}{"done", F("status", 200)}, c.args[0])
+	assert.Equal(t, []interface{}{"service", "orders"}, c.fields[1])
+
+	// With on a wrapped logger does not nest wrappers
+	assert.Same(t, c, With(l, "a", 1).ILogger)
+}
+
+func TestWithFields_DoesNotAlias(t *testing.T) {
+	base := WithFields(context.Background(), "a", 1)
+
+	c1 := WithFields(base, "b", 2)
+	c2 := WithFields(base, "c", 3)
+
+	assert.Equal(t, []interface{}{"a", 1, "b", 2}, Fields(c1))
+	assert.Equal(t, []interface{}{"a", 1, "c", 3}, Fields(c2))
+	assert.Nil(t, Fields(context.Background()))
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+)
+
+// Levels between the slog ones matching MESSAGE and CRITICAL of the logger chain
+const (
+	SlogLevelMessage  = slog.Level(6)
+	SlogLevelCritical = slog.Level(12)
+)
+
+// SlogHandler is a slog.Handler forwarding records into a logger chain.
+// Record attributes become structured fields, groups prefix their keys with "group.".
+type SlogHandler struct {
+	logger interfaces.ILogger
+	opts   slog.HandlerOptions
+	fields []interface{}
+	group  string
+}
+
+// NewSlogHandler creates a handler writing to l. Only Level and AddSource of opts are used,
+// a nil opts enables all levels and lets the logger chain filter them.
+func NewSlogHandler(l interfaces.ILogger, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{
+		logger: l,
+	}
+
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelDebug
+	}
+
+	return h
+}
+
+func (t *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return t.logger != nil && level >= t.opts.Level.Level()
+}
+
+func (t *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]interface{}, 0, len(t.fields)+r.NumAttrs()*2+2)
+	fields = append(fields, t.fields...)
+
+	if t.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fields = append(fields, slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line))
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, t.group, a)
+		return true
+	})
+
+	ctx = WithFields(ctx, fields...)
+
+	switch {
+	case r.Level >= SlogLevelCritical:
+		t.logger.Fatal(ctx, errors.New(r.Message))
+	case r.Level >= slog.LevelError:
+		t.logger.Error(ctx, errors.New(r.Message))
+	case r.Level >= SlogLevelMessage:
+		t.logger.Message(ctx, r.Message)
+	case r.Level >= slog.LevelWarn:
+		t.logger.Warn(ctx, r.Message)
+	case r.Level >= slog.LevelInfo:
+		t.logger.Info(ctx, r.Message)
+	default:
+		t.logger.Debug(ctx, r.Message)
+	}
+
+	return nil
+}
+
+func (t *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return t
+	}
+
+	h := *t
+	h.fields = make([]interface{}, 0, len(t.fields)+len(attrs)*2)
+	h.fields = append(h.fields, t.fields...)
+
+	for _, a := range attrs {
+		h.fields = appendAttr(h.fields, t.group, a)
+	}
+
+	return &h
+}
+
+func (t *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return t
+	}
+
+	h := *t
+	h.group = t.group + name + "."
+
+	return &h
+}
+
+// appendAttr flattens a into key/value pairs, groups are expanded with dotted keys
+func appendAttr(fields []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+
+		for _, g := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, g)
+		}
+
+		return fields
+	}
+
+	return append(fields, prefix+a.Key, a.Value.Any())
+}
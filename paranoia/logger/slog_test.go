@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	c := &captureLogger{}
+
+	l := slog.New(NewSlogHandler(c, nil)).With("driver", "pgx").WithGroup("query")
+
+	l.InfoContext(WithFields(context.Background(), "request_id", "1"), "select", "rows", 2, slog.Group("args", "id", 5))
+	l.Log(context.Background(), SlogLevelCritical, "broken")
+
+	assert.Equal(t, []interface{}{"request_id", "1", "driver", "pgx", "query.rows", int64(2), "query.args.id", int64(5)}, c.fields[0])
+	assert.Equal(t, []interface{}{"select"}, c.args[0])
+
+	assert.EqualError(t, c.args[1][0].(error), "broken")
+	assert.Equal(t, []interface{}{"driver", "pgx"}, c.fields[1])
+}
+
+func TestSlogHandler_Level(t *testing.T) {
+	c := &captureLogger{}
+
+	l := slog.New(NewSlogHandler(c, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	l.Info("skipped")
+	l.Warn("kept")
+
+	assert.Len(t, c.args, 1)
+	assert.Equal(t, []interface{}{"kept"}, c.args[0])
+}
@@ -0,0 +1,231 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	instanceOnce sync.Once
+	instanceId   string
+)
+
+// InstanceId returns the identifier of the running service instance: host name and process id.
+func InstanceId() string {
+	instanceOnce.Do(func() {
+		host, err := os.Hostname()
+
+		if err != nil {
+			host = "unknown"
+		}
+
+		instanceId = fmt.Sprintf("%s-%d", host, os.Getpid())
+	})
+
+	return instanceId
+}
+
+// AppMeter is a meter scoped to a service module, see interfaces.IMeter.
+type AppMeter struct {
+	metric.Meter
+	prefix string
+}
+
+func NewAppMeter(service string, module string) *AppMeter {
+	return &AppMeter{
+		Meter:  otel.Meter(service+"/"+module, metric.WithInstrumentationAttributes(scopeAttributes(service, module)...)),
+		prefix: metricName(service) + "." + metricName(module) + ".",
+	}
+}
+
+func NewAppTracer(service string, module string) trace.Tracer {
+	return otel.Tracer(service+"/"+module, trace.WithInstrumentationAttributes(scopeAttributes(service, module)...))
+}
+
+func scopeAttributes(service string, module string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("service.name", service),
+		attribute.String("service.instance.id", InstanceId()),
+		attribute.String("module", module),
+	}
+}
+
+// metricName converts a free-form service or module name to a metric name segment.
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func (t *AppMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return t.Meter.Int64Counter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return t.Meter.Int64UpDownCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return t.Meter.Int64Histogram(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	return t.Meter.Int64Gauge(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64ObservableCounter(name string, options ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return t.Meter.Int64ObservableCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64ObservableUpDownCounter(name string, options ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return t.Meter.Int64ObservableUpDownCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Int64ObservableGauge(name string, options ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return t.Meter.Int64ObservableGauge(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64Counter(name string, options ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	return t.Meter.Float64Counter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64UpDownCounter(name string, options ...metric.Float64UpDownCounterOption) (metric.Float64UpDownCounter, error) {
+	return t.Meter.Float64UpDownCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return t.Meter.Float64Histogram(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64Gauge(name string, options ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	return t.Meter.Float64Gauge(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64ObservableCounter(name string, options ...metric.Float64ObservableCounterOption) (metric.Float64ObservableCounter, error) {
+	return t.Meter.Float64ObservableCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64ObservableUpDownCounter(name string, options ...metric.Float64ObservableUpDownCounterOption) (metric.Float64ObservableUpDownCounter, error) {
+	return t.Meter.Float64ObservableUpDownCounter(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return t.Meter.Float64ObservableGauge(t.prefix+name, options...)
+}
+
+func (t *AppMeter) Timer(name string, options ...metric.Float64HistogramOption) (interfaces.ITimer, error) {
+	h, err := t.Float64Histogram(name, append([]metric.Float64HistogramOption{metric.WithUnit("s")}, options...)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &appTimer{histogram: h}, nil
+}
+
+func (t *AppMeter) ErrorCounter(name string, options ...metric.Int64CounterOption) (interfaces.IErrorCounter, error) {
+	c, err := t.Int64Counter(name, append([]metric.Int64CounterOption{metric.WithUnit("{error}")}, options...)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &appErrorCounter{counter: c}, nil
+}
+
+func (t *AppMeter) Gauge(name string, callback func(ctx context.Context) int64, options ...metric.Int64ObservableGaugeOption) error {
+	options = append(options, metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+		o.Observe(callback(ctx))
+		return nil
+	}))
+
+	_, err := t.Int64ObservableGauge(name, options...)
+
+	return err
+}
+
+type appTimer struct {
+	histogram metric.Float64Histogram
+}
+
+func (t *appTimer) Start(ctx context.Context, attrs ...attribute.KeyValue) func() {
+	s := time.Now()
+
+	return func() {
+		t.Record(ctx, time.Since(s), attrs...)
+	}
+}
+
+func (t *appTimer) Time(ctx context.Context, fn func(ctx context.Context) error, attrs ...attribute.KeyValue) error {
+	s := time.Now()
+	err := fn(ctx)
+
+	t.Record(ctx, time.Since(s), append(attrs[:len(attrs):len(attrs)], attribute.Bool("error", err != nil))...)
+
+	return err
+}
+
+func (t *appTimer) Record(ctx context.Context, d time.Duration, attrs ...attribute.KeyValue) {
+	t.histogram.Record(ctx, d.Seconds(), metric.WithAttributes(attrs...))
+}
+
+type appErrorCounter struct {
+	counter metric.Int64Counter
+}
+
+// errorKind is implemented by errors which know their own kind
+type errorKind interface {
+	Kind() string
+}
+
+func (t *appErrorCounter) Add(ctx context.Context, err error, attrs ...attribute.KeyValue) {
+	if err == nil {
+		return
+	}
+
+	t.AddKind(ctx, ErrorKind(err), attrs...)
+}
+
+func (t *appErrorCounter) AddKind(ctx context.Context, kind string, attrs ...attribute.KeyValue) {
+	t.counter.Add(ctx, 1, metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], attribute.String("error.type", kind))...))
+}
+
+// ErrorKind returns the Kind() of the first error in the chain implementing it,
+// otherwise the type name of the innermost wrapped error.
+func ErrorKind(err error) string {
+	var k errorKind
+
+	if errors.As(err, &k) {
+		return k.Kind()
+	}
+
+	for {
+		next := errors.Unwrap(err)
+
+		if next == nil {
+			break
+		}
+
+		err = next
+	}
+
+	return fmt.Sprintf("%T", err)
+}
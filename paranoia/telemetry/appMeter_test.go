@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type kindError struct{}
+
+func (kindError) Error() string { return "kind error" }
+func (kindError) Kind() string  { return "validation" }
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, "validation", ErrorKind(fmt.Errorf("wrap: %w", kindError{})))
+	assert.Equal(t, "context.deadlineExceededError", ErrorKind(fmt.Errorf("wrap: %w", context.DeadlineExceeded)))
+}
+
+func TestMetricName(t *testing.T) {
+	assert.Equal(t, "minimal_paranoia_app", metricName("Minimal Paranoia-App"))
+}
+
+func TestAppMeter(t *testing.T) {
+	reader := metric.NewManualReader()
+	otel.SetMeterProvider(metric.NewMeterProvider(metric.WithReader(reader)))
+
+	m := NewAppMeter("Orders Service", "repository.orders")
+
+	timer, err := m.Timer("query")
+	assert.NoError(t, err)
+	_ = timer.Time(context.Background(), func(ctx context.Context) error { return errors.New("failed") })
+	timer.Start(context.Background(), attribute.String("op", "select"))()
+
+	errCounter, err := m.ErrorCounter("errors")
+	assert.NoError(t, err)
+	errCounter.Add(context.Background(), kindError{})
+	errCounter.Add(context.Background(), nil)
+
+	assert.NoError(t, m.Gauge("cache_size", func(ctx context.Context) int64 { return 42 }))
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	if !assert.Len(t, rm.ScopeMetrics, 1) {
+		return
+	}
+
+	scope := rm.ScopeMetrics[0].Scope
+	assert.Equal(t, "Orders Service/repository.orders", scope.Name)
+	v, _ := scope.Attributes.Value("module")
+	assert.Equal(t, "repository.orders", v.AsString())
+	_, ok := scope.Attributes.Value("service.instance.id")
+	assert.True(t, ok)
+
+	found := map[string]metricdata.Metrics{}
+	for _, item := range rm.ScopeMetrics[0].Metrics {
+		found[item.Name] = item
+	}
+
+	prefix := "orders_service.repository_orders."
+
+	if assert.Contains(t, found, prefix+"query") {
+		assert.Equal(t, "s", found[prefix+"query"].Unit)
+		assert.Len(t, found[prefix+"query"].Data.(metricdata.Histogram[float64]).DataPoints, 2)
+	}
+
+	if assert.Contains(t, found, prefix+"errors") {
+		points := found[prefix+"errors"].Data.(metricdata.Sum[int64]).DataPoints
+		assert.Len(t, points, 1)
+		kind, _ := points[0].Attributes.Value("error.type")
+		assert.Equal(t, "validation", kind.AsString())
+		assert.Equal(t, int64(1), points[0].Value)
+	}
+
+	if assert.Contains(t, found, prefix+"cache_size") {
+		assert.Equal(t, int64(42), found[prefix+"cache_size"].Data.(metricdata.Gauge[int64]).DataPoints[0].Value)
+	}
+}
+
+func TestAppMeter_CallerAttrs(t *testing.T) {
+	m := NewAppMeter("Orders Service", "repository.orders")
+
+	timer, err := m.Timer("query_attrs")
+	assert.NoError(t, err)
+	errCounter, err := m.ErrorCounter("errors_attrs")
+	assert.NoError(t, err)
+
+	// spare capacity of the caller's slice must not be written
+	attrs := make([]attribute.KeyValue, 1, 2)
+	attrs[0] = attribute.String("op", "select")
+	spare := attrs[:2]
+	spare[1] = attribute.String("keep", "me")
+
+	_ = timer.Time(context.Background(), func(ctx context.Context) error { return nil }, attrs...)
+	assert.Equal(t, "me", spare[1].Value.AsString())
+
+	errCounter.AddKind(context.Background(), "validation", attrs...)
+	assert.Equal(t, "me", spare[1].Value.AsString())
+}
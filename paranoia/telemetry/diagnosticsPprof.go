@@ -0,0 +1,227 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+	"sync"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/logger"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// profileStorage is the subset of storage/file and storage/s3 used to keep captured profiles.
+type profileStorage interface {
+	Put(name string, data io.Reader) error
+}
+
+type DiagnosticsPprof struct {
+	name    string
+	config  DiagnosticsPprofConfig
+	logger  interfaces.ILogger
+	server  *http.Server
+	storage profileStorage
+
+	done     chan interface{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type DiagnosticsPprofConfig struct {
+	ServiceName     string        `yaml:"service_name"`
+	Port            string        `yaml:"port"`
+	ProfileStorage  string        `yaml:"profile_storage"`
+	ProfileInterval time.Duration `yaml:"profile_interval"`
+	CpuDuration     time.Duration `yaml:"cpu_duration"`
+}
+
+func NewDiagnosticsPprof(name string) *DiagnosticsPprof {
+	return &DiagnosticsPprof{
+		name: name,
+	}
+}
+
+func (t *DiagnosticsPprof) Init(app interfaces.IEngine, cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+
+	if err != nil {
+		return err
+	}
+
+	if t.config.ServiceName == "" {
+		t.config.ServiceName = t.name
+	}
+
+	if t.config.CpuDuration == 0 {
+		t.config.CpuDuration = 30 * time.Second
+	}
+
+	t.logger = app.GetLogger()
+	t.done = make(chan interface{})
+
+	if t.config.ProfileStorage != "" {
+		pkg := app.GetPkg(interfaces.PkgStorage, t.config.ProfileStorage)
+
+		if pkg == nil {
+			return fmt.Errorf("storage %s not found", t.config.ProfileStorage)
+		}
+
+		storage, ok := pkg.(profileStorage)
+
+		if !ok {
+			return fmt.Errorf("storage %s does not support put", t.config.ProfileStorage)
+		}
+
+		t.storage = storage
+
+		if t.config.ProfileInterval <= 0 {
+			t.config.ProfileInterval = time.Hour
+		}
+
+		if t.config.CpuDuration >= t.config.ProfileInterval {
+			return fmt.Errorf("cpu_duration must be less than profile_interval")
+		}
+	}
+
+	if t.config.Port != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/log/levels", logger.LevelsHandler())
+
+		t.server = &http.Server{
+			Addr:        ":" + t.config.Port,
+			Handler:     mux,
+			ReadTimeout: 5 * time.Second,
+			// CPU profiles and traces stream for the requested number of seconds
+			WriteTimeout: 0,
+			IdleTimeout:  5 * time.Second,
+		}
+	}
+
+	return nil
+}
+
+func (t *DiagnosticsPprof) Start() error {
+	if t.storage != nil {
+		t.wg.Add(1)
+		go t.captureLoop()
+	}
+
+	if t.server == nil {
+		return nil
+	}
+
+	listenErr := make(chan error, 1)
+
+	go func() {
+		listenErr <- t.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-listenErr:
+		return err
+
+	case <-time.After(time.Second):
+		// pass
+	}
+
+	return nil
+}
+
+func (t *DiagnosticsPprof) Stop() error {
+	// done is nil when the diagnostics were never initialized, e.g. replaced by Engine.SetDiagnostics
+	if t.done != nil {
+		t.stopOnce.Do(func() { close(t.done) })
+	}
+
+	t.wg.Wait()
+
+	if t.server != nil {
+		return t.server.Shutdown(context.TODO())
+	}
+
+	return nil
+}
+
+func (t *DiagnosticsPprof) Name() string {
+	return t.name
+}
+
+func (t *DiagnosticsPprof) captureLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.ProfileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.Capture()
+
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Capture records a CPU profile for cpu_duration followed by a heap profile
+// and puts both into the configured storage.
+func (t *DiagnosticsPprof) Capture() {
+	if t.storage == nil {
+		return
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var buf bytes.Buffer
+
+	err := rpprof.StartCPUProfile(&buf)
+
+	if err != nil {
+		// profiling is already enabled, e.g. by a /debug/pprof/profile request
+		t.logError(fmt.Errorf("diagnostics: cpu profile: %w", err))
+	} else {
+		select {
+		case <-time.After(t.config.CpuDuration):
+		case <-t.done:
+		}
+
+		rpprof.StopCPUProfile()
+		t.put(fmt.Sprintf("%s_cpu_%s.pprof", t.config.ServiceName, stamp), &buf)
+	}
+
+	buf = bytes.Buffer{}
+
+	err = rpprof.Lookup("heap").WriteTo(&buf, 0)
+
+	if err != nil {
+		t.logError(fmt.Errorf("diagnostics: heap profile: %w", err))
+		return
+	}
+
+	t.put(fmt.Sprintf("%s_heap_%s.pprof", t.config.ServiceName, stamp), &buf)
+}
+
+func (t *DiagnosticsPprof) put(name string, data io.Reader) {
+	err := t.storage.Put(name, data)
+
+	if err != nil {
+		t.logError(fmt.Errorf("diagnostics: store %s: %w", name, err))
+	}
+}
+
+func (t *DiagnosticsPprof) logError(err error) {
+	if t.logger != nil {
+		t.logger.Error(context.Background(), err)
+	}
+}
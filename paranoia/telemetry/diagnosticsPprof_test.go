@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+)
+
+type memoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (t *memoryStorage) Init(map[string]interface{}) error { return nil }
+func (t *memoryStorage) Stop() error                       { return nil }
+func (t *memoryStorage) Name() string                      { return "profiles" }
+func (t *memoryStorage) Type() string                      { return interfaces.PkgStorage }
+
+func (t *memoryStorage) Put(name string, data io.Reader) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[name] = b
+
+	return nil
+}
+
+type diagnosticsEngine struct {
+	interfaces.IEngine
+	storage interfaces.IPkg
+}
+
+func (t *diagnosticsEngine) GetLogger() interfaces.ILogger { return nil }
+
+func (t *diagnosticsEngine) GetPkg(typePkg string, key string) interfaces.IPkg {
+	if typePkg == interfaces.PkgStorage && t.storage != nil && key == t.storage.Name() {
+		return t.storage
+	}
+
+	return nil
+}
+
+func TestDiagnosticsPprof_Listener(t *testing.T) {
+	d := NewDiagnosticsPprof("pprof")
+
+	err := d.Init(&diagnosticsEngine{}, map[string]interface{}{
+		"port": "8093",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, d.Start())
+	defer d.Stop()
+
+	resp, err := http.Get("http://127.0.0.1:8093/debug/pprof/")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Contains(t, string(body), "goroutine")
+}
+
+func TestDiagnosticsPprof_Capture(t *testing.T) {
+	storage := &memoryStorage{files: map[string][]byte{}}
+	d := NewDiagnosticsPprof("pprof")
+
+	err := d.Init(&diagnosticsEngine{storage: storage}, map[string]interface{}{
+		"service_name":     "test",
+		"profile_storage":  "profiles",
+		"profile_interval": "1h",
+		"cpu_duration":     "100ms",
+	})
+	assert.NoError(t, err)
+
+	d.Capture()
+
+	var cpu, heap int
+
+	for name, data := range storage.files {
+		assert.True(t, len(data) > 0, name)
+
+		if strings.HasPrefix(name, "test_cpu_") {
+			cpu++
+		}
+
+		if strings.HasPrefix(name, "test_heap_") {
+			heap++
+		}
+	}
+
+	assert.Equal(t, 1, cpu)
+	assert.Equal(t, 1, heap)
+}
+
+func TestDiagnosticsPprof_StorageNotFound(t *testing.T) {
+	d := NewDiagnosticsPprof("pprof")
+
+	err := d.Init(&diagnosticsEngine{}, map[string]interface{}{
+		"profile_storage": "unknown",
+	})
+
+	assert.Error(t, err)
+}
+
+func TestDiagnosticsPprof_StopDuringCapture(t *testing.T) {
+	storage := &memoryStorage{files: map[string][]byte{}}
+	d := NewDiagnosticsPprof("pprof")
+
+	err := d.Init(&diagnosticsEngine{storage: storage}, map[string]interface{}{
+		"profile_storage":  "profiles",
+		"profile_interval": "1h",
+		"cpu_duration":     "30m",
+	})
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		d.Capture()
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, d.Stop())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("capture did not stop")
+	}
+
+	assert.Len(t, storage.files, 2)
+}
+
+func TestDiagnosticsPprof_Stop(t *testing.T) {
+	// never initialized
+	assert.NoError(t, NewDiagnosticsPprof("pprof").Stop())
+
+	d := NewDiagnosticsPprof("pprof")
+	assert.NoError(t, d.Init(&diagnosticsEngine{}, map[string]interface{}{}))
+	assert.NoError(t, d.Start())
+	assert.NoError(t, d.Stop())
+	assert.NoError(t, d.Stop())
+}
@@ -47,3 +47,12 @@ func NewTrace(cfg map[string]interface{}) interfaces.ITrace {
 
 	return nil
 }
+
+func NewDiagnostics(cfg map[string]interface{}) interfaces.IDiagnostics {
+	t, ok := cfg["name"].(string)
+	if ok {
+		return NewDiagnosticsPprof(t)
+	}
+
+	return nil
+}
@@ -2,30 +2,56 @@ package telemetry
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
+	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/listener"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/prometheus"
 	api "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
+// stopTimeout bounds each step of Stop, an unreachable pushgateway must not block the engine shutdown
+const stopTimeout = 5 * time.Second
+
 type MetricPrometheus struct {
-	name     string
-	config   MetricPrometheusConfig
-	server   *http.Server
-	exporter metric.Reader
-	meter    api.Meter
+	name      string
+	config    MetricPrometheusConfig
+	server    *http.Server
+	listeners []*listener.Listener
+	exporter  metric.Reader
+	meter     api.Meter
+	pusher    *push.Pusher
+
+	done chan interface{}
+	wg   sync.WaitGroup
 }
 
 type MetricPrometheusConfig struct {
 	ServiceName string `yaml:"service_name"`
 	Port        string `yaml:"port"`
+
+	// Listeners from the listeners key replace port, e.g. a loopback or unix socket listener for the scraper
+	Listeners []listener.Config `yaml:"-"`
+
+	// PushUrl enables push mode to a pushgateway-compatible endpoint, for short-lived jobs.
+	PushUrl      string        `yaml:"push_url"`
+	PushJob      string        `yaml:"push_job"`
+	PushInterval time.Duration `yaml:"push_interval"`
+	PushUser     string        `yaml:"push_user"`
+	PushPassword string        `yaml:"push_password"`
 }
 
 func NewMetricPrometheus(name string) *MetricPrometheus {
@@ -35,12 +61,20 @@ func NewMetricPrometheus(name string) *MetricPrometheus {
 }
 
 func (t *MetricPrometheus) Init(cfg map[string]interface{}) error {
-	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	listeners, err := listener.Pop(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	err = decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
 
 	if err != nil {
 		return err
 	}
 
+	t.config.Listeners = listeners
+
 	res, err := resource.Merge(resource.Default(),
 		resource.NewSchemaless(
 			attribute.String("service.name", t.config.ServiceName),
@@ -50,21 +84,56 @@ func (t *MetricPrometheus) Init(cfg map[string]interface{}) error {
 		return err
 	}
 
-	t.server = &http.Server{
-		Addr:                         ":" + t.config.Port,
-		Handler:                      promhttp.Handler(),
-		DisableGeneralOptionsHandler: false,
-		ReadTimeout:                  5 * time.Second,
-		WriteTimeout:                 10 * time.Second,
-		IdleTimeout:                  5 * time.Second,
+	if t.config.PushUrl == "" || t.config.Port != "" || len(t.config.Listeners) > 0 {
+		// OpenMetrics is required to expose exemplars
+		handler := promhttp.InstrumentMetricHandler(
+			prom.DefaultRegisterer,
+			promhttp.HandlerFor(prom.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+		)
+
+		t.server = &http.Server{
+			Addr:                         ":" + t.config.Port,
+			Handler:                      handler,
+			DisableGeneralOptionsHandler: false,
+			ReadTimeout:                  5 * time.Second,
+			WriteTimeout:                 10 * time.Second,
+			IdleTimeout:                  5 * time.Second,
+		}
+	}
+
+	if t.config.PushUrl != "" {
+		if t.config.PushJob == "" {
+			t.config.PushJob = t.config.ServiceName
+		}
+
+		if t.config.PushJob == "" {
+			return fmt.Errorf("push_job or service_name is required for push mode")
+		}
+
+		if t.config.PushInterval <= 0 {
+			t.config.PushInterval = 15 * time.Second
+		}
+
+		t.pusher = push.New(t.config.PushUrl, t.config.PushJob).Gatherer(prom.DefaultGatherer)
+
+		if t.config.PushUser != "" {
+			t.pusher = t.pusher.BasicAuth(t.config.PushUser, t.config.PushPassword)
+		}
 	}
 
+	t.done = make(chan interface{})
+
 	t.exporter, err = prometheus.New()
 	if err != nil {
 		return err
 	}
 
-	provider := metric.NewMeterProvider(metric.WithResource(res), metric.WithReader(t.exporter))
+	// Measurements recorded inside a sampled span carry its trace and span id as exemplar
+	provider := metric.NewMeterProvider(
+		metric.WithResource(res),
+		metric.WithReader(t.exporter),
+		metric.WithExemplarFilter(exemplar.TraceBasedFilter),
+	)
 	otel.SetMeterProvider(provider)
 
 	return nil
@@ -72,11 +141,29 @@ func (t *MetricPrometheus) Init(cfg map[string]interface{}) error {
 }
 
 func (t *MetricPrometheus) Start() error {
-	listenErr := make(chan error, 1)
+	if t.pusher != nil {
+		t.wg.Add(1)
+		go t.pushLoop()
+	}
+
+	if t.server == nil {
+		return nil
+	}
+
+	listeners, err := listener.ListenAll(listener.Defaults(t.config.Listeners, t.config.Port))
+
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		listenErr <- t.server.ListenAndServe()
-	}()
+	t.listeners = listeners
+	listenErr := make(chan error, len(listeners))
+
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			listenErr <- t.server.Serve(l)
+		}(l)
+	}
 
 	select {
 	case err := <-listenErr:
@@ -90,13 +177,73 @@ func (t *MetricPrometheus) Start() error {
 }
 
 func (t *MetricPrometheus) Stop() error {
-	err := t.server.Shutdown(context.TODO())
+	close(t.done)
+	t.wg.Wait()
 
-	if err != nil {
-		return err
+	var errs []error
+
+	if t.pusher != nil {
+		// last push so that short-lived jobs do not lose measurements made after the last tick,
+		// an unreachable gateway must not keep the listener and the exporter open
+		c, cancel := context.WithTimeout(context.Background(), min(t.config.PushInterval, stopTimeout))
+		err := t.Push(c)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("metrics push: %w", err))
+		}
+	}
+
+	if t.server != nil {
+		c, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		err := t.server.Shutdown(c)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	if err := t.exporter.Shutdown(c); err != nil {
+		errs = append(errs, err)
 	}
 
-	return t.exporter.Shutdown(context.TODO())
+	return errors.Join(errs...)
+}
+
+// Push sends the current state of all metrics to the pushgateway, replacing the job group.
+func (t *MetricPrometheus) Push(ctx context.Context) error {
+	if t.pusher == nil {
+		return nil
+	}
+
+	return t.pusher.PushContext(ctx)
+}
+
+func (t *MetricPrometheus) pushLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c, cancel := context.WithTimeout(context.Background(), t.config.PushInterval)
+			err := t.Push(c)
+			cancel()
+
+			if err != nil {
+				otel.Handle(fmt.Errorf("metrics push: %w", err))
+			}
+
+		case <-t.done:
+			return
+		}
+	}
 }
 
 func (t *MetricPrometheus) Name() string {
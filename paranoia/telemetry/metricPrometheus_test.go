@@ -0,0 +1,200 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+type pushGateway struct {
+	mu     sync.Mutex
+	paths  []string
+	bodies [][]byte
+}
+
+func (t *pushGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	t.mu.Lock()
+	t.paths = append(t.paths, r.Method+" "+r.URL.Path)
+	t.bodies = append(t.bodies, body)
+	t.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (t *pushGateway) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.bodies)
+}
+
+func TestMetricPrometheus_Push(t *testing.T) {
+	gw := &pushGateway{}
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	m := NewMetricPrometheus("prometheus")
+
+	err := m.Init(map[string]interface{}{
+		"service_name":  "test",
+		"push_url":      srv.URL,
+		"push_job":      "batch",
+		"push_interval": "50ms",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Nil(t, m.server)
+	assert.NoError(t, m.Start())
+
+	counter, _ := otel.Meter("").Int64Counter("push_test_counter")
+	counter.Add(context.Background(), 1)
+
+	assert.Eventually(t, func() bool { return gw.count() > 0 }, 2*time.Second, 10*time.Millisecond)
+
+	before := gw.count()
+	assert.NoError(t, m.Stop())
+
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	assert.Greater(t, len(gw.bodies), before, "push on stop")
+	assert.Equal(t, "PUT /metrics/job/batch", gw.paths[len(gw.paths)-1])
+	assert.True(t, bytes.Contains(gw.bodies[len(gw.bodies)-1], []byte("push_test_counter")))
+}
+
+func TestMetricPrometheus_Exemplar(t *testing.T) {
+	m := NewMetricPrometheus("prometheus")
+
+	err := m.Init(map[string]interface{}{
+		"service_name": "test",
+		"port":         "0",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer m.exporter.Shutdown(context.Background())
+
+	tp := trace.NewTracerProvider(trace.WithSampler(trace.AlwaysSample()))
+	defer tp.Shutdown(context.Background())
+
+	c, span := tp.Tracer("").Start(context.Background(), "request")
+	histogram, _ := otel.Meter("").Int64Histogram("exemplar_test_latency")
+	histogram.Record(c, 12)
+	span.End()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+
+	m.server.Handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+
+	assert.Contains(t, body, "exemplar_test_latency")
+	assert.Contains(t, body, `trace_id="`+span.SpanContext().TraceID().String()+`"`)
+}
+
+func TestMetricPrometheus_Listeners(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "metrics.sock")
+	m := NewMetricPrometheus("prometheus")
+
+	err := m.Init(map[string]interface{}{
+		"service_name": "test",
+		"listeners": []interface{}{
+			map[string]interface{}{"name": "scrape", "network": "unix", "address": sock},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.NoError(t, m.Start()) {
+		return
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(c context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(c, "unix", sock)
+		},
+	}}
+
+	resp, err := client.Get("http://metrics/metrics")
+
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	assert.NoError(t, m.Stop())
+}
+
+func TestMetricPrometheus_StopUnreachableGateway(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "metrics.sock")
+	gw := httptest.NewServer(http.NotFoundHandler())
+	gw.Close()
+
+	m := NewMetricPrometheus("prometheus")
+
+	err := m.Init(map[string]interface{}{
+		"service_name":  "test",
+		"push_url":      gw.URL,
+		"push_interval": "1h",
+		"listeners": []interface{}{
+			map[string]interface{}{"name": "scrape", "network": "unix", "address": sock},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.NoError(t, m.Start()) {
+		return
+	}
+
+	assert.ErrorContains(t, m.Stop(), "metrics push")
+
+	// the listener is closed even though the last push failed
+	_, err = net.Dial("unix", sock)
+	assert.Error(t, err)
+}
+
+func TestMetricPrometheus_StopBlackholedGateway(t *testing.T) {
+	release := make(chan struct{})
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer gw.Close()
+	defer close(release)
+
+	m := NewMetricPrometheus("prometheus")
+
+	err := m.Init(map[string]interface{}{
+		"service_name":  "test",
+		"push_url":      gw.URL,
+		"push_interval": "200ms",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, m.Start())
+
+	start := time.Now()
+	assert.ErrorContains(t, m.Stop(), "metrics push")
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// StartRuntimeMetrics registers Go runtime (GC, goroutines, heap), host (CPU, memory, network)
+// and process file descriptor instruments on the global meter provider.
+// It must be called after the metric exporter has installed its provider.
+func StartRuntimeMetrics() error {
+	err := runtime.Start(runtime.WithMinimumReadMemStatsInterval(time.Second))
+
+	if err != nil {
+		return err
+	}
+
+	err = host.Start()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = otel.Meter("process").Int64ObservableGauge(
+		"process.open_fds",
+		api.WithDescription("Number of open file descriptors"),
+		api.WithUnit("{file_descriptor}"),
+		api.WithInt64Callback(func(_ context.Context, o api.Int64Observer) error {
+			entries, err := os.ReadDir("/proc/self/fd")
+
+			if err != nil {
+				// not supported on this platform
+				return nil
+			}
+
+			o.Observe(int64(len(entries)))
+
+			return nil
+		}),
+	)
+
+	return err
+}
@@ -50,4 +50,7 @@ type IMemory interface {
 
 	// Expire updates the expiration time for the value stored under the given key.
 	Expire(ctx context.Context, key string, timeout time.Duration) error
+
+	// Len returns the number of items currently stored in the cache, e.g. to back a gauge.
+	Len() int64
 }
@@ -553,6 +553,10 @@ func (t *Memory) Expire(ctx context.Context, key string, timeout time.Duration)
 	return nil
 }
 
+func (t *Memory) Len() int64 {
+	return atomic.LoadInt64(&t.itemCount)
+}
+
 // --- Helpers: LRU and capacity enforcement ---
 
 func (t *Memory) touchLRULocked(shard int, key string) {
@@ -715,6 +715,32 @@ func TestMemory_Capacity_Error(t1 *testing.T) {
 	}
 }
 
+func TestMemory_Len(t1 *testing.T) {
+	t := New("test")
+	err := t.Init(map[string]interface{}{
+		"time_clear":  0,
+		"shard_count": 2,
+	})
+	if err != nil {
+		t1.Fatalf("Init() error = %v", err)
+	}
+	defer t.Stop()
+
+	_ = t.Set(context.Background(), "k1", "v1", time.Minute)
+	_ = t.Set(context.Background(), "k2", "v2", time.Minute)
+	_ = t.Set(context.Background(), "k2", "v3", time.Minute)
+
+	if l := t.Len(); l != 2 {
+		t1.Fatalf("Len() = %d, want 2", l)
+	}
+
+	_ = t.Delete(context.Background(), "k1")
+
+	if l := t.Len(); l != 1 {
+		t1.Fatalf("Len() = %d, want 1", l)
+	}
+}
+
 func TestMemory_Capacity_TTL(t1 *testing.T) {
 	t := New("test")
 	err := t.Init(map[string]interface{}{
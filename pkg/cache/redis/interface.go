@@ -73,6 +73,10 @@ type IRedis interface {
 
 	// Expire sets or updates the expiration time for the given key.
 	Expire(ctx context.Context, key string, timeout time.Duration) error
+
+	// Eval atomically runs a Lua script with the given keys and arguments and returns its reply,
+	// nil for a nil reply.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
 }
 
 // Batcher provides a minimal set of batched operations supported by Batch.
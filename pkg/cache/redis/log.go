@@ -0,0 +1,19 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// redisLogger forwards go-redis internal logs to the default slog logger, which the engine
+// points at its logger chain. go-redis only logs connection and pool problems, hence warn.
+type redisLogger struct{}
+
+func (redisLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	slog.Default().Log(ctx, slog.LevelWarn, "redis: "+fmt.Sprintf(format, v...))
+}
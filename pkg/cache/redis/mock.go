@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"sync"
 	"time"
@@ -36,6 +37,7 @@ type Mock struct {
 	BatchFunc         func(ctx context.Context, ops []BatchOp) error
 	DeleteFunc        func(ctx context.Context, key string) error
 	ExpireFunc        func(ctx context.Context, key string, timeout time.Duration) error
+	EvalFunc          func(ctx context.Context, script string, keys []string, args ...any) (any, error)
 
 	NamePkg string
 
@@ -315,6 +317,18 @@ func (m *Mock) Expire(ctx context.Context, key string, timeout time.Duration) er
 	return nil
 }
 
+func (m *Mock) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	key := ""
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	m.record("Eval", key, "")
+	if m.EvalFunc != nil {
+		return m.EvalFunc(ctx, script, keys, args...)
+	}
+	return nil, errors.New("redis mock: EvalFunc is not set")
+}
+
 // MockBatcher captures batched operations for tests
 type MockBatcher struct{ ops *[]BatchOp }
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	redisExt "github.com/redis/go-redis/v9"
@@ -17,7 +18,8 @@ type Redis struct {
 	name   string
 	config Config
 
-	client redisExt.UniversalClient
+	client  redisExt.UniversalClient
+	scripts sync.Map // source -> *redisExt.Script
 
 	counterRead  metric.Int64Counter
 	counterWrite metric.Int64Counter
@@ -51,6 +53,8 @@ func (t *Redis) Init(cfg map[string]interface{}) error {
 		return errors.New("hosts is required")
 	}
 
+	redisExt.SetLogger(redisLogger{})
+
 	if t.config.UseCluster {
 		if t.config.DBNum != 0 {
 			return errors.New("database number not available when using Redis cluster")
@@ -278,6 +282,30 @@ func (t *Redis) Expire(ctx context.Context, key string, timeout time.Duration) e
 	return nil
 }
 
+// Eval runs a Lua script by its SHA1, loading it on NOSCRIPT. Keys are prefixed with key_prefix.
+func (t *Redis) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	s := time.Now()
+	t.counterWrite.Add(ctx, 1)
+
+	sc, ok := t.scripts.Load(script)
+	if !ok {
+		sc, _ = t.scripts.LoadOrStore(script, redisExt.NewScript(script))
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = t.config.KeyPrefix + k
+	}
+
+	v, err := sc.(*redisExt.Script).Run(ctx, t.client, prefixed, args...).Result()
+	t.timeWrite.Record(ctx, time.Since(s).Milliseconds())
+	if errors.Is(err, redisExt.Nil) {
+		return nil, nil
+	}
+
+	return v, err
+}
+
 // internal pipeline batcher
 type pipelineBatcher struct {
 	p    redisExt.Pipeliner
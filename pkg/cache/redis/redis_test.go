@@ -1136,3 +1136,48 @@ func TestRedis_Batch(t1 *testing.T) {
 		t1.Fatalf("keys not deleted")
 	}
 }
+
+func TestRedis_Eval(t1 *testing.T) {
+	if os.Getenv("PARANOIA_INTEGRATED_TESTS") != "Y" {
+		t1.Skip()
+		return
+	}
+
+	host := os.Getenv("PARANOIA_INTEGRATED_SERVER")
+
+	t := &Redis{
+		name: "test",
+		config: Config{
+			Hosts:     host + ":6379",
+			KeyPrefix: fmt.Sprintf("test_%d", rand.Int64()),
+		},
+	}
+	err := t.Init(nil)
+	defer t.Stop()
+
+	if err != nil {
+		t1.Errorf("Init() = %v", err)
+	}
+
+	script := `return redis.call('INCRBY', KEYS[1], ARGV[1])`
+
+	for i := int64(1); i <= 2; i++ {
+		got, err := t.Eval(context.Background(), script, []string{"ev"}, 5)
+		if err != nil || got != 5*i {
+			t1.Fatalf("Eval = %v err=%v", got, err)
+		}
+	}
+
+	// keys are prefixed like the other operations
+	got, err := t.Get(context.Background(), "ev")
+	if err != nil || got != "10" {
+		t1.Fatalf("Get ev = %v err=%v", got, err)
+	}
+
+	got2, err := t.Eval(context.Background(), `return redis.call('GET', KEYS[1])`, []string{"missing"})
+	if err != nil || got2 != nil {
+		t1.Fatalf("Eval nil = %v err=%v", got2, err)
+	}
+
+	_ = t.Delete(context.Background(), "ev")
+}
@@ -16,6 +16,7 @@ type KafkaClient struct {
 	name     string
 	config   Config
 	producer *otelkafka.Producer
+	logs     chan kafka.LogEvent
 
 	counter      metric.Int64Counter
 	timeCounter  metric.Int64Histogram
@@ -51,6 +52,10 @@ func (t *KafkaClient) Init(cfg map[string]interface{}) error {
 		"bootstrap.servers": t.config.Hosts,
 	}
 
+	t.logs = make(chan kafka.LogEvent, 1000)
+	_ = cfgKafka.SetKey("go.logs.channel.enable", true)
+	_ = cfgKafka.SetKey("go.logs.channel", t.logs)
+
 	if t.config.Username != "" {
 		if t.config.SecurityProtocol != "" {
 			_ = cfgKafka.SetKey("security.protocol", t.config.SecurityProtocol)
@@ -67,9 +72,12 @@ func (t *KafkaClient) Init(cfg map[string]interface{}) error {
 	t.producer, err = otelkafka.NewProducer(&cfgKafka)
 
 	if err != nil {
+		close(t.logs)
 		return err
 	}
 
+	go forwardLogs(t.name, t.logs)
+
 	t.counter, _ = otel.Meter("").Int64Counter("client_kafka." + t.name + ".count")
 	t.timeCounter, _ = otel.Meter("").Int64Histogram("client_kafka." + t.name + ".time")
 	t.retryCounter, _ = otel.Meter("").Int64Histogram("client_kafka." + t.name + ".retry")
@@ -79,6 +87,7 @@ func (t *KafkaClient) Init(cfg map[string]interface{}) error {
 
 func (t *KafkaClient) Stop() error {
 	t.producer.Close()
+	close(t.logs)
 	return nil
 }
 
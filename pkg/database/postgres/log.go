@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+// pgxLogger forwards pgx driver logs to the default slog logger, which the engine
+// points at its logger chain.
+func pgxLogger(name string) tracelog.Logger {
+	return tracelog.LoggerFunc(func(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]interface{}) {
+		keys := make([]string, 0, len(data))
+
+		for k := range data {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		attrs := make([]slog.Attr, 0, len(keys)+1)
+		attrs = append(attrs, slog.String("postgres", name))
+
+		for _, k := range keys {
+			attrs = append(attrs, slog.Any(k, data[k]))
+		}
+
+		slog.Default().LogAttrs(ctx, pgxLevel(level), "pgx: "+msg, attrs...)
+	})
+}
+
+func pgxLevel(level tracelog.LogLevel) slog.Level {
+	switch level {
+	case tracelog.LogLevelTrace, tracelog.LogLevelDebug:
+		return slog.LevelDebug
+	case tracelog.LogLevelInfo:
+		return slog.LevelInfo
+	case tracelog.LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/tracelog"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
@@ -22,7 +23,8 @@ type Postgres struct {
 }
 
 type Config struct {
-	URI string `yaml:"uri"`
+	URI      string `yaml:"uri"`
+	LogLevel string `yaml:"log_level"` // trace|debug|info|warn|error|none, default warn
 }
 
 func New(name string) *Postgres {
@@ -46,6 +48,22 @@ func (t *Postgres) Init(cfg map[string]interface{}) error {
 		return err
 	}
 
+	if t.config.LogLevel == "" {
+		t.config.LogLevel = "warn"
+	}
+
+	logLevel, err := tracelog.LogLevelFromString(t.config.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	if logLevel != tracelog.LogLevelNone {
+		poolCfg.ConnConfig.Tracer = &tracelog.TraceLog{
+			Logger:   pgxLogger(t.name),
+			LogLevel: logLevel,
+		}
+	}
+
 	t.pool, err = pgxpool.NewWithConfig(context.TODO(), poolCfg)
 	if err != nil {
 		return err
@@ -4,24 +4,55 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
+// Overflow policies applied when the queue is full
+const (
+	OverflowBlock      = logcore.OverflowBlock
+	OverflowDropNewest = logcore.OverflowDropNewest
+	OverflowDropOldest = logcore.OverflowDropOldest
+)
+
 type File struct {
-	name   string
-	parent ILogger
-	config Config
-	queue  chan string
-	done   chan interface{}
-	f      *os.File
+	name     string
+	parent   ILogger
+	config   Config
+	queue    *logcore.Queue
+	reopenCh chan struct{}
+
+	// owned by the writer goroutine
+	f    *os.File
+	path string
+	size int64
+	mode os.FileMode
+
+	mu sync.Mutex     // serializes compression and cleanup of rotated files
+	wg sync.WaitGroup // running maintenance goroutines
 }
 
 type Config struct {
-	Level  LogLevel `yaml:"level"`
-	FName  string   `yaml:"filename"`
-	Enable bool     `yaml:"enable"`
+	Level          LogLevel      `yaml:"level"`
+	FName          string        `yaml:"filename"`
+	Dir            string        `yaml:"dir"`       // default ./log
+	FileMode       string        `yaml:"file_mode"` // octal, default 0644
+	Enable         bool          `yaml:"enable"`
+	Format         string        `yaml:"format"`           // text|json
+	MaxSize        int64         `yaml:"max_size"`         // megabytes before the file is rotated, 0 - only daily rotation
+	MaxBackups     int           `yaml:"max_backups"`      // rotated files to keep, 0 - keep all
+	MaxAge         time.Duration `yaml:"max_age"`          // remove rotated files older than, 0 - keep all
+	Compress       bool          `yaml:"compress"`         // gzip rotated files
+	ReopenOnSighup bool          `yaml:"reopen_on_sighup"` // reopen the file on SIGHUP for external logrotate
+	QueueSize      int           `yaml:"queue_size"`       // default 1000
+	Overflow       string        `yaml:"overflow"`         // block|drop_newest|drop_oldest, default block
+	StopTimeout    time.Duration `yaml:"stop_timeout"`     // time to drain the queue on stop and flush on fatal, default 5s
 }
 
 func New(name string) *File {
@@ -40,19 +71,51 @@ func (t *File) Init(cfg map[string]interface{}) error {
 		return errors.New("filename is required")
 	}
 
-	t.queue = make(chan string, 1000)
-	t.done = make(chan interface{})
+	if t.config.Format == "" {
+		t.config.Format = "text"
+	}
+
+	if t.config.Format != "text" && t.config.Format != "json" {
+		return fmt.Errorf("unknown format: %s", t.config.Format)
+	}
+
+	if t.config.Dir == "" {
+		t.config.Dir = "./log"
+	}
+
+	if t.config.FileMode == "" {
+		t.config.FileMode = "0644"
+	}
+
+	mode, err := strconv.ParseUint(t.config.FileMode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file_mode: %s", t.config.FileMode)
+	}
 
-	_, err = os.Stat("./log")
+	t.mode = os.FileMode(mode)
 
-	if os.IsNotExist(err) {
-		_ = os.Mkdir("./log", 0766)
+	if t.config.MaxSize < 0 || t.config.MaxBackups < 0 || t.config.MaxAge < 0 {
+		return errors.New("max_size, max_backups and max_age must not be negative")
 	}
 
-	t.f, err = os.OpenFile(
-		fmt.Sprintf("./log/%s_%s.log", t.config.FName, time.Now().Format("2006_01_02")),
-		os.O_WRONLY|os.O_APPEND|os.O_CREATE,
-		0666)
+	if t.config.StopTimeout <= 0 {
+		t.config.StopTimeout = 5 * time.Second
+	}
+
+	t.queue, err = logcore.NewQueue(t.config.QueueSize, t.config.Overflow)
+	if err != nil {
+		return err
+	}
+
+	t.reopenCh = make(chan struct{}, 1)
+
+	err = os.MkdirAll(t.config.Dir, 0755)
+
+	if err != nil {
+		return err
+	}
+
+	err = t.open()
 
 	if err != nil {
 		fmt.Println("error open log file")
@@ -65,15 +128,28 @@ func (t *File) Init(cfg map[string]interface{}) error {
 }
 
 func (t *File) Stop() error {
+	var err error
+
+	if t.queue != nil {
+		err = t.queue.Close(t.config.StopTimeout)
+	}
+
 	if t.parent != nil {
-		return t.parent.Stop()
+		if parentErr := t.parent.Stop(); err == nil {
+			err = parentErr
+		}
 	}
 
-	close(t.done)
-	time.Sleep(time.Second * 1)
-	close(t.queue)
+	return err
+}
 
-	return nil
+// Dropped returns the number of lines discarded by the overflow policy or pushed after stop
+func (t *File) Dropped() int64 {
+	if t.queue == nil {
+		return 0
+	}
+
+	return t.queue.Dropped()
 }
 
 func (t *File) Name() string {
@@ -84,9 +160,32 @@ func (t *File) Type() string {
 	return "logger"
 }
 
+// Reopen asks the writer to reopen the log file, e.g. after it was moved by an external tool
+func (t *File) Reopen() {
+	select {
+	case t.reopenCh <- struct{}{}:
+	default:
+	}
+}
+
 func (t *File) run() {
+	hup := make(chan os.Signal, 1)
+
+	if t.config.ReopenOnSighup {
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+
 	go func() {
-		defer t.f.Close()
+		defer func() {
+			signal.Stop(hup)
+			t.wg.Wait()
+
+			if t.f != nil {
+				_ = t.f.Close()
+			}
+
+			t.queue.WriterDone()
+		}()
 
 		timeNow := time.Now()
 		seconds := 24*60*60 - (timeNow.Hour()*60*60 + timeNow.Minute()*60 + timeNow.Second())
@@ -94,31 +193,30 @@ func (t *File) run() {
 
 		for {
 			select {
-			case m := <-t.queue:
+			case m := <-t.queue.Lines():
 				t.write(m)
 
-			case <-timerRecreate.C:
-				err := t.f.Close()
-
-				if err != nil {
-					fmt.Println("error close log file")
-				}
+			case ack := <-t.queue.Flushes():
+				t.queue.Drain(t.write, time.Time{})
+				t.sync()
+				close(ack)
 
-				t.f, err = os.OpenFile(
-					fmt.Sprintf("./log/%s_%s.log", t.config.FName, time.Now().Format("2006_01_02")),
-					os.O_WRONLY|os.O_APPEND|os.O_CREATE,
-					0666)
-
-				if err != nil {
-					fmt.Println("error open log file")
-				}
+			case <-timerRecreate.C:
+				t.rotate()
 
 				timeNow = time.Now()
 				seconds = 24*60*60 - (timeNow.Hour()*60*60 + timeNow.Minute()*60 + timeNow.Second())
 				timerRecreate.Reset(time.Second * time.Duration(seconds))
 
-			case <-t.done:
-				time.Sleep(time.Millisecond * 100)
+			case <-hup:
+				t.reopen()
+
+			case <-t.reopenCh:
+				t.reopen()
+
+			case <-t.queue.Done():
+				t.queue.Drain(t.write, time.Now().Add(t.config.StopTimeout))
+				t.sync()
 				return
 			}
 		}
@@ -126,12 +224,33 @@ func (t *File) run() {
 }
 
 func (t *File) write(m string) {
-	_, err := t.f.Write([]byte(m))
+	if t.config.MaxSize > 0 && t.size > 0 && t.size+int64(len(m)) > t.config.MaxSize*1024*1024 {
+		t.rotate()
+	}
+
+	if t.f == nil {
+		if err := t.open(); err != nil {
+			fmt.Println("error open log file")
+			return
+		}
+	}
+
+	n, err := t.f.Write([]byte(m))
+	t.size += int64(n)
+
 	if err != nil {
 		fmt.Println("error write log file")
 	}
 }
 
+func (t *File) sync() {
+	if t.f != nil {
+		if err := t.f.Sync(); err != nil {
+			fmt.Println("error sync log file")
+		}
+	}
+}
+
 func (t *File) SetLevel(level int) {
 	t.config.Level = LogLevel(level)
 
@@ -140,15 +259,28 @@ func (t *File) SetLevel(level int) {
 	}
 }
 
-func (t *File) push(level LogLevel, msg string) {
+func (t *File) push(level LogLevel, msg string, fields []field) {
 	if t.config.Enable {
-		t.queue <- fmt.Sprintf("%s [%s] %s\n", level.String(), time.Now().Format("2006-01-02 15:04.05"), msg)
+		// critical lines are never dropped and are on disk before Fatal and Panic return
+		force := level == CRITICAL
+
+		if force {
+			defer t.queue.Flush(t.config.StopTimeout)
+		}
+
+		if t.config.Format == "json" {
+			t.queue.Push(logcore.FormatJSON(level.String(), time.Now(), msg, fields)+"\n", force)
+			return
+		}
+
+		t.queue.Push(fmt.Sprintf("%s [%s] %s%s\n", level.String(), time.Now().Format("2006-01-02 15:04.05"), msg, logcore.FormatText(fields)), force)
 	}
 }
 
 func (t *File) Debug(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= DEBUG {
-		t.push(DEBUG, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(DEBUG, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Debug(ctx, args...)
@@ -157,8 +289,9 @@ func (t *File) Debug(ctx context.Context, args ...interface{}) {
 }
 
 func (t *File) Info(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= INFO {
-		t.push(INFO, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(INFO, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Info(ctx, args...)
@@ -167,8 +300,9 @@ func (t *File) Info(ctx context.Context, args ...interface{}) {
 }
 
 func (t *File) Warn(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= WARNING {
-		t.push(WARNING, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(WARNING, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Warn(ctx, args...)
@@ -177,8 +311,9 @@ func (t *File) Warn(ctx context.Context, args ...interface{}) {
 }
 
 func (t *File) Message(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= MESSAGE {
-		t.push(MESSAGE, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(MESSAGE, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Message(ctx, args...)
@@ -187,8 +322,8 @@ func (t *File) Message(ctx context.Context, args ...interface{}) {
 }
 
 func (t *File) Error(ctx context.Context, err error) {
-	if t.config.Level <= ERROR {
-		t.push(ERROR, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		t.push(ERROR, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Error(ctx, err)
@@ -197,8 +332,8 @@ func (t *File) Error(ctx context.Context, err error) {
 }
 
 func (t *File) Fatal(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		t.push(CRITICAL, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Fatal(ctx, err)
@@ -207,8 +342,8 @@ func (t *File) Fatal(ctx context.Context, err error) {
 }
 
 func (t *File) Panic(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		t.push(CRITICAL, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Panic(ctx, err)
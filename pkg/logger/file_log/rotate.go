@@ -0,0 +1,226 @@
+package file_log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// activePath returns the file currently written to, one per day
+func (t *File) activePath(tm time.Time) string {
+	return filepath.Join(t.config.Dir, fmt.Sprintf("%s_%s.log", t.config.FName, tm.Format("2006_01_02")))
+}
+
+func (t *File) open() error {
+	path := t.activePath(time.Now())
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, t.mode)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	t.f = f
+	t.path = path
+	t.size = info.Size()
+
+	return nil
+}
+
+// reopen closes and opens the active file again, used after an external rotation moved it away
+func (t *File) reopen() {
+	if t.f != nil {
+		if err := t.f.Close(); err != nil {
+			fmt.Println("error close log file")
+		}
+
+		t.f = nil
+	}
+
+	if err := t.open(); err != nil {
+		fmt.Println("error open log file")
+	}
+}
+
+// rotate closes the active file, moves it aside when it is still the active one (size limit)
+// and opens a new one. The writer goroutine calls it between two writes, so no line is lost.
+func (t *File) rotate() {
+	old := t.path
+
+	if t.f != nil {
+		if err := t.f.Close(); err != nil {
+			fmt.Println("error close log file")
+		}
+
+		t.f = nil
+	}
+
+	if old == t.activePath(time.Now()) {
+		backup := t.backupPath(old)
+
+		if err := os.Rename(old, backup); err != nil {
+			fmt.Println("error rename log file")
+			backup = ""
+		}
+
+		old = backup
+	}
+
+	if err := t.open(); err != nil {
+		fmt.Println("error open log file")
+	}
+
+	t.wg.Add(1)
+	go t.maintain(old)
+}
+
+// backupPath returns the first free "<name>_<date>.<n>.log" for the active path
+func (t *File) backupPath(path string) string {
+	base := strings.TrimSuffix(path, ".log")
+
+	for n := 1; ; n++ {
+		backup := fmt.Sprintf("%s.%d.log", base, n)
+
+		if !exists(backup) && !exists(backup+".gz") {
+			return backup
+		}
+	}
+}
+
+// maintain compresses a rotated file and removes the backups exceeding max_backups or max_age
+func (t *File) maintain(rotated string) {
+	defer t.wg.Done()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.config.Compress && rotated != "" && exists(rotated) {
+		if err := compress(rotated); err != nil {
+			fmt.Println("error compress log file")
+		}
+	}
+
+	t.cleanup()
+}
+
+func (t *File) cleanup() {
+	if t.config.MaxBackups <= 0 && t.config.MaxAge <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(t.config.Dir)
+
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+
+	prefix := t.config.FName + "_"
+	backups := make([]backup, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) || name[len(prefix)] < '0' || name[len(prefix)] > '9' {
+			continue
+		}
+
+		if !strings.HasSuffix(name, ".log") && !strings.HasSuffix(name, ".log.gz") {
+			continue
+		}
+
+		path := filepath.Join(t.config.Dir, name)
+
+		if path == t.currentPath() {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backup{path: path, modTime: info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	for i, b := range backups {
+		if (t.config.MaxBackups > 0 && i >= t.config.MaxBackups) || (t.config.MaxAge > 0 && time.Since(b.modTime) > t.config.MaxAge) {
+			if err := os.Remove(b.path); err != nil {
+				fmt.Println("error remove log file")
+			}
+		}
+	}
+}
+
+// currentPath is the active path for the maintenance goroutine, path itself belongs to the writer
+func (t *File) currentPath() string {
+	return t.activePath(time.Now())
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	info, err := src.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	_ = os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
@@ -0,0 +1,191 @@
+package file_log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func initFileTest(t *testing.T, cfg map[string]interface{}) (*File, string) {
+	dir := t.TempDir()
+
+	cfg["dir"] = dir
+	cfg["filename"] = "app"
+	cfg["enable"] = true
+
+	l := New("file")
+
+	if err := l.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	return l, dir
+}
+
+func stopFileTest(t *testing.T, l *File) {
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLogLines(t *testing.T, dir string) ([]string, []string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names, lines []string
+
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r io.Reader = f
+
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r = gz
+		}
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		_ = f.Close()
+	}
+
+	return names, lines
+}
+
+func TestFile_RotateSize(t *testing.T) {
+	l, dir := initFileTest(t, map[string]interface{}{
+		"max_size": 1,
+		"compress": true,
+	})
+
+	payload := strings.Repeat("x", 100*1024)
+
+	for i := 0; i < 50; i++ {
+		l.Info(context.Background(), fmt.Sprintf("line-%03d ", i), payload)
+	}
+
+	stopFileTest(t, l)
+
+	names, lines := readLogLines(t, dir)
+
+	gz := 0
+	for _, name := range names {
+		if strings.HasSuffix(name, ".log.gz") {
+			gz++
+		}
+	}
+
+	if gz < 4 {
+		t.Fatalf("expected rotated gzip files, got %v", names)
+	}
+
+	seen := map[string]int{}
+	for _, line := range lines {
+		seen[strings.Fields(line)[3]]++
+	}
+
+	for i := 0; i < 50; i++ {
+		if n := seen[fmt.Sprintf("line-%03d", i)]; n != 1 {
+			t.Fatalf("line-%03d written %d times", i, n)
+		}
+	}
+}
+
+func TestFile_MaxBackups(t *testing.T) {
+	l, dir := initFileTest(t, map[string]interface{}{
+		"max_size":    1,
+		"max_backups": 2,
+	})
+
+	payload := strings.Repeat("x", 100*1024)
+
+	for i := 0; i < 50; i++ {
+		l.Info(context.Background(), payload)
+	}
+
+	stopFileTest(t, l)
+
+	names, _ := readLogLines(t, dir)
+
+	if len(names) != 3 {
+		t.Fatalf("expected active file and 2 backups, got %v", names)
+	}
+}
+
+func TestFile_Reopen(t *testing.T) {
+	l, dir := initFileTest(t, map[string]interface{}{
+		"file_mode": "0600",
+	})
+
+	l.Info(context.Background(), "before")
+	l.queue.Flush(time.Second)
+
+	path := l.activePath(time.Now())
+
+	if err := os.Rename(path, filepath.Join(dir, "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Reopen()
+	time.Sleep(50 * time.Millisecond)
+	l.Info(context.Background(), "after")
+
+	stopFileTest(t, l)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "before") || !strings.Contains(string(data), "after") {
+		t.Fatalf("unexpected content after reopen: %q", data)
+	}
+
+	info, _ := os.Stat(path)
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode %v", info.Mode().Perm())
+	}
+}
+
+func TestFile_FatalFlush(t *testing.T) {
+	l, _ := initFileTest(t, map[string]interface{}{
+		"overflow": OverflowDropOldest,
+	})
+	defer stopFileTest(t, l)
+
+	l.Fatal(context.Background(), errors.New("crash"))
+
+	data, err := os.ReadFile(l.activePath(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "CRITICAL") || !strings.Contains(string(data), "crash") {
+		t.Fatalf("fatal line must be written before Fatal returns: %q", data)
+	}
+}
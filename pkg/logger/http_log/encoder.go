@@ -0,0 +1,273 @@
+package http_log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+// encoder builds the request body for a batch of records
+type encoder interface {
+	encode(batch []record) ([]byte, error)
+	contentType() string
+	// rejected returns the number of records refused in a successful response and the first reason
+	rejected(body io.Reader) (int, string)
+}
+
+// lokiEncoder groups records into streams by their label set, see the Loki push API
+type lokiEncoder struct {
+	labels []string
+	static map[string]string
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (t *lokiEncoder) contentType() string {
+	return "application/json"
+}
+
+func (t *lokiEncoder) rejected(io.Reader) (int, string) {
+	// a push is accepted or refused as a whole
+	return 0, ""
+}
+
+func (t *lokiEncoder) encode(batch []record) ([]byte, error) {
+	streams := make(map[string]*lokiStream)
+	keys := make([]string, 0)
+
+	for i := range batch {
+		r := &batch[i]
+		labels := t.stream(r)
+		key := labelsKey(labels)
+
+		s, ok := streams[key]
+
+		if !ok {
+			s = &lokiStream{Stream: labels}
+			streams[key] = s
+			keys = append(keys, key)
+		}
+
+		s.Values = append(s.Values, [2]string{
+			strconv.FormatInt(r.time.UnixNano(), 10),
+			line(r, "msg", nil),
+		})
+	}
+
+	sort.Strings(keys)
+
+	push := lokiPush{Streams: make([]*lokiStream, 0, len(keys))}
+
+	for _, key := range keys {
+		push.Streams = append(push.Streams, streams[key])
+	}
+
+	return json.Marshal(push)
+}
+
+// stream returns the labels of r: static ones, the level and configured fields
+func (t *lokiEncoder) stream(r *record) map[string]string {
+	labels := make(map[string]string, len(t.static)+len(t.labels)+1)
+
+	for k, v := range t.static {
+		if name := labelName(k); name != "" {
+			labels[name] = v
+		}
+	}
+
+	labels["level"] = strings.ToLower(r.level.String())
+
+	for _, key := range t.labels {
+		name := labelName(key)
+
+		if name == "" {
+			continue
+		}
+
+		for _, f := range r.fields {
+			if f.Key == key {
+				labels[name] = labelValue(f.Value)
+			}
+		}
+	}
+
+	return labels
+}
+
+// labelName returns key as a Loki label name matching [a-zA-Z_][a-zA-Z0-9_]*, e.g. user.id as user_id.
+// It is empty for an empty key, Loki refuses the whole push for an invalid name.
+func labelName(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	res := make([]byte, 0, len(key)+1)
+
+	if key[0] >= '0' && key[0] <= '9' {
+		res = append(res, '_')
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			c = '_'
+		}
+
+		res = append(res, c)
+	}
+
+	return string(res)
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+
+	return b.String()
+}
+
+func labelValue(v interface{}) string {
+	if raw, ok := v.(json.RawMessage); ok {
+		// fields restored from the spill file
+		var s string
+
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+
+		return string(raw)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// bulkEncoder writes records as Elasticsearch bulk index actions
+type bulkEncoder struct {
+	index string
+}
+
+func (t *bulkEncoder) contentType() string {
+	return "application/x-ndjson"
+}
+
+// bulkResponse is the part of the bulk API response reporting the refused documents
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// rejected counts the documents refused with a 200 response and errors set, e.g. on mapping errors
+func (t *bulkEncoder) rejected(body io.Reader) (int, string) {
+	var resp bulkResponse
+
+	if json.NewDecoder(body).Decode(&resp) != nil || !resp.Errors {
+		return 0, ""
+	}
+
+	n := 0
+	reason := ""
+
+	for _, item := range resp.Items {
+		for _, action := range item {
+			if action.Error == nil && action.Status < 300 {
+				continue
+			}
+
+			n++
+
+			if reason == "" && action.Error != nil {
+				reason = action.Error.Type + ": " + action.Error.Reason
+			}
+		}
+	}
+
+	return n, reason
+}
+
+func (t *bulkEncoder) encode(batch []record) ([]byte, error) {
+	action, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": t.index}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	for i := range batch {
+		r := &batch[i]
+
+		b.Write(action)
+		b.WriteByte('\n')
+		b.WriteString(line(r, "message", []field{
+			{Key: "@timestamp", Value: r.time.UTC().Format(time.RFC3339Nano)},
+			{Key: "level", Value: r.level.String()},
+		}))
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes(), nil
+}
+
+// line returns r as a JSON object: head fields, the message under msgKey, then the record fields
+func line(r *record, msgKey string, head []field) string {
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for _, f := range head {
+		b.Write(logcore.JSONValue(f.Key))
+		b.WriteByte(':')
+		b.Write(logcore.JSONValue(f.Value))
+		b.WriteByte(',')
+	}
+
+	b.Write(logcore.JSONValue(msgKey))
+	b.WriteByte(':')
+	b.Write(logcore.JSONValue(r.msg))
+
+	for _, f := range r.fields {
+		b.WriteByte(',')
+		b.Write(logcore.JSONValue(f.Key))
+		b.WriteByte(':')
+		b.Write(logcore.JSONValue(f.Value))
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
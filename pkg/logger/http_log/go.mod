@@ -0,0 +1,10 @@
+module gitlab.com/devpro_studio/Paranoia/pkg/logger/http_log
+
+go 1.23.4
+
+require (
+	gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore v0.0.0-00010101000000-000000000000
+	gitlab.com/devpro_studio/go_utils v1.1.5
+)
+
+replace gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore => ../internal/logcore
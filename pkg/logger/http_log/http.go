@@ -0,0 +1,421 @@
+package http_log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// Http pushes log records in batches to a Loki push or Elasticsearch bulk endpoint.
+// Batches failing after all retries are spilled to a local file and resent once the endpoint is back.
+type Http struct {
+	name    string
+	parent  ILogger
+	config  Config
+	encoder encoder
+	client  *http.Client
+
+	queue   chan record
+	flushCh chan chan struct{}
+	done    chan interface{}
+	stopped chan interface{}
+	closed  atomic.Bool
+	dropped atomic.Int64
+}
+
+type Config struct {
+	Level         LogLevel          `yaml:"level"`
+	Enable        bool              `yaml:"enable"`
+	URL           string            `yaml:"url"`    // e.g. http://loki:3100/loki/api/v1/push or http://es:9200/_bulk
+	Format        string            `yaml:"format"` // loki|elasticsearch
+	Index         string            `yaml:"index"`  // elasticsearch index, default logs
+	Labels        []string          `yaml:"labels"` // field keys used as loki stream labels
+	StaticLabels  map[string]string `yaml:"static_labels"`
+	Headers       map[string]string `yaml:"headers"` // e.g. X-Scope-OrgID
+	Username      string            `yaml:"username"`
+	Password      string            `yaml:"password"`
+	Gzip          bool              `yaml:"gzip"`
+	BatchSize     int               `yaml:"batch_size"`     // records per request, default 500
+	FlushInterval time.Duration     `yaml:"flush_interval"` // default 1s
+	Timeout       time.Duration     `yaml:"timeout"`        // request timeout, default 10s
+	MaxRetries    int               `yaml:"max_retries"`    // default 3
+	RetryBackoff  time.Duration     `yaml:"retry_backoff"`  // first retry delay, doubled up to 30s, default 500ms
+	SpillFile     string            `yaml:"spill_file"`     // batches that could not be sent, empty - dropped
+	SpillMaxSize  int64             `yaml:"spill_max_size"` // megabytes, default 100
+	QueueSize     int               `yaml:"queue_size"`     // default 10000, records are dropped when full
+	StopTimeout   time.Duration     `yaml:"stop_timeout"`   // default 10s
+}
+
+type record struct {
+	time   time.Time
+	level  LogLevel
+	msg    string
+	fields []field
+}
+
+func New(name string) *Http {
+	return &Http{
+		name: name,
+	}
+}
+
+func (t *Http) Init(cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if t.config.URL == "" {
+		return errors.New("url is required")
+	}
+
+	switch t.config.Format {
+	case "", "loki":
+		t.encoder = &lokiEncoder{labels: t.config.Labels, static: t.config.StaticLabels}
+	case "elasticsearch":
+		if t.config.Index == "" {
+			t.config.Index = "logs"
+		}
+
+		t.encoder = &bulkEncoder{index: t.config.Index}
+	default:
+		return fmt.Errorf("unknown format: %s", t.config.Format)
+	}
+
+	if t.config.BatchSize <= 0 {
+		t.config.BatchSize = 500
+	}
+
+	if t.config.FlushInterval <= 0 {
+		t.config.FlushInterval = time.Second
+	}
+
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 10 * time.Second
+	}
+
+	if t.config.MaxRetries < 0 {
+		t.config.MaxRetries = 0
+	} else if t.config.MaxRetries == 0 {
+		t.config.MaxRetries = 3
+	}
+
+	if t.config.RetryBackoff <= 0 {
+		t.config.RetryBackoff = 500 * time.Millisecond
+	}
+
+	if t.config.SpillMaxSize <= 0 {
+		t.config.SpillMaxSize = 100
+	}
+
+	if t.config.QueueSize <= 0 {
+		t.config.QueueSize = 10000
+	}
+
+	if t.config.StopTimeout <= 0 {
+		t.config.StopTimeout = 10 * time.Second
+	}
+
+	t.client = &http.Client{Timeout: t.config.Timeout}
+	t.queue = make(chan record, t.config.QueueSize)
+	t.flushCh = make(chan chan struct{})
+	t.done = make(chan interface{})
+	t.stopped = make(chan interface{})
+
+	go t.run()
+
+	return nil
+}
+
+func (t *Http) Stop() error {
+	var err error
+
+	if t.done != nil && !t.closed.Swap(true) {
+		close(t.done)
+
+		select {
+		case <-t.stopped:
+		case <-time.After(t.config.StopTimeout):
+			err = fmt.Errorf("http log not flushed in %s", t.config.StopTimeout)
+		}
+	}
+
+	if t.parent != nil {
+		if parentErr := t.parent.Stop(); err == nil {
+			err = parentErr
+		}
+	}
+
+	return err
+}
+
+func (t *Http) Name() string {
+	return t.name
+}
+
+func (t *Http) Type() string {
+	return "logger"
+}
+
+// Dropped returns the number of records discarded because the queue was full, the logger stopped,
+// the spill file was full or the endpoint refused them one by one
+func (t *Http) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+func (t *Http) SetLevel(level int) {
+	t.config.Level = LogLevel(level)
+
+	if t.parent != nil {
+		t.parent.SetLevel(level)
+	}
+}
+
+func (t *Http) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]record, 0, t.config.BatchSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the spill file is resent once a live batch is delivered, or with a backoff while the endpoint is down
+	var live bool
+	var resendAt time.Time
+	var resendDelay time.Duration
+
+	send := func() {
+		if len(batch) > 0 {
+			live = t.send(ctx, batch) || live
+			batch = batch[:0]
+		}
+	}
+
+	resend := func() {
+		if !live && time.Now().Before(resendAt) {
+			return
+		}
+
+		live = false
+
+		if err := t.resend(ctx); err != nil {
+			fmt.Printf("http log: spill file not resent: %s\n", err)
+
+			resendDelay = min(max(resendDelay*2, t.config.FlushInterval), maxBackoff)
+			resendAt = time.Now().Add(resendDelay)
+
+			return
+		}
+
+		resendDelay = 0
+		resendAt = time.Time{}
+	}
+
+	drain := func() {
+		for {
+			select {
+			case r := <-t.queue:
+				batch = append(batch, r)
+
+				if len(batch) >= t.config.BatchSize {
+					send()
+				}
+
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case r := <-t.queue:
+			batch = append(batch, r)
+
+			if len(batch) >= t.config.BatchSize {
+				send()
+			}
+
+		case <-ticker.C:
+			send()
+			resend()
+
+		case ack := <-t.flushCh:
+			drain()
+			close(ack)
+
+		case <-t.done:
+			// bound the final delivery, whatever is left goes to the spill file
+			timer := time.AfterFunc(t.config.StopTimeout*3/4, cancel)
+			drain()
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// send delivers a batch, retrying with backoff, and spills it when the endpoint stays unavailable.
+// It returns whether the endpoint accepted the batch.
+func (t *Http) send(ctx context.Context, batch []record) bool {
+	err := t.deliver(ctx, batch, t.config.MaxRetries)
+
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, errRejected) {
+		fmt.Printf("http log: batch of %d records rejected: %s\n", len(batch), err)
+		return false
+	}
+
+	if t.config.SpillFile == "" {
+		fmt.Printf("http log: batch of %d records dropped: %s\n", len(batch), err)
+		return false
+	}
+
+	if spillErr := t.spill(batch); spillErr != nil {
+		fmt.Printf("http log: batch of %d records dropped: %s\n", len(batch), spillErr)
+	}
+
+	return false
+}
+
+func (t *Http) flush(timeout time.Duration) {
+	ack := make(chan struct{})
+
+	select {
+	case t.flushCh <- ack:
+	case <-t.stopped:
+		return
+	case <-time.After(timeout):
+		return
+	}
+
+	select {
+	case <-ack:
+	case <-time.After(timeout):
+	}
+}
+
+func (t *Http) push(level LogLevel, msg string, fields []field) {
+	if !t.config.Enable || t.queue == nil {
+		return
+	}
+
+	if t.closed.Load() {
+		t.dropped.Add(1)
+		return
+	}
+
+	r := record{time: time.Now(), level: level, msg: msg, fields: fields}
+
+	if level == CRITICAL {
+		// crash records are waited for and delivered before Fatal and Panic return
+		select {
+		case t.queue <- r:
+		case <-t.done:
+			t.dropped.Add(1)
+		}
+
+		t.flush(t.config.StopTimeout)
+
+		return
+	}
+
+	select {
+	case t.queue <- r:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *Http) Debug(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(DEBUG, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Debug(ctx, args...)
+		}
+	}
+}
+
+func (t *Http) Info(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(INFO, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Info(ctx, args...)
+		}
+	}
+}
+
+func (t *Http) Warn(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(WARNING, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Warn(ctx, args...)
+		}
+	}
+}
+
+func (t *Http) Message(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(MESSAGE, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Message(ctx, args...)
+		}
+	}
+}
+
+func (t *Http) Error(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		t.push(ERROR, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Error(ctx, err)
+		}
+	}
+}
+
+func (t *Http) Fatal(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Fatal(ctx, err)
+		}
+	}
+}
+
+func (t *Http) Panic(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Panic(ctx, err)
+		}
+	}
+}
+
+func (t *Http) Parent() interface{} {
+	return t.parent
+}
+
+func (t *Http) SetParent(parent interface{}) {
+	t.parent = parent.(ILogger)
+}
@@ -0,0 +1,474 @@
+package http_log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+type fieldArg struct {
+	key   string
+	value interface{}
+}
+
+func (t fieldArg) LogField() (string, interface{}) {
+	return t.key, t.value
+}
+
+type stub struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	failures atomic.Int32
+}
+
+func (t *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if t.failures.Load() > 0 {
+		t.failures.Add(-1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var reader io.Reader = r.Body
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		reader = gz
+	}
+
+	body, _ := io.ReadAll(reader)
+
+	t.mu.Lock()
+	t.bodies = append(t.bodies, body)
+	t.headers = append(t.headers, r.Header.Clone())
+	t.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (t *stub) requests() ([][]byte, []http.Header) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([][]byte(nil), t.bodies...), append([]http.Header(nil), t.headers...)
+}
+
+func newLogger(t *testing.T, cfg map[string]interface{}) *Http {
+	l := New("http")
+
+	if err := l.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestHttp_LokiLabels(t *testing.T) {
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":        true,
+		"url":           srv.URL,
+		"labels":        []string{"service"},
+		"static_labels": map[string]string{"env": "test"},
+		"gzip":          true,
+		"headers":       map[string]string{"X-Scope-OrgID": "tenant"},
+	})
+
+	ctx := context.WithValue(context.Background(), logcore.FieldsKey, []interface{}{"service", "orders"})
+
+	l.Info(ctx, "created", fieldArg{"id", 7})
+	l.Warn(context.Background(), "slow")
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, headers := s.requests()
+
+	if len(bodies) != 1 {
+		t.Fatalf("expected one batch, got %d", len(bodies))
+	}
+
+	if headers[0].Get("Content-Encoding") != "gzip" || headers[0].Get("X-Scope-OrgID") != "tenant" {
+		t.Fatalf("unexpected headers %v", headers[0])
+	}
+
+	var push lokiPush
+
+	if err := json.Unmarshal(bodies[0], &push); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(push.Streams) != 2 {
+		t.Fatalf("expected two streams, got %s", bodies[0])
+	}
+
+	var info *lokiStream
+
+	for _, st := range push.Streams {
+		if st.Stream["level"] == "info" {
+			info = st
+		}
+
+		if st.Stream["env"] != "test" {
+			t.Fatalf("static label missing: %v", st.Stream)
+		}
+	}
+
+	if info == nil || info.Stream["service"] != "orders" || len(info.Values) != 1 {
+		t.Fatalf("unexpected info stream %s", bodies[0])
+	}
+
+	if info.Values[0][1] != `{"msg":"created","service":"orders","id":7}` {
+		t.Fatalf("unexpected line %s", info.Values[0][1])
+	}
+}
+
+func TestHttp_LokiLabelName(t *testing.T) {
+	names := map[string]string{
+		"service": "service", "user.id": "user_id", "http-status": "http_status",
+		"1st": "_1st", "_x": "_x", "ключ": "________", "": "",
+	}
+
+	for key, expected := range names {
+		if res := labelName(key); res != expected {
+			t.Fatalf("%q: expected %q, got %q", key, expected, res)
+		}
+	}
+
+	e := &lokiEncoder{labels: []string{"user.id", ""}, static: map[string]string{"k8s.ns": "prod"}}
+	labels := e.stream(&record{level: INFO, fields: []field{{Key: "user.id", Value: 7}, {Key: "", Value: 1}}})
+
+	if labels["user_id"] != "7" || labels["k8s_ns"] != "prod" || len(labels) != 3 {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+}
+
+func TestHttp_ElasticsearchBulk(t *testing.T) {
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	l := newLogger(t, map[string]interface{}{
+		"enable": true,
+		"url":    srv.URL,
+		"format": "elasticsearch",
+		"index":  "app",
+	})
+
+	l.Error(context.Background(), errors.New("failed"))
+	l.Debug(context.Background(), "started", fieldArg{"port", 8080})
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, headers := s.requests()
+
+	if len(bodies) != 1 || headers[0].Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected requests %q", bodies)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(bodies[0]), "\n"), "\n")
+
+	if len(lines) != 4 || lines[0] != `{"index":{"_index":"app"}}` {
+		t.Fatalf("unexpected bulk body %s", bodies[0])
+	}
+
+	var doc map[string]interface{}
+
+	if err := json.Unmarshal([]byte(lines[3]), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["level"] != "DEBUG" || doc["message"] != "started" || doc["port"] != float64(8080) || doc["@timestamp"] == nil {
+		t.Fatalf("unexpected document %s", lines[3])
+	}
+}
+
+func TestHttp_ElasticsearchItemErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"took":3,"errors":true,"items":[` +
+			`{"index":{"_index":"app","status":201}},` +
+			`{"index":{"_index":"app","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [port]"}}}]}`))
+	}))
+	defer srv.Close()
+
+	spill := filepath.Join(t.TempDir(), "spill.log")
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":     true,
+		"url":        srv.URL,
+		"format":     "elasticsearch",
+		"spill_file": spill,
+	})
+
+	l.Info(context.Background(), "ok")
+	l.Info(context.Background(), "bad", fieldArg{"port", "http"})
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 1 || l.Dropped() != 1 {
+		t.Fatalf("want one request and one dropped record, got %d and %d", calls.Load(), l.Dropped())
+	}
+
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Fatal("refused records must not be spilled")
+	}
+}
+
+func TestHttp_BatchSize(t *testing.T) {
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":         true,
+		"url":            srv.URL,
+		"batch_size":     2,
+		"flush_interval": time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		l.Info(context.Background(), "record")
+	}
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bodies, _ := s.requests(); len(bodies) != 3 {
+		t.Fatalf("expected three batches, got %d", len(bodies))
+	}
+}
+
+func TestHttp_Retry(t *testing.T) {
+	s := &stub{}
+	s.failures.Store(2)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":        true,
+		"url":           srv.URL,
+		"retry_backoff": time.Millisecond,
+	})
+
+	l.Info(context.Background(), "record")
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bodies, _ := s.requests(); len(bodies) != 1 {
+		t.Fatal("batch must be delivered after retries")
+	}
+}
+
+func TestHttp_Rejected(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	spill := filepath.Join(t.TempDir(), "spill.log")
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":        true,
+		"url":           srv.URL,
+		"retry_backoff": time.Millisecond,
+		"spill_file":    spill,
+	})
+
+	l.Info(context.Background(), "record")
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("rejected batch must not be retried, got %d calls", calls.Load())
+	}
+
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Fatal("rejected batch must not be spilled")
+	}
+}
+
+func TestHttp_SpillAndResend(t *testing.T) {
+	s := &stub{}
+	s.failures.Store(1000)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	spill := filepath.Join(t.TempDir(), "spill.log")
+	cfg := map[string]interface{}{
+		"enable":         true,
+		"url":            srv.URL,
+		"max_retries":    1,
+		"retry_backoff":  time.Millisecond,
+		"flush_interval": 10 * time.Millisecond,
+		"spill_file":     spill,
+	}
+
+	l := newLogger(t, cfg)
+
+	ctx := context.WithValue(context.Background(), logcore.FieldsKey, []interface{}{"service", "orders"})
+	l.Info(ctx, "lost")
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countLines(t, spill); n != 1 {
+		t.Fatalf("expected one spilled record, got %d", n)
+	}
+
+	s.failures.Store(0)
+	cfg["labels"] = []string{"service"}
+	l = newLogger(t, cfg)
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		if _, err := os.Stat(spill); os.IsNotExist(err) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("spill file not resent")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, _ := s.requests()
+
+	if len(bodies) != 1 || !bytes.Contains(bodies[0], []byte(`"service":"orders"`)) || !bytes.Contains(bodies[0], []byte(`\"msg\":\"lost\"`)) {
+		t.Fatalf("unexpected resent batch %q", bodies)
+	}
+}
+
+func TestHttp_ResendTail(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		if calls.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	spill := filepath.Join(dir, "spill.log")
+
+	prev := &Http{config: Config{SpillFile: spill, SpillMaxSize: 1}}
+
+	if err := prev.spill([]record{{msg: "a"}, {msg: "b"}, {msg: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":         true,
+		"url":            srv.URL,
+		"batch_size":     1,
+		"flush_interval": 10 * time.Millisecond,
+		"spill_file":     spill,
+	})
+
+	// the first batch is delivered, the endpoint is then down and resends back off
+	time.Sleep(300 * time.Millisecond)
+
+	if err := l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := calls.Load(); n < 2 || n > 8 {
+		t.Fatalf("want resends to back off, got %d requests", n)
+	}
+
+	if n := countLines(t, spill); n != 2 {
+		t.Fatalf("want the two undelivered records kept, got %d", n)
+	}
+
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) > 0 {
+		t.Fatalf("temporary files left: %v", tmp)
+	}
+}
+
+func TestHttp_FatalFlush(t *testing.T) {
+	s := &stub{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	l := newLogger(t, map[string]interface{}{
+		"enable":         true,
+		"url":            srv.URL,
+		"flush_interval": time.Hour,
+	})
+	defer l.Stop()
+
+	l.Fatal(context.Background(), errors.New("crash"))
+
+	if bodies, _ := s.requests(); len(bodies) != 1 || !bytes.Contains(bodies[0], []byte("crash")) {
+		t.Fatal("critical record must be delivered before Fatal returns")
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	f, err := os.Open(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,193 @@
+package http_log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+// spillRecord is a record stored in the spill file, one JSON object per line
+type spillRecord struct {
+	Time   time.Time    `json:"time"`
+	Level  LogLevel     `json:"level"`
+	Msg    string       `json:"msg"`
+	Fields []spillField `json:"fields,omitempty"`
+}
+
+type spillField struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// spill appends batch to the spill file, the batch is dropped once the file reaches spill_max_size
+func (t *Http) spill(batch []record) error {
+	f, err := os.OpenFile(t.config.SpillFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() >= t.config.SpillMaxSize*1024*1024 {
+		t.dropped.Add(int64(len(batch)))
+		return fmt.Errorf("spill file %s is full", t.config.SpillFile)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	for i := range batch {
+		r := &batch[i]
+		s := spillRecord{Time: r.time, Level: r.level, Msg: r.msg, Fields: make([]spillField, 0, len(r.fields))}
+
+		for _, fl := range r.fields {
+			s.Fields = append(s.Fields, spillField{Key: fl.Key, Value: logcore.JSONValue(fl.Value)})
+		}
+
+		if err = enc.Encode(s); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// resend replays the spill file batch by batch with a single attempt each. The file is streamed,
+// on the first failed batch the undelivered tail replaces the spill file through a rename.
+func (t *Http) resend(ctx context.Context) error {
+	if t.config.SpillFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(t.config.SpillFile)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	batch := make([]record, 0, t.config.BatchSize)
+	lines := make([][]byte, 0, t.config.BatchSize)
+	sent := 0
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+
+		if r, ok := parseSpill(line); ok {
+			batch = append(batch, r)
+			lines = append(lines, line)
+		}
+
+		if len(batch) > 0 && (len(batch) >= t.config.BatchSize || readErr != nil) {
+			if err = t.deliver(ctx, batch, 0); err != nil {
+				break
+			}
+
+			sent += len(batch)
+			batch = batch[:0]
+			lines = lines[:0]
+		}
+
+		if readErr == io.EOF {
+			return os.Remove(t.config.SpillFile)
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	if sent == 0 {
+		return err
+	}
+
+	if tailErr := t.replaceSpill(lines, reader); tailErr != nil {
+		return tailErr
+	}
+
+	return err
+}
+
+// replaceSpill writes the undelivered lines followed by the rest of the spill file to a temporary
+// file renamed over the spill file, so that a crash never loses the tail
+func (t *Http) replaceSpill(lines [][]byte, rest io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(t.config.SpillFile), filepath.Base(t.config.SpillFile)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+
+	for _, line := range lines {
+		if _, err = w.Write(line); err == nil && !bytes.HasSuffix(line, []byte{'\n'}) {
+			err = w.WriteByte('\n')
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		_, err = io.Copy(w, rest)
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), t.config.SpillFile)
+}
+
+// parseSpill decodes a line of the spill file, lines cut by a crash are skipped
+func parseSpill(line []byte) (record, bool) {
+	var s spillRecord
+
+	if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &s) != nil {
+		return record{}, false
+	}
+
+	r := record{time: s.Time, level: s.Level, msg: s.Msg, fields: make([]field, 0, len(s.Fields))}
+
+	for _, fl := range s.Fields {
+		r.fields = append(r.fields, field{Key: fl.Key, Value: fl.Value})
+	}
+
+	return r, true
+}
@@ -0,0 +1,115 @@
+package http_log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxBackoff caps the delay between retries
+const maxBackoff = 30 * time.Second
+
+// errRejected marks batches refused by the endpoint, they are not retried or spilled
+var errRejected = errors.New("rejected")
+
+// deliver sends batch, retrying network errors, 429 and 5xx responses up to retries times
+func (t *Http) deliver(ctx context.Context, batch []record, retries int) error {
+	body, err := t.encoder.encode(batch)
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", errRejected, err)
+	}
+
+	if t.config.Gzip {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+
+		if _, err = w.Write(body); err == nil {
+			err = w.Close()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		body = b.Bytes()
+	}
+
+	backoff := t.config.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err = t.post(ctx, body)
+
+		if err == nil || errors.Is(err, errRejected) || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (t *Http) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", errRejected, err)
+	}
+
+	req.Header.Set("Content-Type", t.encoder.contentType())
+
+	if t.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	for k, v := range t.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if t.config.Username != "" {
+		req.SetBasicAuth(t.config.Username, t.config.Password)
+	}
+
+	resp, err := t.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		// records refused one by one are not retried, resending the batch would duplicate the others
+		if n, reason := t.encoder.rejected(resp.Body); n > 0 {
+			t.dropped.Add(int64(n))
+			fmt.Printf("http log: %d records rejected: %s\n", n, reason)
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	default:
+		return fmt.Errorf("%w: status %d: %s", errRejected, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+}
@@ -0,0 +1,148 @@
+// Package logcore holds the record queue and the structured field helpers shared by the loggers
+package logcore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldsKey is the context key holding structured fields as alternating key/value pairs
+const FieldsKey = "log_fields"
+
+// LevelKey is the context key holding the level override of a named logger
+const LevelKey = "log_level"
+
+// logField is implemented by structured fields passed among the log args
+type logField interface {
+	LogField() (string, interface{})
+}
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// CtxFields returns the structured fields carried in ctx
+func CtxFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	kv, ok := ctx.Value(FieldsKey).([]interface{})
+
+	if !ok || len(kv) == 0 {
+		return nil
+	}
+
+	res := make([]Field, 0, len(kv)/2+1)
+
+	for i := 0; i < len(kv); i += 2 {
+		var val interface{}
+
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+
+		res = append(res, Field{Key: fmt.Sprint(kv[i]), Value: val})
+	}
+
+	return res
+}
+
+// Collect splits args into the message text and structured fields, ctx fields go first
+func Collect(ctx context.Context, args []interface{}) (string, []Field) {
+	fields := CtxFields(ctx)
+	rest := make([]interface{}, 0, len(args))
+
+	for _, arg := range args {
+		if f, ok := arg.(logField); ok {
+			k, v := f.LogField()
+			fields = append(fields, Field{Key: k, Value: v})
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return fmt.Sprint(rest...), fields
+}
+
+// CtxLevel returns the level override carried in ctx, otherwise def
+func CtxLevel(ctx context.Context, def int) int {
+	if ctx == nil {
+		return def
+	}
+
+	if level, ok := ctx.Value(LevelKey).(int); ok {
+		return level
+	}
+
+	return def
+}
+
+// FormatText renders fields as " key=value" pairs
+func FormatText(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+
+		v := fmt.Sprint(f.Value)
+
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+
+		b.WriteString(v)
+	}
+
+	return b.String()
+}
+
+// FormatJSON renders a record as a single JSON object line
+func FormatJSON(level string, tm time.Time, msg string, fields []Field) string {
+	var b strings.Builder
+
+	b.WriteString(`{"time":`)
+	b.Write(JSONValue(tm.Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":`)
+	b.Write(JSONValue(level))
+	b.WriteString(`,"msg":`)
+	b.Write(JSONValue(msg))
+
+	for _, f := range fields {
+		b.WriteByte(',')
+		b.Write(JSONValue(f.Key))
+		b.WriteByte(':')
+		b.Write(JSONValue(f.Value))
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// JSONValue returns v as JSON, errors as their message and values failing to marshal as text
+func JSONValue(v interface{}) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	return b
+}
@@ -0,0 +1,50 @@
+package logcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testField struct {
+	key   string
+	value interface{}
+}
+
+func (t testField) LogField() (string, interface{}) {
+	return t.key, t.value
+}
+
+func TestCollect(t *testing.T) {
+	ctx := context.WithValue(context.Background(), FieldsKey, []interface{}{"path", "/api"})
+
+	msg, fields := Collect(ctx, []interface{}{"user ", 10, testField{"user_id", 10}, " logged in"})
+
+	if msg != "user 10 logged in" {
+		t.Fatalf("msg = %q", msg)
+	}
+
+	if got := FormatText(fields); got != " path=/api user_id=10" {
+		t.Fatalf("FormatText() = %q", got)
+	}
+}
+
+func TestFormatText_Quote(t *testing.T) {
+	got := FormatText([]Field{{"q", "a b"}, {"e", ""}})
+
+	if got != ` q="a b" e=""` {
+		t.Fatalf("FormatText() = %q", got)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	tm := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := FormatJSON("INFO", tm, "done", []Field{{"user_id", 10}, {"err", errors.New("failed")}, {"tags", []string{"a"}}})
+	want := `{"time":"2025-01-02T03:04:05Z","level":"INFO","msg":"done","user_id":10,"err":"failed","tags":["a"]}`
+
+	if got != want {
+		t.Fatalf("FormatJSON() = %s, want %s", got, want)
+	}
+}
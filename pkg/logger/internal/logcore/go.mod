@@ -0,0 +1,3 @@
+module gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore
+
+go 1.23.4
@@ -0,0 +1,178 @@
+package logcore
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow policies applied when the queue is full
+const (
+	OverflowBlock      = "block"       // wait for a free slot
+	OverflowDropNewest = "drop_newest" // discard the line being pushed
+	OverflowDropOldest = "drop_oldest" // discard the oldest queued line
+)
+
+// Queue is a bounded queue of formatted lines drained by a single writer goroutine. The writer
+// reads Lines, answers Flushes by draining, drains once Done is closed and then calls WriterDone.
+type Queue struct {
+	lines   chan string
+	flushCh chan chan struct{}
+	done    chan interface{}
+	stopped chan interface{}
+	policy  string
+
+	closed  atomic.Bool
+	dropped atomic.Int64
+}
+
+func NewQueue(size int, policy string) (*Queue, error) {
+	switch policy {
+	case "":
+		policy = OverflowBlock
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy: %s", policy)
+	}
+
+	if size <= 0 {
+		size = 1000
+	}
+
+	return &Queue{
+		lines:   make(chan string, size),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan interface{}),
+		stopped: make(chan interface{}),
+		policy:  policy,
+	}, nil
+}
+
+// Lines returns the queued lines
+func (t *Queue) Lines() <-chan string {
+	return t.lines
+}
+
+// Flushes returns the flush requests, the writer drains the queue and closes the ack
+func (t *Queue) Flushes() <-chan chan struct{} {
+	return t.flushCh
+}
+
+// Done is closed by Close, the writer then drains the queue and returns
+func (t *Queue) Done() <-chan interface{} {
+	return t.done
+}
+
+// WriterDone is called by the writer goroutine once it has returned
+func (t *Queue) WriterDone() {
+	close(t.stopped)
+}
+
+// Dropped returns the number of lines discarded by the overflow policy or pushed after Close
+func (t *Queue) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+// Push queues a line according to the overflow policy, force always waits for a slot.
+// Lines pushed after Close are dropped.
+func (t *Queue) Push(line string, force bool) {
+	if t.closed.Load() {
+		t.dropped.Add(1)
+		return
+	}
+
+	policy := t.policy
+
+	if force {
+		policy = OverflowBlock
+	}
+
+	switch policy {
+	case OverflowDropNewest:
+		select {
+		case t.lines <- line:
+		default:
+			t.dropped.Add(1)
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case t.lines <- line:
+				return
+			default:
+			}
+
+			select {
+			case <-t.lines:
+				t.dropped.Add(1)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case t.lines <- line:
+		case <-t.done:
+			t.dropped.Add(1)
+		}
+	}
+}
+
+// Flush waits until the writer has written all lines queued before the call
+func (t *Queue) Flush(timeout time.Duration) bool {
+	ack := make(chan struct{})
+
+	select {
+	case t.flushCh <- ack:
+	case <-t.stopped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+
+	select {
+	case <-ack:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Drain writes queued lines until the queue is empty or the deadline passes
+func (t *Queue) Drain(write func(string), deadline time.Time) {
+	for {
+		select {
+		case m := <-t.lines:
+			write(m)
+
+		default:
+			return
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+	}
+}
+
+// Close stops accepting lines and waits up to timeout for the writer to drain the queue
+func (t *Queue) Close(timeout time.Duration) error {
+	if t.closed.Swap(true) {
+		return nil
+	}
+
+	close(t.done)
+
+	select {
+	case <-t.stopped:
+	case <-time.After(timeout):
+		return fmt.Errorf("log queue not drained in %s, %d lines left", timeout, len(t.lines))
+	}
+
+	if n := t.dropped.Load(); n > 0 {
+		fmt.Printf("logger: %d lines dropped\n", n)
+	}
+
+	return nil
+}
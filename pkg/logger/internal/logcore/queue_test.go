@@ -0,0 +1,53 @@
+package logcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueue_DropNewest(t *testing.T) {
+	q, _ := NewQueue(2, OverflowDropNewest)
+
+	q.Push("1", false)
+	q.Push("2", false)
+	q.Push("3", false)
+
+	if q.Dropped() != 1 || <-q.lines != "1" || <-q.lines != "2" {
+		t.Fatal("newest line must be dropped")
+	}
+}
+
+func TestQueue_DropOldest(t *testing.T) {
+	q, _ := NewQueue(2, OverflowDropOldest)
+
+	q.Push("1", false)
+	q.Push("2", false)
+	q.Push("3", false)
+
+	if q.Dropped() != 1 || <-q.lines != "2" || <-q.lines != "3" {
+		t.Fatal("oldest line must be dropped")
+	}
+}
+
+func TestQueue_ForceIgnoresPolicy(t *testing.T) {
+	q, _ := NewQueue(1, OverflowDropNewest)
+
+	q.Push("1", false)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-q.lines
+	}()
+
+	q.Push("crash", true)
+
+	if q.Dropped() != 0 || <-q.lines != "crash" {
+		t.Fatal("forced line must wait for a slot")
+	}
+}
+
+func TestQueue_UnknownPolicy(t *testing.T) {
+	if _, err := NewQueue(1, "drop_all"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,14 @@
+package mock_log
+
+import (
+	"context"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+type field = logcore.Field
+
+// ctxLevel returns the level override carried in ctx, otherwise the configured level def
+func ctxLevel(ctx context.Context, def LogLevel) LogLevel {
+	return LogLevel(logcore.CtxLevel(ctx, int(def)))
+}
@@ -1,3 +1,7 @@
 module gitlab.com/devpro_studio/Paranoia/pkg/logger/mock_log
 
 go 1.23.4
+
+require gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore v0.0.0-00010101000000-000000000000
+
+replace gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore => ../internal/logcore
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
 )
 
 var levelColor = map[LogLevel]string{
@@ -43,40 +45,44 @@ func (t *Mock) SetLevel(level int) {
 
 }
 
-func (t *Mock) push(level LogLevel, msg string) {
+func (t *Mock) push(level LogLevel, msg string, fields []field) {
 	if !t.enable {
 		return
 	}
 
-	fmt.Printf("%s%s\u001B[0m [\033[37m%s\033[0m] %s\n", levelColor[level], level.String(), time.Now().Format("2006-01-02 15:04.05"), msg)
+	fmt.Printf("%s%s\u001B[0m [\033[37m%s\033[0m] %s%s\n", levelColor[level], level.String(), time.Now().Format("2006-01-02 15:04.05"), msg, logcore.FormatText(fields))
 }
 
 func (t *Mock) Debug(ctx context.Context, args ...interface{}) {
-	t.push(DEBUG, fmt.Sprint(args...))
+	msg, fields := logcore.Collect(ctx, args)
+	t.push(DEBUG, msg, fields)
 }
 
 func (t *Mock) Info(ctx context.Context, args ...interface{}) {
-	t.push(INFO, fmt.Sprint(args...))
+	msg, fields := logcore.Collect(ctx, args)
+	t.push(INFO, msg, fields)
 }
 
 func (t *Mock) Warn(ctx context.Context, args ...interface{}) {
-	t.push(WARNING, fmt.Sprint(args...))
+	msg, fields := logcore.Collect(ctx, args)
+	t.push(WARNING, msg, fields)
 }
 
 func (t *Mock) Message(ctx context.Context, args ...interface{}) {
-	t.push(MESSAGE, fmt.Sprint(args...))
+	msg, fields := logcore.Collect(ctx, args)
+	t.push(MESSAGE, msg, fields)
 }
 
 func (t *Mock) Error(ctx context.Context, err error) {
-	t.push(ERROR, err.Error())
+	t.push(ERROR, err.Error(), logcore.CtxFields(ctx))
 }
 
 func (t *Mock) Fatal(ctx context.Context, err error) {
-	t.push(CRITICAL, err.Error())
+	t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 }
 
 func (t *Mock) Panic(ctx context.Context, err error) {
-	t.push(CRITICAL, err.Error())
+	t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 }
 
 func (t *Mock) Parent() interface{} {
@@ -0,0 +1,52 @@
+package redact_log
+
+import (
+	"context"
+	"fmt"
+)
+
+// fieldsKey is the context key holding structured fields as alternating key/value pairs
+const fieldsKey = "log_fields"
+
+// logField is implemented by structured fields passed among the log args
+type logField interface {
+	LogField() (string, interface{})
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (t field) LogField() (string, interface{}) {
+	return t.key, t.value
+}
+
+// ctxKV returns the structured fields carried in ctx as key/value pairs
+func ctxKV(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	kv, _ := ctx.Value(fieldsKey).([]interface{})
+
+	return kv
+}
+
+// split separates the message args from the structured fields
+func split(args []interface{}) (string, []field) {
+	var fields []field
+	rest := make([]interface{}, 0, len(args))
+
+	for _, arg := range args {
+		if f, ok := arg.(logField); ok {
+			k, v := f.LogField()
+			fields = append(fields, field{key: k, value: v})
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return fmt.Sprint(rest...), fields
+}
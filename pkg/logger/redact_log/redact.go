@@ -0,0 +1,352 @@
+package redact_log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// Built-in patterns, selected by name in the patterns config
+var builtinPatterns = map[string]string{
+	"jwt":           `eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+	"card":          `\b\d(?:[ -]?\d){12,18}\b`,
+	"email":         `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"authorization": `(?i)(authorization["']?\s*[:=]\s*["']?)(?:(?:bearer|basic|digest|token)\s+)?[^\s"',;]+`,
+}
+
+// Redact is a logger chain stage replacing sensitive data in messages, errors and fields
+// before they reach the output loggers, sentry included.
+type Redact struct {
+	name   string
+	parent ILogger
+	config Config
+
+	patterns []*regexp.Regexp
+	card     *regexp.Regexp
+	keys     map[string]bool
+}
+
+type Config struct {
+	Patterns    []string `yaml:"patterns"`    // built-in patterns: jwt, card, email, authorization; default all
+	Custom      []string `yaml:"custom"`      // additional regular expressions, the first capture group is kept
+	Keys        []string `yaml:"keys"`        // field keys whose values are always replaced, default authorization, password, token
+	Replacement string   `yaml:"replacement"` // default [REDACTED]
+}
+
+func New(name string) *Redact {
+	return &Redact{
+		name: name,
+	}
+}
+
+func (t *Redact) Init(cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if t.config.Patterns == nil {
+		t.config.Patterns = []string{"jwt", "card", "email", "authorization"}
+	}
+
+	if t.config.Keys == nil {
+		t.config.Keys = []string{"authorization", "password", "token"}
+	}
+
+	if t.config.Replacement == "" {
+		t.config.Replacement = "[REDACTED]"
+	}
+
+	t.patterns = t.patterns[:0]
+	t.card = nil
+
+	for _, name := range t.config.Patterns {
+		expr, ok := builtinPatterns[name]
+
+		if !ok {
+			return fmt.Errorf("unknown redact pattern: %s", name)
+		}
+
+		re := regexp.MustCompile(expr)
+
+		if name == "card" {
+			// replaced separately, only digit runs passing the Luhn check
+			t.card = re
+			continue
+		}
+
+		t.patterns = append(t.patterns, re)
+	}
+
+	for _, expr := range t.config.Custom {
+		re, err := regexp.Compile(expr)
+
+		if err != nil {
+			return fmt.Errorf("redact pattern %s: %w", expr, err)
+		}
+
+		t.patterns = append(t.patterns, re)
+	}
+
+	t.keys = make(map[string]bool, len(t.config.Keys))
+
+	for _, k := range t.config.Keys {
+		t.keys[strings.ToLower(k)] = true
+	}
+
+	return nil
+}
+
+func (t *Redact) Stop() error {
+	if t.parent != nil {
+		return t.parent.Stop()
+	}
+
+	return nil
+}
+
+func (t *Redact) Name() string {
+	return t.name
+}
+
+func (t *Redact) Type() string {
+	return "logger"
+}
+
+// LogStage marks the logger as a chain stage, the engine keeps stages ahead of the output loggers
+func (t *Redact) LogStage() {}
+
+func (t *Redact) SetLevel(level int) {
+	if t.parent != nil {
+		t.parent.SetLevel(level)
+	}
+}
+
+// String replaces sensitive data in s
+func (t *Redact) String(s string) string {
+	for _, re := range t.patterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+t.config.Replacement)
+		} else {
+			s = re.ReplaceAllString(s, t.config.Replacement)
+		}
+	}
+
+	if t.card != nil {
+		s = t.card.ReplaceAllStringFunc(s, func(m string) string {
+			if luhn(m) {
+				return t.config.Replacement
+			}
+
+			return m
+		})
+	}
+
+	return s
+}
+
+func (t *Redact) value(key string, v interface{}) interface{} {
+	if t.keys[strings.ToLower(key)] {
+		return t.config.Replacement
+	}
+
+	switch val := v.(type) {
+	case string:
+		return t.String(val)
+
+	case error:
+		return t.String(val.Error())
+
+	case []byte:
+		return t.String(string(val))
+
+	case map[string]string:
+		res := make(map[string]string, len(val))
+
+		for k, item := range val {
+			res[k] = fmt.Sprint(t.value(k, item))
+		}
+
+		return res
+
+	case map[string][]string:
+		res := make(map[string][]string, len(val))
+
+		for k, items := range val {
+			if t.keys[strings.ToLower(k)] {
+				res[k] = []string{t.config.Replacement}
+				continue
+			}
+
+			res[k] = make([]string, len(items))
+
+			for i, item := range items {
+				res[k][i] = t.String(item)
+			}
+		}
+
+		return res
+
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+
+		for k, item := range val {
+			res[k] = t.value(k, item)
+		}
+
+		return res
+
+	default:
+		return v
+	}
+}
+
+// context returns ctx with redacted structured fields, request data and tags used by sentry_log
+func (t *Redact) context(ctx context.Context) context.Context {
+	if ctx == nil {
+		return nil
+	}
+
+	if req, ok := ctx.Value("request").(map[string]interface{}); ok {
+		ctx = context.WithValue(ctx, "request", t.value("request", req))
+	}
+
+	if tags, ok := ctx.Value("tags").(map[string]string); ok {
+		// sentry_log sets them as scope tags, the type is kept
+		res := make(map[string]string, len(tags))
+
+		for k, v := range tags {
+			res[k] = fmt.Sprint(t.value(k, v))
+		}
+
+		ctx = context.WithValue(ctx, "tags", res)
+	}
+
+	kv := ctxKV(ctx)
+
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	res := make([]interface{}, len(kv))
+	copy(res, kv)
+
+	for i := 1; i < len(res); i += 2 {
+		res[i] = t.value(fmt.Sprint(res[i-1]), res[i])
+	}
+
+	return context.WithValue(ctx, fieldsKey, res)
+}
+
+// args returns the redacted message followed by the redacted per-call fields
+func (t *Redact) args(args []interface{}) []interface{} {
+	msg, fields := split(args)
+
+	res := make([]interface{}, 0, len(fields)+1)
+	res = append(res, t.String(msg))
+
+	for _, f := range fields {
+		res = append(res, field{key: f.key, value: t.value(f.key, f.value)})
+	}
+
+	return res
+}
+
+func (t *Redact) err(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	redacted := t.String(msg)
+
+	if redacted == msg {
+		return err
+	}
+
+	// the original is not wrapped, sentry reports the whole unwrap chain
+	return errors.New(redacted)
+}
+
+func (t *Redact) Debug(ctx context.Context, args ...interface{}) {
+	if t.parent != nil {
+		t.parent.Debug(t.context(ctx), t.args(args)...)
+	}
+}
+
+func (t *Redact) Info(ctx context.Context, args ...interface{}) {
+	if t.parent != nil {
+		t.parent.Info(t.context(ctx), t.args(args)...)
+	}
+}
+
+func (t *Redact) Warn(ctx context.Context, args ...interface{}) {
+	if t.parent != nil {
+		t.parent.Warn(t.context(ctx), t.args(args)...)
+	}
+}
+
+func (t *Redact) Message(ctx context.Context, args ...interface{}) {
+	if t.parent != nil {
+		t.parent.Message(t.context(ctx), t.args(args)...)
+	}
+}
+
+func (t *Redact) Error(ctx context.Context, err error) {
+	if t.parent != nil {
+		t.parent.Error(t.context(ctx), t.err(err))
+	}
+}
+
+func (t *Redact) Fatal(ctx context.Context, err error) {
+	if t.parent != nil {
+		t.parent.Fatal(t.context(ctx), t.err(err))
+	}
+}
+
+func (t *Redact) Panic(ctx context.Context, err error) {
+	if t.parent != nil {
+		t.parent.Panic(t.context(ctx), t.err(err))
+	}
+}
+
+func (t *Redact) Parent() interface{} {
+	return t.parent
+}
+
+func (t *Redact) SetParent(parent interface{}) {
+	t.parent = parent.(ILogger)
+}
+
+// luhn validates the check digit of a card number, separators are ignored
+func luhn(s string) bool {
+	sum := 0
+	n := 0
+
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+
+		if n%2 == 1 {
+			d *= 2
+
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		n++
+	}
+
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,148 @@
+package redact_log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type captureLogger struct {
+	lines []string
+	errs  []error
+	ctxs  []context.Context
+}
+
+func (t *captureLogger) Init(map[string]interface{}) error { return nil }
+func (t *captureLogger) Stop() error                       { return nil }
+func (t *captureLogger) Name() string                      { return "capture" }
+func (t *captureLogger) Type() string                      { return "logger" }
+func (t *captureLogger) SetLevel(int)                      {}
+func (t *captureLogger) Parent() interface{}               { return nil }
+func (t *captureLogger) SetParent(interface{})             {}
+
+func (t *captureLogger) push(ctx context.Context, args ...interface{}) {
+	var b strings.Builder
+
+	for _, arg := range args {
+		if f, ok := arg.(logField); ok {
+			k, v := f.LogField()
+			fmt.Fprintf(&b, " %s=%v", k, v)
+			continue
+		}
+
+		fmt.Fprint(&b, arg)
+	}
+
+	kv := ctxKV(ctx)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+	}
+
+	t.lines = append(t.lines, b.String())
+}
+
+func (t *captureLogger) Debug(ctx context.Context, args ...interface{})   { t.push(ctx, args...) }
+func (t *captureLogger) Info(ctx context.Context, args ...interface{})    { t.push(ctx, args...) }
+func (t *captureLogger) Warn(ctx context.Context, args ...interface{})    { t.push(ctx, args...) }
+func (t *captureLogger) Message(ctx context.Context, args ...interface{}) { t.push(ctx, args...) }
+func (t *captureLogger) Error(ctx context.Context, err error)             { t.fail(ctx, err) }
+func (t *captureLogger) Fatal(ctx context.Context, err error)             { t.fail(ctx, err) }
+func (t *captureLogger) Panic(ctx context.Context, err error)             { t.fail(ctx, err) }
+
+func (t *captureLogger) fail(ctx context.Context, err error) {
+	t.errs = append(t.errs, err)
+	t.ctxs = append(t.ctxs, ctx)
+}
+
+func initRedactTest(t *testing.T, cfg map[string]interface{}) (*Redact, *captureLogger) {
+	r := New("redact")
+
+	if err := r.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &captureLogger{}
+	r.SetParent(c)
+
+	return r, c
+}
+
+func TestRedact_String(t *testing.T) {
+	r, _ := initRedactTest(t, map[string]interface{}{})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_123 used", "token [REDACTED] used"},
+		{"card 4111 1111 1111 1111 paid", "card [REDACTED] paid"},
+		{"order 1234567890123 created", "order 1234567890123 created"},
+		{"mail to user.name@example.com", "mail to [REDACTED]"},
+		{"Authorization: Bearer abc.def", "Authorization: [REDACTED]"},
+		{`{"authorization":"Basic dXNlcjpwYXNz"}`, `{"authorization":"[REDACTED]"}`},
+	}
+
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedact_Chain(t *testing.T) {
+	r, c := initRedactTest(t, map[string]interface{}{
+		"patterns": []interface{}{"email"},
+		"custom":   []interface{}{`secret-\w+`},
+	})
+
+	ctx := context.WithValue(context.Background(), fieldsKey, []interface{}{
+		"password", "qwerty",
+		"headers", map[string][]string{"Authorization": {"Bearer x"}, "Accept": {"a@b.io"}},
+	})
+
+	r.Info(ctx, "user ", "a@b.io", " id ", 10, field{key: "note", value: "secret-42"})
+	r.Error(ctx, errors.New("login a@b.io failed"))
+
+	want := "user [REDACTED] id 10 note=[REDACTED] password=[REDACTED] headers=map[Accept:[[REDACTED]] Authorization:[[REDACTED]]]"
+
+	if len(c.lines) != 1 || c.lines[0] != want {
+		t.Fatalf("got %q", c.lines)
+	}
+
+	if len(c.errs) != 1 || c.errs[0].Error() != "login [REDACTED] failed" {
+		t.Fatalf("got %v", c.errs)
+	}
+
+	// the original ctx fields are not modified
+	if ctxKV(ctx)[1] != "qwerty" {
+		t.Fatal("ctx fields modified")
+	}
+}
+
+func TestRedact_SentryTags(t *testing.T) {
+	r, c := initRedactTest(t, map[string]interface{}{"patterns": []interface{}{"email"}})
+
+	tags := map[string]string{"user": "a@b.io", "token": "x", "route": "/login"}
+	ctx := context.WithValue(context.Background(), "tags", tags)
+
+	r.Error(ctx, errors.New("failed"))
+
+	got, ok := c.ctxs[0].Value("tags").(map[string]string)
+
+	if !ok || got["user"] != "[REDACTED]" || got["token"] != "[REDACTED]" || got["route"] != "/login" {
+		t.Fatalf("got %v", c.ctxs[0].Value("tags"))
+	}
+
+	// the original tags are not modified
+	if tags["user"] != "a@b.io" {
+		t.Fatal("ctx tags modified")
+	}
+}
+
+func TestRedact_UnknownPattern(t *testing.T) {
+	if err := New("redact").Init(map[string]interface{}{"patterns": []interface{}{"phone"}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
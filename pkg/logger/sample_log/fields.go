@@ -0,0 +1,24 @@
+package sample_log
+
+import "fmt"
+
+// logField is implemented by structured fields passed among the log args
+type logField interface {
+	LogField() (string, interface{})
+}
+
+// message returns the text of args without structured fields, records differing
+// only in field values are similar
+func message(args []interface{}) string {
+	rest := make([]interface{}, 0, len(args))
+
+	for _, arg := range args {
+		if _, ok := arg.(logField); ok {
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return fmt.Sprint(rest...)
+}
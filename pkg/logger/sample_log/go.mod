@@ -0,0 +1,5 @@
+module gitlab.com/devpro_studio/Paranoia/pkg/logger/sample_log
+
+go 1.23.4
+
+require gitlab.com/devpro_studio/go_utils v1.1.5
@@ -0,0 +1,251 @@
+package sample_log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// Sample is a logger chain stage limiting repeated records: within each interval the first
+// records of a level and message pass, then every thereafter-th one. At the end of the interval
+// a summary with the number of suppressed records is logged. Fatal and Panic are never sampled.
+type Sample struct {
+	name   string
+	parent ILogger
+	config Config
+
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+
+	done chan interface{}
+	wg   sync.WaitGroup
+}
+
+type Config struct {
+	Interval   time.Duration `yaml:"interval"`   // default 1s
+	First      int           `yaml:"first"`      // records passed per interval, default 10
+	Thereafter int           `yaml:"thereafter"` // then every Mth record passes, default 100, 0 - none
+	MaxKeys    int           `yaml:"max_keys"`   // distinct messages tracked per interval, default 10000
+}
+
+type sampleKey struct {
+	level LogLevel
+	msg   string
+}
+
+type sampleCount struct {
+	total      int
+	suppressed int
+}
+
+func New(name string) *Sample {
+	return &Sample{
+		name: name,
+	}
+}
+
+func (t *Sample) Init(cfg map[string]interface{}) error {
+	t.config.Thereafter = 100
+
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if t.config.Interval <= 0 {
+		t.config.Interval = time.Second
+	}
+
+	if t.config.First <= 0 {
+		t.config.First = 10
+	}
+
+	if t.config.Thereafter < 0 {
+		t.config.Thereafter = 0
+	}
+
+	if t.config.MaxKeys <= 0 {
+		t.config.MaxKeys = 10000
+	}
+
+	t.counts = make(map[sampleKey]*sampleCount)
+	t.done = make(chan interface{})
+
+	t.wg.Add(1)
+	go t.run()
+
+	return nil
+}
+
+func (t *Sample) Stop() error {
+	if t.done != nil {
+		close(t.done)
+		t.wg.Wait()
+	}
+
+	if t.parent != nil {
+		return t.parent.Stop()
+	}
+
+	return nil
+}
+
+func (t *Sample) Name() string {
+	return t.name
+}
+
+func (t *Sample) Type() string {
+	return "logger"
+}
+
+// LogStage marks the logger as a chain stage, the engine keeps stages ahead of the output loggers
+func (t *Sample) LogStage() {}
+
+func (t *Sample) SetLevel(level int) {
+	if t.parent != nil {
+		t.parent.SetLevel(level)
+	}
+}
+
+func (t *Sample) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.flush()
+
+		case <-t.done:
+			t.flush()
+			return
+		}
+	}
+}
+
+// flush starts a new interval and logs the summaries of the previous one
+func (t *Sample) flush() {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = make(map[sampleKey]*sampleCount, len(counts))
+	t.mu.Unlock()
+
+	if t.parent == nil {
+		return
+	}
+
+	ctx := context.Background()
+
+	for key, c := range counts {
+		if c.suppressed == 0 {
+			continue
+		}
+
+		summary := fmt.Sprintf("suppressed %d similar messages: %s", c.suppressed, key.msg)
+
+		switch key.level {
+		case DEBUG:
+			t.parent.Debug(ctx, summary)
+		case INFO:
+			t.parent.Info(ctx, summary)
+		case WARNING:
+			t.parent.Warn(ctx, summary)
+		case MESSAGE:
+			t.parent.Message(ctx, summary)
+		default:
+			t.parent.Error(ctx, fmt.Errorf("%s", summary))
+		}
+	}
+}
+
+// allow counts the record and reports whether it passes
+func (t *Sample) allow(level LogLevel, msg string) bool {
+	key := sampleKey{level: level, msg: msg}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.counts == nil {
+		return true
+	}
+
+	c, ok := t.counts[key]
+
+	if !ok {
+		if len(t.counts) >= t.config.MaxKeys {
+			return true
+		}
+
+		c = &sampleCount{}
+		t.counts[key] = c
+	}
+
+	c.total++
+
+	if c.total <= t.config.First {
+		return true
+	}
+
+	if t.config.Thereafter > 0 && (c.total-t.config.First)%t.config.Thereafter == 0 {
+		return true
+	}
+
+	c.suppressed++
+
+	return false
+}
+
+func (t *Sample) Debug(ctx context.Context, args ...interface{}) {
+	if t.parent != nil && t.allow(DEBUG, message(args)) {
+		t.parent.Debug(ctx, args...)
+	}
+}
+
+func (t *Sample) Info(ctx context.Context, args ...interface{}) {
+	if t.parent != nil && t.allow(INFO, message(args)) {
+		t.parent.Info(ctx, args...)
+	}
+}
+
+func (t *Sample) Warn(ctx context.Context, args ...interface{}) {
+	if t.parent != nil && t.allow(WARNING, message(args)) {
+		t.parent.Warn(ctx, args...)
+	}
+}
+
+func (t *Sample) Message(ctx context.Context, args ...interface{}) {
+	if t.parent != nil && t.allow(MESSAGE, message(args)) {
+		t.parent.Message(ctx, args...)
+	}
+}
+
+func (t *Sample) Error(ctx context.Context, err error) {
+	if t.parent != nil && t.allow(ERROR, err.Error()) {
+		t.parent.Error(ctx, err)
+	}
+}
+
+func (t *Sample) Fatal(ctx context.Context, err error) {
+	if t.parent != nil {
+		t.parent.Fatal(ctx, err)
+	}
+}
+
+func (t *Sample) Panic(ctx context.Context, err error) {
+	if t.parent != nil {
+		t.parent.Panic(ctx, err)
+	}
+}
+
+func (t *Sample) Parent() interface{} {
+	return t.parent
+}
+
+func (t *Sample) SetParent(parent interface{}) {
+	t.parent = parent.(ILogger)
+}
@@ -0,0 +1,128 @@
+package sample_log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (t *captureLogger) Init(map[string]interface{}) error { return nil }
+func (t *captureLogger) Stop() error                       { return nil }
+func (t *captureLogger) Name() string                      { return "capture" }
+func (t *captureLogger) Type() string                      { return "logger" }
+func (t *captureLogger) SetLevel(int)                      {}
+func (t *captureLogger) Parent() interface{}               { return nil }
+func (t *captureLogger) SetParent(interface{})             {}
+
+func (t *captureLogger) push(level string, args ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines = append(t.lines, level+" "+fmt.Sprint(args...))
+}
+
+func (t *captureLogger) Debug(_ context.Context, args ...interface{})   { t.push("DEBUG", args...) }
+func (t *captureLogger) Info(_ context.Context, args ...interface{})    { t.push("INFO", args...) }
+func (t *captureLogger) Warn(_ context.Context, args ...interface{})    { t.push("WARNING", args...) }
+func (t *captureLogger) Message(_ context.Context, args ...interface{}) { t.push("MESSAGE", args...) }
+func (t *captureLogger) Error(_ context.Context, err error)             { t.push("ERROR", err) }
+func (t *captureLogger) Fatal(_ context.Context, err error)             { t.push("CRITICAL", err) }
+func (t *captureLogger) Panic(_ context.Context, err error)             { t.push("CRITICAL", err) }
+
+func (t *captureLogger) count(line string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for _, l := range t.lines {
+		if l == line {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestSample(t *testing.T) {
+	s := New("sample")
+
+	err := s.Init(map[string]interface{}{
+		"interval":   "1h",
+		"first":      3,
+		"thereafter": 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &captureLogger{}
+	s.SetParent(c)
+
+	for i := 0; i < 25; i++ {
+		s.Error(context.Background(), errors.New("connection refused"))
+		s.Fatal(context.Background(), errors.New("crash"))
+	}
+
+	s.Info(context.Background(), "other")
+
+	// first 3, then the 13th and 23rd
+	if n := c.count("ERROR connection refused"); n != 5 {
+		t.Fatalf("passed %d", n)
+	}
+
+	if n := c.count("CRITICAL crash"); n != 25 {
+		t.Fatalf("fatal sampled: %d", n)
+	}
+
+	if err = s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.count("ERROR suppressed 20 similar messages: connection refused") != 1 {
+		t.Fatalf("no summary: %v", c.lines)
+	}
+
+	if c.count("INFO other") != 1 || len(c.lines) != 32 {
+		t.Fatalf("unexpected lines: %v", c.lines)
+	}
+}
+
+func TestSample_Interval(t *testing.T) {
+	s := New("sample")
+
+	err := s.Init(map[string]interface{}{
+		"interval":   "50ms",
+		"first":      1,
+		"thereafter": 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := &captureLogger{}
+	s.SetParent(c)
+
+	s.Warn(context.Background(), "slow query ", 1)
+	s.Warn(context.Background(), "slow query ", 1)
+
+	time.Sleep(120 * time.Millisecond)
+
+	s.Warn(context.Background(), "slow query ", 1)
+
+	if n := c.count("WARNING slow query 1"); n != 2 {
+		t.Fatalf("passed %d", n)
+	}
+
+	if n := c.count("WARNING suppressed 1 similar messages: slow query 1"); n != 1 {
+		t.Fatalf("summaries %d", n)
+	}
+}
@@ -10,6 +10,9 @@ require (
 require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
+	gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore v0.0.0-00010101000000-000000000000
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 )
+
+replace gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore => ../internal/logcore
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
 	"gitlab.com/devpro_studio/go_utils/decode"
 )
 
@@ -68,14 +69,14 @@ func (t *Sentry) Init(cfg map[string]interface{}) error {
 }
 
 func (t *Sentry) Stop() error {
-	if t.parent != nil {
-		return t.parent.Stop()
-	}
-
 	if t.enable {
 		sentry.Flush(time.Second * 2)
 	}
 
+	if t.parent != nil {
+		return t.parent.Stop()
+	}
+
 	return nil
 }
 
@@ -96,7 +97,7 @@ func (t *Sentry) SetLevel(level int) {
 }
 
 func (t *Sentry) Debug(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= DEBUG {
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
 		if t.parent != nil {
 			t.parent.Debug(ctx, args...)
 		}
@@ -104,10 +105,12 @@ func (t *Sentry) Debug(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Sentry) Info(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= INFO {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelInfo, nil)
-			hub.CaptureMessage(fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		// level overrides of named loggers do not lower the threshold of events sent to sentry
+		if t.enable && t.config.Level <= INFO {
+			msg, fields := logcore.Collect(ctx, args)
+			hub := t.getHub(ctx, sentry.LevelInfo, nil, fields)
+			hub.CaptureMessage(msg)
 		}
 
 		if t.parent != nil {
@@ -117,10 +120,11 @@ func (t *Sentry) Info(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Sentry) Warn(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= WARNING {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelWarning, nil)
-			hub.CaptureMessage(fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		if t.enable && t.config.Level <= WARNING {
+			msg, fields := logcore.Collect(ctx, args)
+			hub := t.getHub(ctx, sentry.LevelWarning, nil, fields)
+			hub.CaptureMessage(msg)
 		}
 
 		if t.parent != nil {
@@ -130,10 +134,11 @@ func (t *Sentry) Warn(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Sentry) Message(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= MESSAGE {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelInfo, nil)
-			hub.CaptureMessage(fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		if t.enable && t.config.Level <= MESSAGE {
+			msg, fields := logcore.Collect(ctx, args)
+			hub := t.getHub(ctx, sentry.LevelInfo, nil, fields)
+			hub.CaptureMessage(msg)
 		}
 
 		if t.parent != nil {
@@ -143,9 +148,9 @@ func (t *Sentry) Message(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Sentry) Error(ctx context.Context, err error) {
-	if t.config.Level <= ERROR {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelError, err)
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		if t.enable && t.config.Level <= ERROR {
+			hub := t.getHub(ctx, sentry.LevelError, err, logcore.CtxFields(ctx))
 			hub.CaptureException(err)
 		}
 
@@ -156,10 +161,12 @@ func (t *Sentry) Error(ctx context.Context, err error) {
 }
 
 func (t *Sentry) Fatal(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelFatal, err)
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		if t.enable && t.config.Level <= CRITICAL {
+			hub := t.getHub(ctx, sentry.LevelFatal, err, logcore.CtxFields(ctx))
 			hub.CaptureException(err)
+			// the process is about to exit, send the event before returning
+			hub.Flush(time.Second * 2)
 		}
 
 		if t.parent != nil {
@@ -169,10 +176,12 @@ func (t *Sentry) Fatal(ctx context.Context, err error) {
 }
 
 func (t *Sentry) Panic(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		if t.enable {
-			hub := t.getHub(ctx, sentry.LevelFatal, err)
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		if t.enable && t.config.Level <= CRITICAL {
+			hub := t.getHub(ctx, sentry.LevelFatal, err, logcore.CtxFields(ctx))
 			hub.CaptureException(err)
+			// the process is about to exit, send the event before returning
+			hub.Flush(time.Second * 2)
 		}
 
 		if t.parent != nil {
@@ -181,11 +190,26 @@ func (t *Sentry) Panic(ctx context.Context, err error) {
 	}
 }
 
-func (t *Sentry) getHub(ctx context.Context, level sentry.Level, err error) *sentry.Hub {
-	hub := sentry.CurrentHub()
+func (t *Sentry) getHub(ctx context.Context, level sentry.Level, err error, fields []field) *sentry.Hub {
+	// clone so that per-event tags and extra do not leak into the global scope
+	hub := sentry.CurrentHub().Clone()
 	hub.ConfigureScope(func(scope *sentry.Scope) {
 		scope.SetLevel(level)
 
+		// Structured fields: scalar values become searchable tags, the rest goes to extra
+		for _, f := range fields {
+			switch v := f.Value.(type) {
+			case string:
+				scope.SetTag(f.Key, v)
+			case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+				scope.SetTag(f.Key, fmt.Sprint(v))
+			case error:
+				scope.SetExtra(f.Key, v.Error())
+			default:
+				scope.SetExtra(f.Key, v)
+			}
+		}
+
 		span := ctx.Value("span")
 		if span != nil {
 			if _, ok := span.(*sentry.Span); ok {
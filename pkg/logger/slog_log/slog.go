@@ -0,0 +1,180 @@
+package slog_log
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// slog levels for the chain levels without a direct slog counterpart
+const (
+	LevelMessage  = slog.Level(6)
+	LevelCritical = slog.Level(12)
+)
+
+var levelSlog = map[LogLevel]slog.Level{
+	DEBUG:    slog.LevelDebug,
+	INFO:     slog.LevelInfo,
+	WARNING:  slog.LevelWarn,
+	MESSAGE:  LevelMessage,
+	ERROR:    slog.LevelError,
+	CRITICAL: LevelCritical,
+}
+
+// Slog writes log records to any slog.Handler.
+// The handler must not be the one installed by the engine as slog default, that would loop.
+type Slog struct {
+	name    string
+	parent  ILogger
+	config  Config
+	handler slog.Handler
+}
+
+type Config struct {
+	Level  LogLevel `yaml:"level"`
+	Enable bool     `yaml:"enable"`
+}
+
+func New(name string, handler slog.Handler) *Slog {
+	return &Slog{
+		name:    name,
+		handler: handler,
+	}
+}
+
+func (t *Slog) Init(cfg map[string]interface{}) error {
+	return decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+}
+
+func (t *Slog) Stop() error {
+	if t.parent != nil {
+		return t.parent.Stop()
+	}
+
+	return nil
+}
+
+func (t *Slog) Name() string {
+	return t.name
+}
+
+func (t *Slog) Type() string {
+	return "logger"
+}
+
+func (t *Slog) SetLevel(level int) {
+	t.config.Level = LogLevel(level)
+
+	if t.parent != nil {
+		t.parent.SetLevel(level)
+	}
+}
+
+func (t *Slog) push(ctx context.Context, level LogLevel, msg string, fields []field) {
+	if !t.config.Enable || t.handler == nil {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	lvl := levelSlog[level]
+
+	if !t.handler.Enabled(ctx, lvl) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), lvl, msg, 0)
+
+	for _, f := range fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+
+	_ = t.handler.Handle(ctx, r)
+}
+
+func (t *Slog) Debug(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(ctx, DEBUG, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Debug(ctx, args...)
+		}
+	}
+}
+
+func (t *Slog) Info(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(ctx, INFO, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Info(ctx, args...)
+		}
+	}
+}
+
+func (t *Slog) Warn(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(ctx, WARNING, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Warn(ctx, args...)
+		}
+	}
+}
+
+func (t *Slog) Message(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(ctx, MESSAGE, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Message(ctx, args...)
+		}
+	}
+}
+
+func (t *Slog) Error(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		t.push(ctx, ERROR, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Error(ctx, err)
+		}
+	}
+}
+
+func (t *Slog) Fatal(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(ctx, CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Fatal(ctx, err)
+		}
+	}
+}
+
+func (t *Slog) Panic(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(ctx, CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Panic(ctx, err)
+		}
+	}
+}
+
+func (t *Slog) Parent() interface{} {
+	return t.parent
+}
+
+func (t *Slog) SetParent(parent interface{}) {
+	t.parent = parent.(ILogger)
+}
@@ -0,0 +1,62 @@
+package slog_log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+type testField struct {
+	key   string
+	value interface{}
+}
+
+func (t testField) LogField() (string, interface{}) {
+	return t.key, t.value
+}
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New("slog", slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err := l.Init(map[string]interface{}{"enable": true, "level": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), logcore.FieldsKey, []interface{}{"path", "/api"})
+
+	l.Debug(ctx, "skipped")
+	l.Info(ctx, "user ", 10, testField{"user_id", 10})
+	l.Fatal(ctx, errors.New("failed"))
+
+	dec := json.NewDecoder(&buf)
+
+	var info map[string]interface{}
+	if err = dec.Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+
+	if info["level"] != "INFO" || info["msg"] != "user 10" || info["path"] != "/api" || info["user_id"] != float64(10) {
+		t.Fatalf("unexpected record %v", info)
+	}
+
+	var fatal map[string]interface{}
+	if err = dec.Decode(&fatal); err != nil {
+		t.Fatal(err)
+	}
+
+	if fatal["level"] != "ERROR+4" || fatal["msg"] != "failed" {
+		t.Fatalf("unexpected record %v", fatal)
+	}
+
+	if dec.More() {
+		t.Fatal("debug record must be filtered")
+	}
+}
@@ -3,7 +3,10 @@ package std_log
 import (
 	"context"
 	"fmt"
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
 	"gitlab.com/devpro_studio/go_utils/decode"
+	"io"
+	"os"
 	"time"
 )
 
@@ -16,22 +19,34 @@ var levelColor = map[LogLevel]string{
 	CRITICAL: "\033[31m",
 }
 
+// Overflow policies applied when the queue is full
+const (
+	OverflowBlock      = logcore.OverflowBlock
+	OverflowDropNewest = logcore.OverflowDropNewest
+	OverflowDropOldest = logcore.OverflowDropOldest
+)
+
 type Std struct {
 	name   string
 	parent ILogger
 	config Config
-	queue  chan string
-	done   chan interface{}
+	queue  *logcore.Queue
+	out    io.Writer
 }
 
 type Config struct {
-	Level  LogLevel `yaml:"level"`
-	Enable bool     `yaml:"enable"`
+	Level       LogLevel      `yaml:"level"`
+	Enable      bool          `yaml:"enable"`
+	Format      string        `yaml:"format"`       // text|json
+	QueueSize   int           `yaml:"queue_size"`   // default 1000
+	Overflow    string        `yaml:"overflow"`     // block|drop_newest|drop_oldest, default block
+	StopTimeout time.Duration `yaml:"stop_timeout"` // time to drain the queue on stop and flush on fatal, default 5s
 }
 
 func New(name string) *Std {
 	return &Std{
 		name: name,
+		out:  os.Stdout,
 	}
 }
 
@@ -41,8 +56,22 @@ func (t *Std) Init(cfg map[string]interface{}) error {
 		return err
 	}
 
-	t.queue = make(chan string, 1000)
-	t.done = make(chan interface{})
+	if t.config.Format == "" {
+		t.config.Format = "text"
+	}
+
+	if t.config.Format != "text" && t.config.Format != "json" {
+		return fmt.Errorf("unknown format: %s", t.config.Format)
+	}
+
+	if t.config.StopTimeout <= 0 {
+		t.config.StopTimeout = 5 * time.Second
+	}
+
+	t.queue, err = logcore.NewQueue(t.config.QueueSize, t.config.Overflow)
+	if err != nil {
+		return err
+	}
 
 	t.run()
 
@@ -50,15 +79,28 @@ func (t *Std) Init(cfg map[string]interface{}) error {
 }
 
 func (t *Std) Stop() error {
+	var err error
+
+	if t.queue != nil {
+		err = t.queue.Close(t.config.StopTimeout)
+	}
+
 	if t.parent != nil {
-		return t.parent.Stop()
+		if parentErr := t.parent.Stop(); err == nil {
+			err = parentErr
+		}
 	}
 
-	close(t.done)
-	time.Sleep(time.Second * 1)
-	close(t.queue)
+	return err
+}
 
-	return nil
+// Dropped returns the number of lines discarded by the overflow policy or pushed after stop
+func (t *Std) Dropped() int64 {
+	if t.queue == nil {
+		return 0
+	}
+
+	return t.queue.Dropped()
 }
 
 func (t *Std) Name() string {
@@ -71,19 +113,29 @@ func (t *Std) Type() string {
 
 func (t *Std) run() {
 	go func() {
+		defer t.queue.WriterDone()
+
 		for {
 			select {
-			case m := <-t.queue:
-				fmt.Println(m)
+			case m := <-t.queue.Lines():
+				t.write(m)
+
+			case ack := <-t.queue.Flushes():
+				t.queue.Drain(t.write, time.Time{})
+				close(ack)
 
-			case <-t.done:
-				time.Sleep(time.Millisecond * 100)
+			case <-t.queue.Done():
+				t.queue.Drain(t.write, time.Now().Add(t.config.StopTimeout))
 				return
 			}
 		}
 	}()
 }
 
+func (t *Std) write(m string) {
+	_, _ = fmt.Fprintln(t.out, m)
+}
+
 func (t *Std) SetLevel(level int) {
 	t.config.Level = LogLevel(level)
 
@@ -92,15 +144,28 @@ func (t *Std) SetLevel(level int) {
 	}
 }
 
-func (t *Std) push(level LogLevel, msg string) {
+func (t *Std) push(level LogLevel, msg string, fields []field) {
 	if t.config.Enable {
-		t.queue <- fmt.Sprintf("%s%s\u001B[0m [\033[37m%s\033[0m] %s\n", levelColor[level], level.String(), time.Now().Format("2006-01-02 15:04.05"), msg)
+		// critical lines are never dropped and are written before Fatal and Panic return
+		force := level == CRITICAL
+
+		if force {
+			defer t.queue.Flush(t.config.StopTimeout)
+		}
+
+		if t.config.Format == "json" {
+			t.queue.Push(logcore.FormatJSON(level.String(), time.Now(), msg, fields), force)
+			return
+		}
+
+		t.queue.Push(fmt.Sprintf("%s%s\u001B[0m [\033[37m%s\033[0m] %s%s\n", levelColor[level], level.String(), time.Now().Format("2006-01-02 15:04.05"), msg, logcore.FormatText(fields)), force)
 	}
 }
 
 func (t *Std) Debug(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= DEBUG {
-		t.push(DEBUG, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(DEBUG, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Debug(ctx, args...)
@@ -109,8 +174,9 @@ func (t *Std) Debug(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Std) Info(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= INFO {
-		t.push(INFO, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(INFO, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Info(ctx, args...)
@@ -119,8 +185,9 @@ func (t *Std) Info(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Std) Warn(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= WARNING {
-		t.push(WARNING, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(WARNING, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Warn(ctx, args...)
@@ -129,8 +196,9 @@ func (t *Std) Warn(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Std) Message(ctx context.Context, args ...interface{}) {
-	if t.config.Level <= MESSAGE {
-		t.push(MESSAGE, fmt.Sprint(args...))
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(MESSAGE, msg, fields)
 
 		if t.parent != nil {
 			t.parent.Message(ctx, args...)
@@ -139,8 +207,8 @@ func (t *Std) Message(ctx context.Context, args ...interface{}) {
 }
 
 func (t *Std) Error(ctx context.Context, err error) {
-	if t.config.Level <= ERROR {
-		t.push(ERROR, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		t.push(ERROR, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Error(ctx, err)
@@ -149,8 +217,8 @@ func (t *Std) Error(ctx context.Context, err error) {
 }
 
 func (t *Std) Fatal(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		t.push(CRITICAL, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Fatal(ctx, err)
@@ -159,8 +227,8 @@ func (t *Std) Fatal(ctx context.Context, err error) {
 }
 
 func (t *Std) Panic(ctx context.Context, err error) {
-	if t.config.Level <= CRITICAL {
-		t.push(CRITICAL, err.Error())
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
 
 		if t.parent != nil {
 			t.parent.Panic(ctx, err)
@@ -0,0 +1,80 @@
+package std_log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type syncBuffer struct {
+	mu    sync.Mutex
+	lines int
+	data  []byte
+}
+
+func (t *syncBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines++
+	t.data = append(t.data, p...)
+
+	return len(p), nil
+}
+
+func (t *syncBuffer) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.lines
+}
+
+func TestStd_StopDrains(t *testing.T) {
+	out := &syncBuffer{}
+	l := New("std")
+	l.out = out
+
+	err := l.Init(map[string]interface{}{"enable": true, "queue_size": 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5000; i++ {
+		l.Info(context.Background(), "line")
+	}
+
+	if err = l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.count() != 5000 {
+		t.Fatalf("written %d lines", out.count())
+	}
+
+	l.Info(context.Background(), "after stop")
+
+	if l.Dropped() != 1 || out.count() != 5000 {
+		t.Fatalf("dropped %d", l.Dropped())
+	}
+}
+
+func TestStd_FatalFlush(t *testing.T) {
+	out := &syncBuffer{}
+	l := New("std")
+	l.out = out
+
+	err := l.Init(map[string]interface{}{"enable": true, "overflow": OverflowDropNewest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Stop()
+
+	l.Info(context.Background(), "line")
+	l.Fatal(context.Background(), errors.New("crash"))
+
+	if out.count() != 2 || !strings.Contains(string(out.data), "crash") {
+		t.Fatal("fatal line must be written before Fatal returns")
+	}
+}
@@ -0,0 +1,237 @@
+package syslog_log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// severity maps log levels to RFC 5424 severities
+var severity = map[LogLevel]int{
+	DEBUG:    7, // debug
+	INFO:     6, // informational
+	MESSAGE:  5, // notice
+	WARNING:  4, // warning
+	ERROR:    3, // error
+	CRITICAL: 2, // critical
+}
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// formatSyslog renders an RFC 5424 message, fields go to the structured data element sdID
+func formatSyslog(facility int, level LogLevel, tm time.Time, hostname, appName string, pid int, sdID, msg string, fields []field) string {
+	var b strings.Builder
+
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(facility*8 + severity[level]))
+	b.WriteString(">1 ")
+	b.WriteString(tm.Format("2006-01-02T15:04:05.000000Z07:00"))
+	b.WriteByte(' ')
+	b.WriteString(header(hostname, 255))
+	b.WriteByte(' ')
+	b.WriteString(header(appName, 48))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(pid))
+	b.WriteString(" - ")
+
+	if len(fields) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(sdID)
+
+		for _, f := range fields {
+			b.WriteByte(' ')
+			b.WriteString(sdName(f.Key))
+			b.WriteString(`="`)
+			b.WriteString(sdValue(fmt.Sprint(f.Value)))
+			b.WriteByte('"')
+		}
+
+		b.WriteByte(']')
+	}
+
+	if msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+
+	return b.String()
+}
+
+// truncate cuts msg to at most max bytes without splitting a UTF-8 sequence
+func truncate(msg string, max int) string {
+	if len(msg) <= max {
+		return msg
+	}
+
+	i := max
+
+	for i > 0 && !utf8.RuneStart(msg[i]) {
+		i--
+	}
+
+	return msg[:i]
+}
+
+// header returns s as a header field: printable US-ASCII without spaces, "-" when empty
+func header(s string, max int) string {
+	res := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s) && len(res) < max; i++ {
+		if s[i] > 32 && s[i] < 127 {
+			res = append(res, s[i])
+		}
+	}
+
+	if len(res) == 0 {
+		return "-"
+	}
+
+	return string(res)
+}
+
+// sdName returns key as a structured data parameter name
+func sdName(key string) string {
+	res := make([]byte, 0, len(key))
+
+	for i := 0; i < len(key) && len(res) < 32; i++ {
+		c := key[i]
+
+		if c <= 32 || c >= 127 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+
+		res = append(res, c)
+	}
+
+	if len(res) == 0 {
+		return "_"
+	}
+
+	return string(res)
+}
+
+// sdValue escapes '"', '\' and ']' in a structured data parameter value
+func sdValue(v string) string {
+	if !strings.ContainsAny(v, "\"\\]") {
+		return v
+	}
+
+	var b strings.Builder
+
+	for _, r := range v {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// formatJournal renders a record in the journal native protocol
+func formatJournal(facility int, level LogLevel, identifier, msg string, fields []field) string {
+	var b bytes.Buffer
+
+	journalField(&b, "MESSAGE", msg)
+	journalField(&b, "PRIORITY", strconv.Itoa(severity[level]))
+	journalField(&b, "SYSLOG_FACILITY", strconv.Itoa(facility))
+	journalField(&b, "SYSLOG_IDENTIFIER", identifier)
+
+	for _, f := range fields {
+		journalField(&b, journalName(f.Key), fmt.Sprint(f.Value))
+	}
+
+	return b.String()
+}
+
+// journalField writes KEY=value, values with a newline use the length-prefixed binary form
+func journalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+
+		return
+	}
+
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalReserved are the fields of journal-fields(7) set by the logger or with a meaning to
+// journald and its readers, a record field must not spoof the message, severity or identity
+var journalReserved = map[string]bool{
+	"MESSAGE": true, "MESSAGE_ID": true, "PRIORITY": true, "ERRNO": true, "DOCUMENTATION": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true, "TID": true, "UNIT": true, "USER_UNIT": true,
+	"INVOCATION_ID": true, "USER_INVOCATION_ID": true,
+	"SYSLOG_FACILITY": true, "SYSLOG_IDENTIFIER": true, "SYSLOG_PID": true, "SYSLOG_TIMESTAMP": true, "SYSLOG_RAW": true,
+}
+
+// journalName returns key as a journal field name: upper case letters, digits and underscores,
+// not starting with a digit or an underscore, which are reserved for trusted fields.
+// Names of reserved fields, OBJECT_ and COREDUMP_ ones get the F_ prefix.
+func journalName(key string) string {
+	res := make([]byte, 0, len(key)+1)
+
+	for i := 0; i < len(key) && len(res) < 64; i++ {
+		c := key[i]
+
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+
+		res = append(res, c)
+	}
+
+	if len(res) == 0 || res[0] == '_' || (res[0] >= '0' && res[0] <= '9') {
+		res = append([]byte("F"), res...)
+	}
+
+	name := string(res)
+
+	if journalReserved[name] || strings.HasPrefix(name, "OBJECT_") || strings.HasPrefix(name, "COREDUMP_") {
+		name = "F_" + name
+	}
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
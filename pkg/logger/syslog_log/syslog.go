@@ -0,0 +1,384 @@
+package syslog_log
+
+import (
+	"context"
+	"fmt"
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+	"gitlab.com/devpro_studio/go_utils/decode"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Modes of delivery
+const (
+	ModeSyslog   = "syslog"   // RFC 5424 over udp, tcp, unix or unixgram
+	ModeJournald = "journald" // journal native protocol over the journal socket
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// Overflow policies applied when the queue is full
+const (
+	OverflowBlock      = logcore.OverflowBlock
+	OverflowDropNewest = logcore.OverflowDropNewest
+	OverflowDropOldest = logcore.OverflowDropOldest
+)
+
+// Syslog writes records to a syslog server in RFC 5424 format or to journald natively.
+// Structured fields go to a structured data element or to journal fields.
+type Syslog struct {
+	name   string
+	parent ILogger
+	config Config
+	queue  *logcore.Queue
+
+	facility int
+	hostname string
+	pid      int
+	conn     net.Conn
+}
+
+type Config struct {
+	Level       LogLevel      `yaml:"level"`
+	Enable      bool          `yaml:"enable"`
+	Mode        string        `yaml:"mode"`         // syslog|journald, default syslog
+	Network     string        `yaml:"network"`      // udp|tcp|unix|unixgram, default unixgram for /dev/log
+	Address     string        `yaml:"address"`      // host:port or socket path, default /dev/log or the journal socket
+	Facility    string        `yaml:"facility"`     // user, daemon, local0..local7 etc., default user
+	AppName     string        `yaml:"app_name"`     // default executable name
+	Hostname    string        `yaml:"hostname"`     // default os hostname
+	SdID        string        `yaml:"sd_id"`        // structured data element id for fields, default fields@32473
+	Framing     string        `yaml:"framing"`      // stream framing: octet_counting|non_transparent, default octet_counting
+	MaxMessage  int           `yaml:"max_message"`  // bytes, longer messages are truncated, default 64KB
+	Timeout     time.Duration `yaml:"timeout"`      // dial and write timeout, default 5s
+	QueueSize   int           `yaml:"queue_size"`   // default 1000
+	Overflow    string        `yaml:"overflow"`     // block|drop_newest|drop_oldest, default block
+	StopTimeout time.Duration `yaml:"stop_timeout"` // time to drain the queue on stop and flush on fatal, default 5s
+}
+
+func New(name string) *Syslog {
+	return &Syslog{
+		name: name,
+	}
+}
+
+func (t *Syslog) Init(cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	switch t.config.Mode {
+	case "", ModeSyslog:
+		t.config.Mode = ModeSyslog
+
+		if t.config.Address == "" {
+			t.config.Address = "/dev/log"
+		}
+
+		if t.config.Network == "" {
+			t.config.Network = "unixgram"
+		}
+
+	case ModeJournald:
+		if t.config.Address == "" {
+			t.config.Address = journalSocket
+		}
+
+		t.config.Network = "unixgram"
+
+	default:
+		return fmt.Errorf("unknown mode: %s", t.config.Mode)
+	}
+
+	switch t.config.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return fmt.Errorf("unknown network: %s", t.config.Network)
+	}
+
+	switch t.config.Framing {
+	case "":
+		t.config.Framing = "octet_counting"
+	case "octet_counting", "non_transparent":
+	default:
+		return fmt.Errorf("unknown framing: %s", t.config.Framing)
+	}
+
+	if t.config.Facility == "" {
+		t.config.Facility = "user"
+	}
+
+	facility, ok := facilities[t.config.Facility]
+
+	if !ok {
+		return fmt.Errorf("unknown facility: %s", t.config.Facility)
+	}
+
+	t.facility = facility
+
+	if t.config.AppName == "" {
+		t.config.AppName = filepath.Base(os.Args[0])
+	}
+
+	t.hostname = t.config.Hostname
+
+	if t.hostname == "" {
+		t.hostname, _ = os.Hostname()
+	}
+
+	if t.config.SdID == "" {
+		t.config.SdID = "fields@32473"
+	}
+
+	if t.config.MaxMessage <= 0 {
+		t.config.MaxMessage = 64 * 1024
+	}
+
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 5 * time.Second
+	}
+
+	if t.config.StopTimeout <= 0 {
+		t.config.StopTimeout = 5 * time.Second
+	}
+
+	t.pid = os.Getpid()
+
+	if t.config.Enable {
+		// fail fast on a wrong address, later errors are retried per message
+		if err = t.dial(); err != nil {
+			return err
+		}
+	}
+
+	t.queue, err = logcore.NewQueue(t.config.QueueSize, t.config.Overflow)
+	if err != nil {
+		return err
+	}
+
+	t.run()
+
+	return nil
+}
+
+func (t *Syslog) Stop() error {
+	var err error
+
+	if t.queue != nil {
+		err = t.queue.Close(t.config.StopTimeout)
+
+		// the writer goroutine still owns the connection when the queue was not drained
+		if err == nil && t.conn != nil {
+			_ = t.conn.Close()
+		}
+	}
+
+	if t.parent != nil {
+		if parentErr := t.parent.Stop(); err == nil {
+			err = parentErr
+		}
+	}
+
+	return err
+}
+
+// Dropped returns the number of messages discarded by the overflow policy or pushed after stop
+func (t *Syslog) Dropped() int64 {
+	if t.queue == nil {
+		return 0
+	}
+
+	return t.queue.Dropped()
+}
+
+func (t *Syslog) Name() string {
+	return t.name
+}
+
+func (t *Syslog) Type() string {
+	return "logger"
+}
+
+func (t *Syslog) run() {
+	go func() {
+		defer t.queue.WriterDone()
+
+		for {
+			select {
+			case m := <-t.queue.Lines():
+				t.write(m)
+
+			case ack := <-t.queue.Flushes():
+				t.queue.Drain(t.write, time.Time{})
+				close(ack)
+
+			case <-t.queue.Done():
+				t.queue.Drain(t.write, time.Now().Add(t.config.StopTimeout))
+				return
+			}
+		}
+	}()
+}
+
+func (t *Syslog) dial() error {
+	conn, err := net.DialTimeout(t.config.Network, t.config.Address, t.config.Timeout)
+
+	if err != nil {
+		return err
+	}
+
+	t.conn = conn
+
+	return nil
+}
+
+// write sends a message, reconnecting once when the connection is broken
+func (t *Syslog) write(m string) {
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if t.conn == nil {
+			if err = t.dial(); err != nil {
+				continue
+			}
+		}
+
+		_ = t.conn.SetWriteDeadline(time.Now().Add(t.config.Timeout))
+
+		if _, err = t.conn.Write(t.frame(m)); err == nil {
+			return
+		}
+
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+
+	fmt.Printf("syslog: message dropped: %s\n", err)
+}
+
+// frame applies RFC 6587 framing on stream connections
+func (t *Syslog) frame(m string) []byte {
+	if t.config.Network != "tcp" && t.config.Network != "unix" {
+		return []byte(m)
+	}
+
+	if t.config.Framing == "non_transparent" {
+		return []byte(m + "\n")
+	}
+
+	return []byte(strconv.Itoa(len(m)) + " " + m)
+}
+
+func (t *Syslog) SetLevel(level int) {
+	t.config.Level = LogLevel(level)
+
+	if t.parent != nil {
+		t.parent.SetLevel(level)
+	}
+}
+
+func (t *Syslog) push(level LogLevel, msg string, fields []field) {
+	if t.config.Enable {
+		// critical messages are never dropped and are sent before Fatal and Panic return
+		force := level == CRITICAL
+
+		if force {
+			defer t.queue.Flush(t.config.StopTimeout)
+		}
+
+		msg = truncate(msg, t.config.MaxMessage)
+
+		if t.config.Mode == ModeJournald {
+			t.queue.Push(formatJournal(t.facility, level, t.config.AppName, msg, fields), force)
+			return
+		}
+
+		t.queue.Push(formatSyslog(t.facility, level, time.Now(), t.hostname, t.config.AppName, t.pid, t.config.SdID, msg, fields), force)
+	}
+}
+
+func (t *Syslog) Debug(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= DEBUG {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(DEBUG, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Debug(ctx, args...)
+		}
+	}
+}
+
+func (t *Syslog) Info(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= INFO {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(INFO, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Info(ctx, args...)
+		}
+	}
+}
+
+func (t *Syslog) Warn(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= WARNING {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(WARNING, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Warn(ctx, args...)
+		}
+	}
+}
+
+func (t *Syslog) Message(ctx context.Context, args ...interface{}) {
+	if ctxLevel(ctx, t.config.Level) <= MESSAGE {
+		msg, fields := logcore.Collect(ctx, args)
+		t.push(MESSAGE, msg, fields)
+
+		if t.parent != nil {
+			t.parent.Message(ctx, args...)
+		}
+	}
+}
+
+func (t *Syslog) Error(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= ERROR {
+		t.push(ERROR, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Error(ctx, err)
+		}
+	}
+}
+
+func (t *Syslog) Fatal(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Fatal(ctx, err)
+		}
+	}
+}
+
+func (t *Syslog) Panic(ctx context.Context, err error) {
+	if ctxLevel(ctx, t.config.Level) <= CRITICAL {
+		t.push(CRITICAL, err.Error(), logcore.CtxFields(ctx))
+
+		if t.parent != nil {
+			t.parent.Panic(ctx, err)
+		}
+	}
+}
+
+func (t *Syslog) Parent() interface{} {
+	return t.parent
+}
+
+func (t *Syslog) SetParent(parent interface{}) {
+	t.parent = parent.(ILogger)
+}
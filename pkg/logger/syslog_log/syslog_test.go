@@ -0,0 +1,268 @@
+package syslog_log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"gitlab.com/devpro_studio/Paranoia/pkg/logger/internal/logcore"
+)
+
+type fieldArg struct {
+	key   string
+	value interface{}
+}
+
+func (t fieldArg) LogField() (string, interface{}) {
+	return t.key, t.value
+}
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf[:n])
+}
+
+func TestSyslog_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	l := New("syslog")
+
+	err = l.Init(map[string]interface{}{
+		"enable":   true,
+		"network":  "udp",
+		"address":  conn.LocalAddr().String(),
+		"facility": "local3",
+		"app_name": "orders",
+		"hostname": "host1",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), logcore.FieldsKey, []interface{}{"request_id", "r1"})
+	l.Warn(ctx, "slow query", fieldArg{"sql", `select "x"]`})
+
+	m := readPacket(t, conn)
+
+	// local3 * 8 + warning
+	prefix := "<156>1 "
+
+	if !strings.HasPrefix(m, prefix) {
+		t.Fatalf("unexpected priority %q", m)
+	}
+
+	parts := strings.SplitN(m[len(prefix):], " ", 6)
+
+	if len(parts) != 6 {
+		t.Fatalf("unexpected message %q", m)
+	}
+
+	if _, err = time.Parse(time.RFC3339Nano, parts[0]); err != nil {
+		t.Fatalf("bad timestamp %q", parts[0])
+	}
+
+	if parts[1] != "host1" || parts[2] != "orders" || parts[4] != "-" {
+		t.Fatalf("unexpected header %q", m)
+	}
+
+	if parts[5] != `[fields@32473 request_id="r1" sql="select \"x\"\]"] slow query` {
+		t.Fatalf("unexpected structured data %q", parts[5])
+	}
+
+	_ = l.Stop()
+}
+
+func TestSyslog_TCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		var res []string
+
+		for len(res) < 2 {
+			size, err := r.ReadString(' ')
+
+			if err != nil {
+				break
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			buf := make([]byte, n)
+
+			if _, err = io.ReadFull(r, buf); err != nil {
+				break
+			}
+
+			res = append(res, string(buf))
+		}
+
+		received <- res
+	}()
+
+	l := New("syslog")
+
+	err = l.Init(map[string]interface{}{
+		"enable":  true,
+		"network": "tcp",
+		"address": ln.Addr().String(),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info(context.Background(), "first")
+	l.Error(context.Background(), errors.New("second"))
+
+	if err = l.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-received:
+		if len(res) != 2 || !strings.HasPrefix(res[0], "<14>1 ") || !strings.HasSuffix(res[0], " - - first") ||
+			!strings.HasPrefix(res[1], "<11>1 ") || !strings.HasSuffix(res[1], " second") {
+			t.Fatalf("unexpected messages %q", res)
+		}
+
+	case <-time.After(2 * time.Second):
+		t.Fatal("messages not received")
+	}
+}
+
+func TestSyslog_Journald(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	l := New("journal")
+
+	err = l.Init(map[string]interface{}{
+		"enable":   true,
+		"mode":     "journald",
+		"address":  path,
+		"app_name": "orders",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Fatal(context.WithValue(context.Background(), logcore.FieldsKey, []interface{}{"order.id", 42, "priority", 7}), errors.New("line1\nline2"))
+
+	m := []byte(readPacket(t, conn))
+
+	var multiline bytes.Buffer
+	multiline.WriteString("MESSAGE\n")
+	_ = binary.Write(&multiline, binary.LittleEndian, uint64(len("line1\nline2")))
+	multiline.WriteString("line1\nline2\n")
+
+	expected := multiline.String() + "PRIORITY=2\nSYSLOG_FACILITY=1\nSYSLOG_IDENTIFIER=orders\nORDER_ID=42\nF_PRIORITY=7\n"
+
+	if string(m) != expected {
+		t.Fatalf("unexpected journal entry %q", m)
+	}
+
+	_ = l.Stop()
+}
+
+func TestSyslog_Severity(t *testing.T) {
+	levels := map[LogLevel]string{DEBUG: "<7>", INFO: "<6>", MESSAGE: "<5>", WARNING: "<4>", ERROR: "<3>", CRITICAL: "<2>"}
+
+	for level, prefix := range levels {
+		m := formatSyslog(0, level, time.Now(), "h", "a", 1, "f@1", "m", nil)
+
+		if !strings.HasPrefix(m, prefix) {
+			t.Fatalf("level %d: unexpected priority %q", level, m)
+		}
+	}
+}
+
+func TestSyslog_JournalName(t *testing.T) {
+	names := map[string]string{
+		"user-id": "USER_ID", "_source": "F_SOURCE", "1st": "F1ST", "": "F",
+		"message": "F_MESSAGE", "priority": "F_PRIORITY", "syslog_identifier": "F_SYSLOG_IDENTIFIER",
+		"object.pid": "F_OBJECT_PID", "message_text": "MESSAGE_TEXT",
+	}
+
+	for key, expected := range names {
+		if res := journalName(key); res != expected {
+			t.Fatalf("%q: expected %q, got %q", key, expected, res)
+		}
+	}
+}
+
+func TestSyslog_Truncate(t *testing.T) {
+	tests := []struct {
+		msg      string
+		max      int
+		expected string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"привет", 3, "п"},
+		{"привет", 4, "пр"},
+		{"a€", 3, "a"},
+		{"€", 1, ""},
+	}
+
+	for _, tt := range tests {
+		res := truncate(tt.msg, tt.max)
+
+		if res != tt.expected || !utf8.ValidString(res) {
+			t.Fatalf("%q[:%d]: expected %q, got %q", tt.msg, tt.max, tt.expected, res)
+		}
+	}
+}
+
+func TestSyslog_UnknownFacility(t *testing.T) {
+	if err := New("syslog").Init(map[string]interface{}{"facility": "local9"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
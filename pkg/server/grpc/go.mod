@@ -3,12 +3,15 @@ module gitlab.com/devpro_studio/Paranoia/pkg/server/grpc
 go 1.24.0
 
 require (
+	gitlab.com/devpro_studio/Paranoia v0.0.0-00010101000000-000000000000
 	gitlab.com/devpro_studio/go_utils v1.1.5
 	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.63.0
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.10
 )
 
+replace gitlab.com/devpro_studio/Paranoia => ../../../
+
 require (
 	github.com/go-logr/logr v1.4.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"gitlab.com/devpro_studio/Paranoia/paranoia/listener"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
@@ -12,11 +13,13 @@ type Grpc struct {
 	name   string
 	config Config
 
-	server *grpc.Server
+	server    *grpc.Server
+	listeners []*listener.Listener
 }
 
 type Config struct {
-	Port string `yaml:"port"`
+	Port      string            `yaml:"port"`
+	Listeners []listener.Config `yaml:"-"` // listeners key, replaces port, all listeners serve the same services
 }
 
 func New(name string) *Grpc {
@@ -30,11 +33,18 @@ func (t *Grpc) Init(cfg map[string]interface{}) error {
 		delete(cfg, "middlewares")
 	}
 
-	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	listeners, err := listener.Pop(cfg)
 	if err != nil {
 		return err
 	}
 
+	err = decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	t.config.Listeners = listeners
+
 	if t.config.Port == "" {
 		t.config.Port = "9090"
 	}
@@ -50,17 +60,19 @@ func (t *Grpc) Init(cfg map[string]interface{}) error {
 
 func (t *Grpc) Start() error {
 
-	listenErr := make(chan error, 1)
+	listeners, err := listener.ListenAll(listener.Defaults(t.config.Listeners, t.config.Port))
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		l, err := net.Listen("tcp", ":"+t.config.Port)
-		if err != nil {
-			listenErr <- err
-			return
-		}
+	t.listeners = listeners
+	listenErr := make(chan error, len(listeners))
 
-		listenErr <- t.server.Serve(l)
-	}()
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			listenErr <- t.server.Serve(l)
+		}(l)
+	}
 
 	select {
 	case err := <-listenErr:
@@ -5,6 +5,7 @@ import (
 	"gitlab.com/devpro_studio/Paranoia/pkg/server/grpc/example"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"path/filepath"
 	"testing"
 )
 
@@ -51,3 +52,43 @@ func TestGrpc_RegisterService(t1 *testing.T) {
 		}
 	})
 }
+
+func TestGrpc_Listeners(t1 *testing.T) {
+	sock := filepath.Join(t1.TempDir(), "grpc.sock")
+
+	s := New("test")
+	err := s.Init(map[string]interface{}{
+		"listeners": []interface{}{
+			map[string]interface{}{"name": "local", "network": "unix", "address": sock},
+			map[string]interface{}{"name": "public", "address": "127.0.0.1:0"},
+		},
+	})
+	if err != nil {
+		t1.Fatal(err)
+	}
+
+	s.RegisterService(&example.Example_ServiceDesc, &server{})
+
+	if err = s.Start(); err != nil {
+		t1.Fatal(err)
+	}
+	defer s.Stop()
+
+	for _, target := range []string{"unix://" + sock, s.listeners[1].Addr().String()} {
+		client, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t1.Fatal(err)
+		}
+
+		resp, err := example.NewExampleClient(client).Do(context.Background(), &example.Request{Message: target})
+		_ = client.Close()
+
+		if err != nil {
+			t1.Fatal(err)
+		}
+
+		if resp.Message != target {
+			t1.Fatalf("unexpected message %q", resp.Message)
+		}
+	}
+}
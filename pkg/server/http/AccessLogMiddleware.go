@@ -0,0 +1,199 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// AccessLogMiddleware writes a line per request in the combined log format or as JSON,
+// to the engine logger at Info or directly to stdout or stderr
+type AccessLogMiddleware struct {
+	name   string
+	config AccessLogMiddlewareConfig
+
+	logger interfaces.ILogger
+	mu     sync.Mutex
+	out    io.Writer
+}
+
+type AccessLogMiddlewareConfig struct {
+	Format    string   `yaml:"format"`     // combined or json, default combined
+	Output    string   `yaml:"output"`     // logger, stdout or stderr, default logger
+	SkipPaths []string `yaml:"skip_paths"` // not logged, e.g. health checks
+}
+
+// accessEntry is a request as written by AccessLogMiddleware
+type accessEntry struct {
+	Time      time.Time `json:"time"`
+	RemoteIP  string    `json:"remote_ip"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int       `json:"bytes"` // -1 for streamed responses
+	Duration  float64   `json:"duration_ms"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+func NewAccessLogMiddleware(name string) interfaces.IMiddleware {
+	return &AccessLogMiddleware{
+		name: name,
+	}
+}
+
+func (t *AccessLogMiddleware) Init(app interfaces.IEngine, cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	switch t.config.Format {
+	case "":
+		t.config.Format = "combined"
+	case "combined", "json":
+	default:
+		return fmt.Errorf("access log: unknown format %s", t.config.Format)
+	}
+
+	switch t.config.Output {
+	case "", "logger":
+		t.config.Output = "logger"
+		t.logger = app.GetLogger()
+	case "stdout":
+		t.out = os.Stdout
+	case "stderr":
+		t.out = os.Stderr
+	default:
+		return fmt.Errorf("access log: unknown output %s", t.config.Output)
+	}
+
+	return nil
+}
+
+func (t *AccessLogMiddleware) Stop() error {
+	return nil
+}
+
+func (t *AccessLogMiddleware) Name() string {
+	return t.name
+}
+
+func (t *AccessLogMiddleware) Type() string {
+	return "middleware"
+}
+
+func (t *AccessLogMiddleware) Invoke(next RouteFunc) RouteFunc {
+	skip := make(map[string]bool, len(t.config.SkipPaths))
+
+	for _, p := range t.config.SkipPaths {
+		skip[p] = true
+	}
+
+	return func(c context.Context, ctx ICtx) {
+		start := time.Now()
+
+		next(c, ctx)
+
+		req := ctx.GetRequest()
+		path, _, _ := strings.Cut(req.GetURI(), "?")
+
+		if skip[path] {
+			return
+		}
+
+		e := accessEntry{
+			Time:      start,
+			RemoteIP:  req.GetRemoteIP(),
+			Method:    req.GetMethod(),
+			URI:       req.GetURI(),
+			Proto:     "HTTP/1.1",
+			Status:    ctx.GetResponse().GetStatus(),
+			Bytes:     len(ctx.GetResponse().GetBody()),
+			Duration:  float64(time.Since(start).Microseconds()) / 1000,
+			Referer:   req.GetHeader().Get("Referer"),
+			UserAgent: req.GetUserAgent(),
+			RequestID: RequestID(c),
+		}
+
+		if r, ok := req.(*HttpRequest); ok && r.request != nil {
+			e.Proto = r.request.Proto
+		}
+
+		if e.RequestID == "" {
+			if id, err := ctx.GetUserValue("request_id"); err == nil {
+				e.RequestID, _ = id.(string)
+			}
+		}
+
+		if ctx.IsStreaming() {
+			// the size of a streamed body is not known
+			e.Bytes = -1
+		} else if e.Status == http.StatusOK && e.Bytes == 0 {
+			// sent as 204 by the server
+			e.Status = http.StatusNoContent
+		}
+
+		t.write(c, e)
+	}
+}
+
+func (t *AccessLogMiddleware) write(c context.Context, e accessEntry) {
+	var line string
+
+	if t.config.Format == "json" {
+		b, _ := json.Marshal(e)
+		line = string(b)
+	} else {
+		line = e.combined()
+	}
+
+	if t.logger != nil {
+		t.logger.Info(c, line)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, _ = io.WriteString(t.out, line+"\n")
+}
+
+// combined formats the entry as the Apache combined log format
+func (t accessEntry) combined() string {
+	size := "-"
+
+	if t.Bytes > 0 {
+		size = strconv.Itoa(t.Bytes)
+	}
+
+	return fmt.Sprintf("%s - - [%s] %s %d %s %s %s",
+		dash(t.RemoteIP),
+		t.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(t.Method+" "+t.URI+" "+t.Proto),
+		t.Status,
+		size,
+		strconv.Quote(dash(t.Referer)),
+		strconv.Quote(dash(t.UserAgent)),
+	)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
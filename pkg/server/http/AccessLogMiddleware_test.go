@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogMiddleware_RequestID(t *testing.T) {
+	var out bytes.Buffer
+
+	combined := NewAccessLogMiddleware("combined").(*AccessLogMiddleware)
+	_ = combined.Init(nil, map[string]interface{}{"output": "stdout", "skip_paths": []string{"/health"}})
+	combined.out = &out
+
+	var jsonOut bytes.Buffer
+
+	jsonLog := NewAccessLogMiddleware("json").(*AccessLogMiddleware)
+	_ = jsonLog.Init(nil, map[string]interface{}{"output": "stdout", "format": "json"})
+	jsonLog.out = &jsonOut
+
+	requestID := NewRequestIDMiddleware("request_id").(*RequestIDMiddleware)
+	_ = requestID.Init(nil, map[string]interface{}{})
+
+	s := New("access")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{
+			"combined":   combined,
+			"json":       jsonLog,
+			"request_id": requestID,
+		},
+		"base_middleware": []string{"combined", "json", "request_id"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handlerID string
+
+	_ = s.PushRoute("GET", "/items", func(c context.Context, ctx ICtx) {
+		handlerID = RequestID(c)
+		ctx.GetResponse().SetBody([]byte("hello"))
+	}, nil)
+
+	_ = s.PushRoute("GET", "/health", func(_ context.Context, _ ICtx) {}, nil)
+
+	req := httptest.NewRequest("GET", "/items?page=2", nil)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "curl/8")
+	req.Header.Set("X-Request-ID", "abc-123")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if w.Header().Get("X-Request-ID") != "abc-123" || handlerID != "abc-123" {
+		t.Fatalf("incoming request id not kept: %q %q", w.Header().Get("X-Request-ID"), handlerID)
+	}
+
+	line := regexp.MustCompile(`^192\.0\.2\.7 - - \[[^]]+\] "GET /items\?page=2 HTTP/1\.1" 200 5 "-" "curl/8"\n$`)
+
+	if !line.MatchString(out.String()) {
+		t.Fatalf("unexpected combined line %q", out.String())
+	}
+
+	var entry accessEntry
+
+	if err = json.Unmarshal(jsonOut.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.RequestID != "abc-123" || entry.Status != 200 || entry.Bytes != 5 || entry.RemoteIP != "192.0.2.7" {
+		t.Fatalf("unexpected json entry %+v", entry)
+	}
+
+	out.Reset()
+
+	req = httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if id := w.Header().Get("X-Request-ID"); len(id) != 36 || strings.Count(id, "-") != 4 {
+		t.Fatalf("invalid request id not replaced: %q", id)
+	}
+
+	if out.Len() != 0 {
+		t.Fatalf("skipped path logged: %q", out.String())
+	}
+}
@@ -0,0 +1,349 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// CompressMiddleware compresses response bodies with the encoding negotiated by Accept-Encoding
+// and decompresses request bodies sent with Content-Encoding. Streamed responses are not compressed.
+type CompressMiddleware struct {
+	name   string
+	config CompressMiddlewareConfig
+}
+
+type CompressMiddlewareConfig struct {
+	Encodings    []string `yaml:"encodings"`     // supported encodings by server preference, default br, gzip, deflate
+	Level        int      `yaml:"level"`         // compression level of the encoding, 0 - its default
+	MinSize      int      `yaml:"min_size"`      // smaller bodies are not compressed, default 1024
+	ContentTypes []string `yaml:"content_types"` // compressed content types, a trailing / matches the type prefix
+
+	MaxDecompressedSize int64 `yaml:"max_decompressed_size"` // limit of a decompressed request body, default 10MB
+	DisableRequests     bool  `yaml:"disable_requests"`      // request bodies are not decompressed
+}
+
+var compressors = map[string]func(w io.Writer, level int) (io.WriteCloser, error){
+	"gzip": func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+
+		return gzip.NewWriterLevel(w, level)
+	},
+	"deflate": func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = flate.DefaultCompression
+		}
+
+		return flate.NewWriter(w, level)
+	},
+	"br": func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = brotli.DefaultCompression
+		}
+
+		return brotli.NewWriterLevel(w, level), nil
+	},
+}
+
+var decompressors = map[string]func(r io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	},
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+}
+
+func NewCompressMiddleware(name string) interfaces.IMiddleware {
+	return &CompressMiddleware{
+		name: name,
+	}
+}
+
+func (t *CompressMiddleware) Init(app interfaces.IEngine, cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if len(t.config.Encodings) == 0 {
+		t.config.Encodings = []string{"br", "gzip", "deflate"}
+	}
+
+	for _, e := range t.config.Encodings {
+		if _, ok := compressors[e]; !ok {
+			return fmt.Errorf("compress: unknown encoding %s", e)
+		}
+	}
+
+	if t.config.MinSize == 0 {
+		t.config.MinSize = 1024
+	}
+
+	if len(t.config.ContentTypes) == 0 {
+		t.config.ContentTypes = []string{
+			"text/",
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+		}
+	}
+
+	if t.config.MaxDecompressedSize == 0 {
+		t.config.MaxDecompressedSize = 10 << 20
+	}
+
+	return nil
+}
+
+func (t *CompressMiddleware) Stop() error {
+	return nil
+}
+
+func (t *CompressMiddleware) Name() string {
+	return t.name
+}
+
+func (t *CompressMiddleware) Type() string {
+	return "middleware"
+}
+
+func (t *CompressMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		if !t.config.DisableRequests && !t.decompressRequest(ctx) {
+			return
+		}
+
+		next(c, ctx)
+
+		if ctx.IsStreaming() {
+			return
+		}
+
+		t.compressResponse(ctx)
+	}
+}
+
+// decompressRequest replaces an encoded request body with the decoded one, false when the request is answered
+func (t *CompressMiddleware) decompressRequest(ctx ICtx) bool {
+	encoding := strings.ToLower(strings.TrimSpace(ctx.GetRequest().GetHeader().Get("Content-Encoding")))
+
+	if encoding == "" || encoding == "identity" {
+		return true
+	}
+
+	hr, ok := ctx.GetRequest().(*HttpRequest)
+
+	if !ok || hr.request == nil {
+		return true
+	}
+
+	newReader, ok := decompressors[encoding]
+
+	if !ok {
+		ctx.GetResponse().Header().Set("Accept-Encoding", strings.Join(t.config.Encodings, ", "))
+		ctx.Problem(fmt.Errorf("%w: content encoding %s", ErrUnsupportedMedia, encoding))
+		return false
+	}
+
+	r, err := newReader(hr.request.Body)
+
+	if err != nil {
+		ctx.Problem(fmt.Errorf("%w: invalid %s body", ErrBadRequest, encoding))
+		return false
+	}
+
+	hr.request.Body = &limitedBody{r: r, body: hr.request.Body, left: t.config.MaxDecompressedSize, limit: t.config.MaxDecompressedSize}
+	hr.request.ContentLength = -1
+	hr.request.Header.Del("Content-Encoding")
+	hr.request.Header.Del("Content-Length")
+
+	return true
+}
+
+func (t *CompressMiddleware) compressResponse(ctx ICtx) {
+	res := ctx.GetResponse()
+	body := res.GetBody()
+
+	contentType := res.Header().Get("Content-Type")
+
+	if contentType == "" && len(body) > 0 {
+		contentType = detectContentType(body)
+	}
+
+	if !t.compressible(contentType) {
+		return
+	}
+
+	res.Header().Add("Vary", "Accept-Encoding")
+
+	status := res.GetStatus()
+
+	if len(body) < t.config.MinSize || res.Header().Get("Content-Encoding") != "" ||
+		status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		ctx.GetRequest().GetMethod() == http.MethodHead {
+		return
+	}
+
+	encoding := negotiateEncoding(ctx.GetRequest().GetHeader().Get("Accept-Encoding"), t.config.Encodings)
+
+	if encoding == "" {
+		return
+	}
+
+	var buf bytes.Buffer
+
+	w, err := compressors[encoding](&buf, t.config.Level)
+
+	if err != nil {
+		return
+	}
+
+	if _, err = w.Write(body); err != nil {
+		return
+	}
+
+	if err = w.Close(); err != nil || buf.Len() >= len(body) {
+		return
+	}
+
+	if res.Header().Get("Content-Type") == "" {
+		res.Header().Set("Content-Type", contentType)
+	}
+
+	res.Header().Set("Content-Encoding", encoding)
+	res.Header().Del("Content-Length")
+	res.SetBody(buf.Bytes())
+}
+
+func (t *CompressMiddleware) compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	if mediaType == "" {
+		return false
+	}
+
+	for _, allowed := range t.config.ContentTypes {
+		if strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) || mediaType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiateEncoding returns the supported encoding with the highest q value in header,
+// ties are broken by the order of supported, "" when only identity is acceptable
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	q := make(map[string]float64, 4)
+
+	for _, part := range strings.Split(header, ",") {
+		name, value := parseQuality(part)
+
+		if name != "" {
+			q[strings.ToLower(name)] = value
+		}
+	}
+
+	best, bestQ := "", 0.0
+
+	for _, e := range supported {
+		v, ok := q[e]
+
+		if !ok {
+			v, ok = q["*"]
+		}
+
+		if ok && v > bestQ {
+			best, bestQ = e, v
+		}
+	}
+
+	return best
+}
+
+// parseQuality splits an element of an Accept header into the value and its q parameter, default 1
+func parseQuality(part string) (string, float64) {
+	value, params, _ := strings.Cut(part, ";")
+	q := 1.0
+
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+
+		if ok && strings.EqualFold(k, "q") {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+
+	return strings.TrimSpace(value), q
+}
+
+// limitedBody reads a decompressed body and fails with *http.MaxBytesError past limit, Bind answers 413
+type limitedBody struct {
+	r     io.ReadCloser
+	body  io.ReadCloser
+	left  int64
+	limit int64
+}
+
+func (t *limitedBody) Read(p []byte) (int, error) {
+	if t.left <= 0 {
+		// a byte more tells a body of exactly limit bytes from a larger one, a decompressor may
+		// return no byte and no error before its next output, as http.MaxBytesReader keep reading
+		var b [1]byte
+
+		for {
+			n, err := t.r.Read(b[:])
+
+			if n > 0 {
+				return 0, &http.MaxBytesError{Limit: t.limit}
+			}
+
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if int64(len(p)) > t.left {
+		p = p[:t.left]
+	}
+
+	n, err := t.r.Read(p)
+	t.left -= int64(n)
+
+	return n, err
+}
+
+func (t *limitedBody) Close() error {
+	_ = t.r.Close()
+
+	return t.body.Close()
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func newCompressServer(t *testing.T, cfg map[string]interface{}) *Http {
+	compress := NewCompressMiddleware("compress").(*CompressMiddleware)
+
+	if err := compress.Init(nil, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("compress")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"compress": compress},
+		"base_middleware": []string{"compress"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestCompressMiddleware_Response(t *testing.T) {
+	s := newCompressServer(t, map[string]interface{}{"min_size": 100})
+
+	large := `{"items":"` + strings.Repeat("a", 2000) + `"}`
+
+	_ = s.PushRoute("GET", "/large", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte(large))
+	}, nil)
+
+	_ = s.PushRoute("GET", "/small", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte(`{"ok":true}`))
+	}, nil)
+
+	_ = s.PushRoute("GET", "/image", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().Header().Set("Content-Type", "image/png")
+		ctx.GetResponse().SetBody(bytes.Repeat([]byte{0}, 2000))
+	}, nil)
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+		vary           bool
+	}{
+		{"/large", "gzip, deflate, br", "br", true},
+		{"/large", "gzip;q=1, br;q=0.5", "gzip", true},
+		{"/large", "deflate", "deflate", true},
+		{"/large", "*;q=0.1, br;q=0", "gzip", true},
+		{"/large", "identity", "", true},
+		{"/large", "", "", true},
+		{"/small", "gzip", "", true},
+		{"/image", "gzip", "", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+
+		if tt.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Fatalf("%s %q: want encoding %q, got %q", tt.path, tt.acceptEncoding, tt.encoding, got)
+		}
+
+		if got := w.Header().Get("Vary") == "Accept-Encoding"; got != tt.vary {
+			t.Fatalf("%s %q: unexpected Vary %q", tt.path, tt.acceptEncoding, w.Header().Get("Vary"))
+		}
+
+		if tt.path != "/large" {
+			continue
+		}
+
+		var r io.Reader = w.Body
+
+		switch tt.encoding {
+		case "gzip":
+			r, _ = gzip.NewReader(w.Body)
+		case "deflate":
+			r = flate.NewReader(w.Body)
+		case "br":
+			r = brotli.NewReader(w.Body)
+		}
+
+		body, _ := io.ReadAll(r)
+
+		if string(body) != large || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Fatalf("%s %q: unexpected body of %d bytes %q", tt.path, tt.acceptEncoding, len(body), w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestCompressMiddleware_Request(t *testing.T) {
+	s := newCompressServer(t, map[string]interface{}{"max_decompressed_size": 1000})
+
+	type payload struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	_ = s.PushRoute("POST", "/bind", func(_ context.Context, ctx ICtx) {
+		var p payload
+
+		if err := ctx.Bind(&p); err != nil {
+			ctx.Problem(err)
+			return
+		}
+
+		ctx.GetResponse().SetBody([]byte(p.Name))
+	}, nil)
+
+	gz := func(data string) *bytes.Buffer {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write([]byte(data))
+		_ = w.Close()
+
+		return &buf
+	}
+
+	tests := []struct {
+		name     string
+		body     io.Reader
+		encoding string
+		status   int
+		want     string
+	}{
+		{"gzip", gz(`{"name":"partner"}`), "gzip", http.StatusOK, "partner"},
+		{"bomb", gz(`{"name":"` + strings.Repeat("a", 100000) + `"}`), "gzip", http.StatusRequestEntityTooLarge, ""},
+		{"broken", strings.NewReader("not gzip"), "gzip", http.StatusBadRequest, ""},
+		{"unknown", strings.NewReader(`{"name":"x"}`), "zstd", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/bind", tt.body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", tt.encoding)
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if w.Code != tt.status || tt.want != "" && w.Body.String() != tt.want {
+			t.Fatalf("%s: unexpected response %d %q", tt.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+// stutterReader returns no byte and no error before each chunk, as a decompressor may
+type stutterReader struct {
+	chunks []string
+	empty  bool
+}
+
+func (t *stutterReader) Read(p []byte) (int, error) {
+	if len(t.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	if t.empty = !t.empty; t.empty {
+		return 0, nil
+	}
+
+	n := copy(p, t.chunks[0])
+	t.chunks = t.chunks[1:]
+
+	return n, nil
+}
+
+func (t *stutterReader) Close() error { return nil }
+
+func TestCompressMiddleware_LimitedBody(t *testing.T) {
+	tests := []struct {
+		chunks []string
+		err    bool
+	}{
+		{[]string{"abcd"}, false},
+		{[]string{"ab", "cd"}, false},
+		{[]string{"abcd", "e"}, true},
+		{[]string{"ab", "cd", "e"}, true},
+	}
+
+	for _, tt := range tests {
+		body := &limitedBody{r: &stutterReader{chunks: tt.chunks}, body: io.NopCloser(nil), left: 4, limit: 4}
+
+		data, err := io.ReadAll(body)
+
+		var maxErr *http.MaxBytesError
+
+		if tt.err != errors.As(err, &maxErr) || string(data) != "abcd" {
+			t.Fatalf("%q: want limit error %v, got %q %v", tt.chunks, tt.err, data, err)
+		}
+	}
+}
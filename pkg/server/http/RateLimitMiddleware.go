@@ -2,44 +2,46 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"strconv"
-	"sync"
 	"time"
 
 	interfaces2 "gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
 	"gitlab.com/devpro_studio/go_utils/decode"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
 )
 
 type RateLimitMiddleware struct {
 	name   string
 	config RateLimitMiddlewareConfig
 
-	mu      sync.RWMutex
-	buckets map[string]*bucket
-
+	store   RateLimitStore
 	keyFunc func(ICtx) (string, int, int)
 
-	stopCh        chan struct{}
-	cleanupTicker *time.Ticker
-	wg            sync.WaitGroup
+	storeErrors metric.Int64Counter
 }
 
 type RateLimitMiddlewareConfig struct {
 	Requests        int           `yaml:"requests"`
 	Interval        time.Duration `yaml:"interval"`
 	Burst           int           `yaml:"burst"`
-	KeyStrategy     string        `yaml:"key_strategy"` // ip|header|global|method_path|ip_method_path
+	KeyStrategy     string        `yaml:"key_strategy"` // ip|header|global|method_path|ip_method_path, paths by route template
 	HeaderName      string        `yaml:"header_name"`
-	CleanupInterval time.Duration `yaml:"cleanup_interval"`
-	EvictAfter      time.Duration `yaml:"evict_after"`
-}
-
-type bucket struct {
-	mu         sync.Mutex
-	tokens     float64
-	lastRefill time.Time
-	lastUsed   time.Time
+	Algorithm       string        `yaml:"algorithm"`        // token_bucket|fixed_window|sliding_window|concurrency, default token_bucket
+	Store           string        `yaml:"store"`            // memory|redis, default memory
+	Cache           string        `yaml:"cache"`            // redis cache package of the redis store
+	KeyPrefix       string        `yaml:"key_prefix"`       // redis store, default ratelimit:<name>:
+	StoreTimeout    time.Duration `yaml:"store_timeout"`    // redis store, default 100ms
+	FailClosed      bool          `yaml:"fail_closed"`      // reject with 503 when the store fails instead of letting requests through
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // memory store
+	EvictAfter      time.Duration `yaml:"evict_after"`      // memory store
+	Lease           time.Duration `yaml:"lease"`            // redis store, concurrency slot lease renewed while the request runs, default 30s
+
+	Policies     []RateLimitPolicy `yaml:"-"`              // policies key
+	ClaimsCtxKey string            `yaml:"claims_ctx_key"` // ctx_key of JWTMiddleware, for the claims of the policies
 }
 
 func NewRateLimitMiddleware(name string) interfaces2.IMiddleware {
@@ -48,11 +50,18 @@ func NewRateLimitMiddleware(name string) interfaces2.IMiddleware {
 	}
 }
 
-func (t *RateLimitMiddleware) Init(_ interfaces2.IEngine, cfg map[string]interface{}) error {
-	if err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst); err != nil {
+func (t *RateLimitMiddleware) Init(app interfaces2.IEngine, cfg map[string]interface{}) error {
+	cfg, policies, err := popRateLimitPolicies(cfg)
+	if err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+
+	if err = decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst); err != nil {
 		return err
 	}
 
+	t.config.Policies = policies
+
 	if t.config.Requests <= 0 {
 		t.config.Requests = 60
 	}
@@ -65,89 +74,90 @@ func (t *RateLimitMiddleware) Init(_ interfaces2.IEngine, cfg map[string]interfa
 	if t.config.KeyStrategy == "" {
 		t.config.KeyStrategy = "ip"
 	}
+	if t.config.Algorithm == "" {
+		t.config.Algorithm = RateLimitTokenBucket
+	}
+	if t.config.Store == "" {
+		t.config.Store = "memory"
+	}
+	if t.config.KeyPrefix == "" {
+		t.config.KeyPrefix = "ratelimit:" + t.name + ":"
+	}
+	if t.config.StoreTimeout <= 0 {
+		t.config.StoreTimeout = 100 * time.Millisecond
+	}
 	if t.config.CleanupInterval <= 0 {
 		t.config.CleanupInterval = time.Minute
 	}
 	if t.config.EvictAfter <= 0 {
 		t.config.EvictAfter = 15 * time.Minute
 	}
+	if t.config.Lease <= 0 {
+		t.config.Lease = defaultRateLimitLease
+	}
 
-	t.mu.Lock()
-	t.buckets = make(map[string]*bucket, 128)
-	t.mu.Unlock()
+	if !validAlgorithm(t.config.Algorithm) {
+		return fmt.Errorf("rate limit: unknown algorithm %s", t.config.Algorithm)
+	}
 
-	t.stopCh = make(chan struct{})
-	t.cleanupTicker = time.NewTicker(t.config.CleanupInterval)
-	t.wg.Add(1)
-	go t.cleanupLoop()
+	names := make(map[string]bool, len(t.config.Policies))
 
-	// default key builder
-	if t.keyFunc == nil {
-		t.keyFunc = t.buildKeyDefault
-	}
+	for i := range t.config.Policies {
+		p := &t.config.Policies[i]
 
-	return nil
-}
+		if err = p.init(t.config); err != nil {
+			return fmt.Errorf("rate limit: policy %d %s: %w", i, p.Name, err)
+		}
 
-func (t *RateLimitMiddleware) cleanupLoop() {
-	defer t.wg.Done()
-	for {
-		select {
-		case <-t.cleanupTicker.C:
-			t.cleanupOnce()
-		case <-t.stopCh:
-			if t.cleanupTicker != nil {
-				t.cleanupTicker.Stop()
-			}
-			return
+		if names[p.Name] {
+			return fmt.Errorf("rate limit: duplicate policy %s", p.Name)
 		}
+
+		names[p.Name] = true
 	}
-}
 
-func (t *RateLimitMiddleware) cleanupOnce() {
-	now := time.Now()
+	// store set by SetStore
+	if t.store == nil {
+		switch t.config.Store {
+		case "memory":
+			t.store = newMemoryRateLimitStore(t.config.CleanupInterval, t.config.EvictAfter)
+		case "redis":
+			if app == nil {
+				return errors.New("rate limit: the redis store needs the engine")
+			}
 
-	// Copy entries under RLock
-	t.mu.RLock()
-	type pair struct {
-		key string
-		b   *bucket
-	}
-	items := make([]pair, 0, len(t.buckets))
-	for k, v := range t.buckets {
-		items = append(items, pair{key: k, b: v})
-	}
-	t.mu.RUnlock()
-
-	// Decide which to evict
-	toEvict := make([]string, 0)
-	for i := 0; i < len(items); i++ {
-		b := items[i].b
-		b.mu.Lock()
-		idle := now.Sub(b.lastUsed)
-		b.mu.Unlock()
-		if idle >= t.config.EvictAfter {
-			toEvict = append(toEvict, items[i].key)
+			pkg := app.GetPkg(interfaces2.PkgCache, t.config.Cache)
+
+			if pkg == nil {
+				return fmt.Errorf("rate limit: cache %s not found", t.config.Cache)
+			}
+
+			cache, ok := pkg.(rateLimitScripter)
+
+			if !ok {
+				return fmt.Errorf("rate limit: cache %s does not support scripts", t.config.Cache)
+			}
+
+			t.store = newRedisRateLimitStore(cache, t.config.KeyPrefix, t.config.StoreTimeout)
+		default:
+			return fmt.Errorf("rate limit: unknown store %s", t.config.Store)
 		}
 	}
 
-	if len(toEvict) == 0 {
-		return
-	}
+	t.storeErrors, _ = otel.Meter("").Int64Counter("middleware_rate_limit." + t.name + ".store_errors")
 
-	// Delete selected keys
-	t.mu.Lock()
-	for _, k := range toEvict {
-		delete(t.buckets, k)
+	// default key builder
+	if t.keyFunc == nil {
+		t.keyFunc = t.buildKeyDefault
 	}
-	t.mu.Unlock()
+
+	return nil
 }
 
 func (t *RateLimitMiddleware) Stop() error {
-	if t.stopCh != nil {
-		close(t.stopCh)
+	if t.store != nil {
+		return t.store.Stop()
 	}
-	t.wg.Wait()
 	return nil
 }
 
@@ -156,83 +166,136 @@ func (t *RateLimitMiddleware) Type() string { return "middleware" }
 
 func (t *RateLimitMiddleware) Invoke(next RouteFunc) RouteFunc {
 	return func(c context.Context, ctx ICtx) {
-		key, burst, reqs := t.keyFunc(ctx)
-		// If reqs is 0, disable rate limiting for this request
-		if reqs == 0 {
+		limit, key, policy, ok := t.limitOf(c, ctx)
+		// not limited
+		if !ok {
 			next(c, ctx)
 			return
 		}
-		now := time.Now()
-
-		// Get or create bucket
-		t.mu.RLock()
-		b := t.buckets[key]
-		t.mu.RUnlock()
-		if b == nil {
-			t.mu.Lock()
-			// Re-check after acquiring write lock
-			if b = t.buckets[key]; b == nil {
-				b = &bucket{
-					tokens:     float64(burst),
-					lastRefill: now,
-					lastUsed:   now,
-				}
-				t.buckets[key] = b
-			}
-			t.mu.Unlock()
-		}
 
-		rate := float64(reqs) / t.config.Interval.Seconds()
-		if rate <= 0 {
-			// Degenerate config: block everything immediately
-			hdr := ctx.GetResponse().Header()
-			hdr.Set("X-RateLimit-Limit", strconv.Itoa(reqs))
+		hdr := ctx.GetResponse().Header()
+
+		if limit.Requests <= 0 {
+			// Degenerate config: block everything immediately, the stores expect a positive limit
+			hdr.Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
 			hdr.Set("X-RateLimit-Remaining", "0")
 			hdr.Set("Retry-After", "1")
 			ctx.GetResponse().SetStatus(429)
 			return
 		}
 
-		// Refill and consume
-		b.mu.Lock()
-		dt := now.Sub(b.lastRefill).Seconds()
-		if dt > 0 {
-			b.tokens = math.Min(float64(burst), b.tokens+dt*rate)
-			b.lastRefill = now
-		}
+		res, err := t.store.Take(c, key, limit)
 
-		if b.tokens >= 1.0 {
-			b.tokens -= 1.0
-			remaining := int(math.Floor(b.tokens))
-			b.lastUsed = now
-			b.mu.Unlock()
+		if err != nil {
+			t.storeErrors.Add(c, 1)
 
-			hdr := ctx.GetResponse().Header()
-			hdr.Set("X-RateLimit-Limit", strconv.Itoa(reqs))
-			hdr.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if t.config.FailClosed {
+				hdr.Set("Retry-After", "1")
+				ctx.GetResponse().SetStatus(503)
+				return
+			}
 
 			next(c, ctx)
 			return
 		}
 
-		// Not enough tokens
-		needed := 1.0 - b.tokens
-		secondsToOne := needed / rate
-		retryAfter := int(math.Ceil(secondsToOne))
-		resetAt := now.Add(time.Duration(retryAfter) * time.Second)
-		b.mu.Unlock()
+		setRateLimitHeaders(hdr, limit, res, policy)
 
-		hdr := ctx.GetResponse().Header()
-		hdr.Set("X-RateLimit-Limit", strconv.Itoa(reqs))
-		hdr.Set("X-RateLimit-Remaining", "0")
-		hdr.Set("Retry-After", strconv.Itoa(retryAfter))
-		hdr.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+		if res.Allowed {
+			if res.Release != nil {
+				defer res.Release()
+			}
+
+			next(c, ctx)
+			return
+		}
 
 		ctx.GetResponse().SetStatus(429)
 		// Body is optional; keeping empty by default
 	}
 }
 
+// limitOf returns the limit of the request with its key and policy name, false when not limited
+func (t *RateLimitMiddleware) limitOf(c context.Context, ctx ICtx) (RateLimit, string, string, bool) {
+	if len(t.config.Policies) > 0 {
+		claims := claimsFrom(c, t.config.ClaimsCtxKey)
+
+		for i := range t.config.Policies {
+			p := &t.config.Policies[i]
+
+			if !p.matches(ctx, claims) {
+				continue
+			}
+
+			if p.Unlimited {
+				return RateLimit{}, "", "", false
+			}
+
+			return p.limit, p.key(ctx, claims), p.Name, true
+		}
+	}
+
+	key, burst, reqs := t.keyFunc(ctx)
+	// If reqs is 0, disable rate limiting for this request
+	if reqs == 0 {
+		return RateLimit{}, "", "", false
+	}
+
+	return RateLimit{
+		Algorithm: t.config.Algorithm,
+		Requests:  reqs,
+		Interval:  t.config.Interval,
+		Burst:     burst,
+		Lease:     t.config.Lease,
+	}, key, "", true
+}
+
+// setRateLimitHeaders sets the X-RateLimit headers and the RateLimit ones of the IETF draft
+// (draft-ietf-httpapi-ratelimit-headers), with Retry-After on rejected requests
+func setRateLimitHeaders(hdr IHeader, limit RateLimit, res RateLimitResult, policy string) {
+	remaining := strconv.Itoa(res.Remaining)
+	retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+
+	hdr.Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+	hdr.Set("X-RateLimit-Remaining", remaining)
+	hdr.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	hdr.Set("RateLimit-Remaining", remaining)
+
+	if !res.Allowed {
+		hdr.Set("Retry-After", strconv.Itoa(retryAfter))
+		hdr.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Duration(retryAfter)*time.Second).Unix(), 10))
+	}
+
+	if limit.Algorithm == RateLimitConcurrency {
+		// no window, the slots are freed by the requests in flight
+		return
+	}
+
+	reset := res.Reset
+	if !res.Allowed {
+		reset = res.RetryAfter
+	}
+
+	hdr.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+
+	p := strconv.Itoa(limit.Requests) + ";w=" + strconv.Itoa(max(int(math.Ceil(limit.Interval.Seconds())), 1))
+
+	if limit.Algorithm == RateLimitTokenBucket {
+		p += ";burst=" + strconv.Itoa(limit.Burst)
+	}
+
+	if policy != "" {
+		p += ";name=" + strconv.Quote(policy)
+	}
+
+	hdr.Set("RateLimit-Policy", p)
+}
+
+// SetStore replaces the store of the rate limited keys, call it before Init
+func (t *RateLimitMiddleware) SetStore(s RateLimitStore) {
+	t.store = s
+}
+
 // SetKeyFunc allows overriding key building logic.
 // Custom function returns: key, bucket capacity (burst), requests per interval.
 // Capacity 0 disables rate limiting for the request; requests <= 0 falls back to config.Requests.
@@ -253,9 +316,9 @@ func (t *RateLimitMiddleware) buildKeyDefault(ctx ICtx) (string, int, int) {
 		}
 		return "header:" + v, t.config.Burst, t.config.Requests
 	case "method_path":
-		return req.GetMethod() + " " + req.GetURI(), t.config.Burst, t.config.Requests
+		return req.GetMethod() + " " + routeOf(ctx), t.config.Burst, t.config.Requests
 	case "ip_method_path":
-		return req.GetRemoteIP() + " " + req.GetMethod() + " " + req.GetURI(), t.config.Burst, t.config.Requests
+		return req.GetRemoteIP() + " " + req.GetMethod() + " " + routeOf(ctx), t.config.Burst, t.config.Requests
 	case "ip":
 		fallthrough
 	default:
@@ -3,10 +3,16 @@ package http
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	interfaces2 "gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
 )
 
 func TestRateLimitMiddleware_Basic(t *testing.T) {
@@ -77,3 +83,228 @@ func TestRateLimitMiddleware_Basic(t *testing.T) {
 		t.Fatalf("want 200 {}, got %d %s", code, string(body))
 	}
 }
+
+// claimsMiddleware puts the claims of the X-Sub and X-Plan headers in the context like JWTMiddleware
+type claimsMiddleware struct{}
+
+func (claimsMiddleware) Init(_ interfaces2.IEngine, _ map[string]interface{}) error { return nil }
+func (claimsMiddleware) Stop() error                                                { return nil }
+func (claimsMiddleware) Name() string                                               { return "claims" }
+func (claimsMiddleware) Type() string                                               { return "middleware" }
+
+func (claimsMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		if sub := ctx.GetRequest().GetHeader().Get("X-Sub"); sub != "" {
+			c = context.WithValue(c, "user", jwt.MapClaims{
+				"sub":   sub,
+				"plan":  ctx.GetRequest().GetHeader().Get("X-Plan"),
+				"roles": []interface{}{"reader", "writer"},
+			})
+		}
+
+		next(c, ctx)
+	}
+}
+
+func TestRateLimitMiddleware_Policies(t *testing.T) {
+	rl := NewRateLimitMiddleware("rate_limit").(*RateLimitMiddleware)
+
+	err := rl.Init(nil, map[string]interface{}{
+		"requests":       100,
+		"interval":       "1m",
+		"key_strategy":   "method_path",
+		"claims_ctx_key": "user",
+		"policies": []interface{}{
+			map[string]interface{}{
+				"name":      "health",
+				"match":     map[string]interface{}{"headers": map[string]interface{}{"X-Health": "*"}},
+				"unlimited": true,
+			},
+			map[string]interface{}{
+				"name":      "login",
+				"match":     map[string]interface{}{"routes": []string{"/login"}, "methods": []string{"post"}},
+				"requests":  1,
+				"algorithm": "fixed_window",
+			},
+			map[string]interface{}{
+				"name":      "pro",
+				"match":     map[string]interface{}{"claims": map[string]interface{}{"plan": "pro", "roles": "writer"}},
+				"key":       []string{"claim:sub"},
+				"requests":  3,
+				"algorithm": "sliding_window",
+			},
+			map[string]interface{}{
+				"name":     "users",
+				"match":    map[string]interface{}{"routes": []string{"/users/{id:int}"}},
+				"key":      []string{"route"},
+				"requests": 2,
+				"burst":    2,
+			},
+			map[string]interface{}{
+				"name":      "slow",
+				"match":     map[string]interface{}{"routes": []string{"/slow"}},
+				"key":       []string{"global"},
+				"requests":  1,
+				"algorithm": "concurrency",
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("policies")
+
+	err = s.Init(map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"claims": claimsMiddleware{}, "rate_limit": rl},
+		"base_middleware": []string{"claims", "rate_limit"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	_ = s.PushRoute("POST", "/login", routeBody("ok"), nil)
+	_ = s.PushRoute("GET", "/users/{id:int}", routeBody("ok"), nil)
+	_ = s.PushRoute("GET", "/items", routeBody("ok"), nil)
+	_ = s.PushRoute("GET", "/slow", func(_ context.Context, ctx ICtx) {
+		close(started)
+		<-release
+		ctx.GetResponse().SetBody([]byte("ok"))
+	}, nil)
+
+	do := func(method, path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	// fixed window on the route template and method
+	if w := do("POST", "/login", nil); w.Code != 200 || w.Header().Get("RateLimit-Policy") != `1;w=60;name="login"` {
+		t.Fatalf("login: want 200, got %d %v", w.Code, w.Header())
+	}
+
+	if w := do("POST", "/login", nil); w.Code != 429 || w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("login: want 429, got %d %v", w.Code, w.Header())
+	}
+
+	// the first matching policy applies
+	if w := do("POST", "/login", map[string]string{"X-Health": "1"}); w.Code != 200 || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("unlimited: want 200 without headers, got %d %v", w.Code, w.Header())
+	}
+
+	// token bucket keyed by the template, not the URL
+	for i, want := range []int{200, 200, 429} {
+		if w := do("GET", fmt.Sprintf("/users/%d?page=%d", i, i), nil); w.Code != want {
+			t.Fatalf("users %d: want %d, got %d", i, want, w.Code)
+		}
+	}
+
+	// sliding window per user of the pro plan
+	for i, want := range []int{200, 200, 200, 429} {
+		w := do("GET", "/users/1", map[string]string{"X-Sub": "alice", "X-Plan": "pro"})
+
+		if w.Code != want {
+			t.Fatalf("pro %d: want %d, got %d", i, want, w.Code)
+		}
+
+		if want == 200 && w.Header().Get("RateLimit-Remaining") != strconv.Itoa(2-i) {
+			t.Fatalf("pro %d: unexpected remaining %v", i, w.Header())
+		}
+	}
+
+	if w := do("GET", "/users/1", map[string]string{"X-Sub": "bob", "X-Plan": "pro"}); w.Code != 200 {
+		t.Fatalf("pro bob: want 200, got %d", w.Code)
+	}
+
+	// no policy: the middleware limit by method and template
+	if w := do("GET", "/items?id=1", nil); w.Code != 200 || w.Header().Get("RateLimit-Remaining") != "99" {
+		t.Fatalf("default: want 200, got %d %v", w.Code, w.Header())
+	}
+
+	if w := do("GET", "/items?id=2", nil); w.Header().Get("RateLimit-Remaining") != "98" {
+		t.Fatalf("default: key must not include the query, got %v", w.Header())
+	}
+
+	// concurrency limit, the slot is freed once the request is done
+	done := make(chan int)
+
+	go func() {
+		done <- do("GET", "/slow", nil).Code
+	}()
+
+	<-started
+
+	if w := do("GET", "/slow", nil); w.Code != 429 || w.Header().Get("RateLimit-Reset") != "" {
+		t.Fatalf("concurrency: want 429, got %d %v", w.Code, w.Header())
+	}
+
+	close(release)
+
+	if code := <-done; code != 200 {
+		t.Fatalf("concurrency: want 200, got %d", code)
+	}
+
+	started = make(chan struct{})
+
+	if w := do("GET", "/slow", nil); w.Code != 200 {
+		t.Fatalf("concurrency: slot not freed, got %d", w.Code)
+	}
+}
+
+func TestRateLimitPolicy_Key(t *testing.T) {
+	p := &RateLimitPolicy{Name: "p", Key: []string{"header:A", "header:B"}}
+
+	key := func(a, b string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("A", a)
+		req.Header.Set("B", b)
+
+		ctx := HttpCtxPool.Get().(*HttpCtx)
+		defer HttpCtxPool.Put(ctx)
+		ctx.Fill(req)
+
+		return p.key(ctx, nil)
+	}
+
+	if k1, k2 := key("x|y", "z"), key("x", "y|z"); k1 == k2 {
+		t.Fatalf("values holding the separator share the key %q", k1)
+	}
+
+	if k := key("x", ""); k != "policy:p|1:x|0:" {
+		t.Fatalf("unexpected key %q", k)
+	}
+}
+
+func TestRateLimitMiddleware_PolicyConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"policies": []interface{}{map[string]interface{}{"requests": 1}}},
+		{"policies": []interface{}{map[string]interface{}{"name": "a"}}},
+		{"policies": []interface{}{map[string]interface{}{"name": "a", "requests": 1, "algorithm": "leaky"}}},
+		{"policies": []interface{}{map[string]interface{}{"name": "a", "requests": 1, "key": []string{"claim:sub"}}}},
+		{"policies": []interface{}{map[string]interface{}{"name": "a", "requests": 1, "key": []string{"cookie"}}}},
+		{"policies": []interface{}{
+			map[string]interface{}{"name": "a", "requests": 1},
+			map[string]interface{}{"name": "a", "requests": 1},
+		}},
+	}
+
+	for i, cfg := range tests {
+		rl := NewRateLimitMiddleware("rate_limit").(*RateLimitMiddleware)
+
+		if err := rl.Init(nil, cfg); err == nil {
+			_ = rl.Stop()
+			t.Fatalf("%d: want error", i)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/logger"
+	"gitlab.com/devpro_studio/go_utils/decode"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDMiddleware accepts the request ID sent by the client or generates one. The ID is echoed
+// in the response, stored in the user value and the context, added to the log fields and the span.
+type RequestIDMiddleware struct {
+	name   string
+	config RequestIDMiddlewareConfig
+}
+
+type RequestIDMiddlewareConfig struct {
+	Header         string `yaml:"header"`          // default X-Request-ID
+	IgnoreIncoming bool   `yaml:"ignore_incoming"` // IDs sent by clients are replaced
+	MaxLength      int    `yaml:"max_length"`      // longer incoming IDs are replaced, default 128
+}
+
+type requestIDKey struct{}
+
+func NewRequestIDMiddleware(name string) interfaces.IMiddleware {
+	return &RequestIDMiddleware{
+		name: name,
+	}
+}
+
+func (t *RequestIDMiddleware) Init(app interfaces.IEngine, cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if t.config.Header == "" {
+		t.config.Header = "X-Request-ID"
+	}
+
+	if t.config.MaxLength <= 0 {
+		t.config.MaxLength = 128
+	}
+
+	return nil
+}
+
+func (t *RequestIDMiddleware) Stop() error {
+	return nil
+}
+
+func (t *RequestIDMiddleware) Name() string {
+	return t.name
+}
+
+func (t *RequestIDMiddleware) Type() string {
+	return "middleware"
+}
+
+func (t *RequestIDMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		id := ctx.GetRequest().GetHeader().Get(t.config.Header)
+
+		if t.config.IgnoreIncoming || !t.valid(id) {
+			id = newRequestID()
+		}
+
+		ctx.PushUserValue("request_id", id)
+		ctx.GetResponse().Header().Set(t.config.Header, id)
+
+		trace.SpanFromContext(c).SetAttributes(attribute.String("http.request_id", id))
+
+		c = context.WithValue(c, requestIDKey{}, id)
+		c = logger.WithFields(c, "request_id", id)
+
+		next(c, ctx)
+	}
+}
+
+// valid accepts printable ASCII IDs up to max_length, other values could forge log lines
+func (t *RequestIDMiddleware) valid(id string) bool {
+	if id == "" || len(id) > t.config.MaxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequestID returns the request ID set by RequestIDMiddleware, "" without one
+func RequestID(c context.Context) string {
+	id, _ := c.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random UUID v4
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+
+	return string(s[:])
+}
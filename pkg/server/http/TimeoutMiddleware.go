@@ -2,6 +2,11 @@ package http
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
 	"time"
 
 	interfaces2 "gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
@@ -14,7 +19,10 @@ type TimeoutMiddleware struct {
 }
 
 type TimeoutMiddlewareConfig struct {
-	Timeout time.Duration `yaml:"timeout"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Status      int           `yaml:"status"`       // status of a timed out request, default 504
+	Body        string        `yaml:"body"`         // body of a timed out request, an RFC 7807 problem when empty
+	ContentType string        `yaml:"content_type"` // content type of body, default text/plain
 }
 
 func NewTimeoutMiddleware(name string) interfaces2.IMiddleware {
@@ -33,6 +41,14 @@ func (t *TimeoutMiddleware) Init(app interfaces2.IEngine, cfg map[string]interfa
 		t.config.Timeout = time.Second
 	}
 
+	if t.config.Status == 0 {
+		t.config.Status = http.StatusGatewayTimeout
+	}
+
+	if t.config.ContentType == "" {
+		t.config.ContentType = "text/plain; charset=utf-8"
+	}
+
 	return nil
 }
 
@@ -48,34 +64,98 @@ func (t *TimeoutMiddleware) Type() string {
 	return "middleware"
 }
 
+// Invoke runs next with a deadline. The handler is expected to return once c is done, until then
+// it works on a fork of the context: the timeout response is written without waiting for it,
+// and the pooled context is recycled only after it returns. A started stream or upgrade is not cut,
+// c is canceled at the deadline only while the response is pending, context.Cause reports DeadlineExceeded.
 func (t *TimeoutMiddleware) Invoke(next RouteFunc) RouteFunc {
 	return func(c context.Context, ctx ICtx) {
-		var end context.CancelFunc
-		c, end = context.WithTimeout(c, t.config.Timeout)
+		hc, ok := ctx.(*HttpCtx)
+
+		if !ok {
+			// the context is not pooled, the handler only gets the deadline
+			c, end := context.WithTimeout(c, t.config.Timeout)
+			defer end()
+			next(c, ctx)
+			return
+		}
+
+		c, end := context.WithCancelCause(c)
+		deadline := time.NewTimer(t.config.Timeout)
+		defer deadline.Stop()
 
-		done := make(chan interface{})
-		defer close(done)
-		defer end()
+		guard := &atomic.Int32{}
+		fork := hc.fork(guard)
+		release := hc.hold()
+		done := make(chan struct{})
+
+		var recovered interface{}
 
 		go func() {
-			next(c, ctx)
+			defer release()
+			defer end(nil)
+			defer close(done)
 
-			select {
-			case <-c.Done():
-				return
-			default:
-				done <- nil
-			}
+			defer func() {
+				// re-raised for RestoreMiddleware unless the request has timed out,
+				// committing keeps the timeout response from being written over a panic
+				recovered = recover()
+
+				if recovered != nil && !guard.CompareAndSwap(responsePending, responseCommitted) && guard.Load() == responseTimedOut {
+					t.lost(recovered)
+				}
+			}()
+
+			next(c, fork)
 		}()
 
 		select {
+		case <-done:
+
+		case <-deadline.C:
+			if guard.CompareAndSwap(responsePending, responseTimedOut) {
+				end(context.DeadlineExceeded)
+				t.timeout(hc)
+				return
+			}
+
+			// the response is streamed, upgraded or the handler panicked, it ends with the handler
+			<-done
+
 		case <-c.Done():
-			time.Sleep(time.Millisecond)
-			ctx.GetResponse().SetStatus(499)
-			break
+			// the client is gone or the server drains
+			if guard.CompareAndSwap(responsePending, responseTimedOut) {
+				t.timeout(hc)
+				return
+			}
 
-		case <-done:
-			break
+			<-done
+		}
+
+		if recovered != nil {
+			panic(recovered)
 		}
+
+		hc.join(fork)
+	}
+}
+
+func (t *TimeoutMiddleware) timeout(ctx *HttpCtx) {
+	if t.config.Body == "" {
+		ctx.Problem(NewProblem(t.config.Status, "request timed out"))
+		return
 	}
+
+	ctx.GetResponse().Header().Set("Content-Type", t.config.ContentType)
+	ctx.GetResponse().SetStatus(t.config.Status)
+	ctx.GetResponse().SetBody([]byte(t.config.Body))
+}
+
+// lost logs a panic of a handler that outlived its timeout, there is nothing left to re-raise it to
+func (t *TimeoutMiddleware) lost(recovered interface{}) {
+	slog.Default().Error("http: handler panicked after timeout",
+		slog.String("middleware", t.name),
+		slog.String("panic", fmt.Sprint(recovered)),
+		slog.String("stack", string(debug.Stack())),
+	)
 }
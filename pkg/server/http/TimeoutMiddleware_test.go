@@ -0,0 +1,347 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTimeoutServer(t *testing.T, cfg map[string]interface{}) (*Http, *httptest.Server) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	return newStreamServer(t, map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"timeout": timeout},
+		"base_middleware": []string{"timeout"},
+	})
+}
+
+func TestTimeoutMiddleware_Problem(t *testing.T) {
+	s, srv := newTimeoutServer(t, map[string]interface{}{"timeout": "20ms"})
+
+	returned := make(chan struct{})
+
+	_ = s.PushRoute("GET", "/slow", func(_ context.Context, ctx ICtx) {
+		defer close(returned)
+
+		// ignores the deadline and keeps writing its own copy of the response
+		time.Sleep(80 * time.Millisecond)
+		ctx.GetResponse().SetStatus(http.StatusCreated)
+		ctx.GetResponse().Header().Set("X-Late", "1")
+		ctx.PushUserValue("late", true)
+	}, nil)
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/slow")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	var p Problem
+	_ = json.NewDecoder(resp.Body).Decode(&p)
+
+	if elapsed := time.Since(start); elapsed > 70*time.Millisecond {
+		t.Fatalf("the timeout response waited for the handler: %s", elapsed)
+	}
+
+	if resp.StatusCode != http.StatusGatewayTimeout || resp.Header.Get("Content-Type") != "application/problem+json" || p.Status != http.StatusGatewayTimeout {
+		t.Fatalf("unexpected response %d %q %+v", resp.StatusCode, resp.Header.Get("Content-Type"), p)
+	}
+
+	if resp.Header.Get("X-Late") != "" {
+		t.Fatal("the late handler must not change the response")
+	}
+
+	<-returned
+}
+
+func TestTimeoutMiddleware_Config(t *testing.T) {
+	s, srv := newTimeoutServer(t, map[string]interface{}{
+		"timeout":      "10ms",
+		"status":       http.StatusServiceUnavailable,
+		"body":         `{"error":"busy"}`,
+		"content_type": "application/json",
+	})
+
+	_ = s.PushRoute("GET", "/slow", func(c context.Context, _ ICtx) {
+		<-c.Done()
+	}, nil)
+
+	_ = s.PushRoute("GET", "/fast", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().Header().Set("X-Handler", "fast")
+		ctx.GetResponse().SetBody([]byte("ok"))
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/slow")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Content-Type") != "application/json" || string(body) != `{"error":"busy"}` {
+		t.Fatalf("unexpected response %d %q %q", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	resp, err = http.Get(srv.URL + "/fast")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Handler") != "fast" || string(body) != "ok" {
+		t.Fatalf("unexpected response %d %q %q", resp.StatusCode, resp.Header.Get("X-Handler"), body)
+	}
+}
+
+func TestTimeoutMiddleware_StreamAfterTimeout(t *testing.T) {
+	s, srv := newTimeoutServer(t, map[string]interface{}{"timeout": "10ms"})
+
+	streamErr := make(chan error, 1)
+
+	_ = s.PushRoute("GET", "/late", func(c context.Context, ctx ICtx) {
+		<-c.Done()
+		time.Sleep(10 * time.Millisecond)
+
+		streamErr <- ctx.Stream(func(w io.Writer, flush func()) error {
+			_, err := w.Write([]byte("late"))
+			return err
+		})
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/late")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("want 504, got %d", resp.StatusCode)
+	}
+
+	if err = <-streamErr; err != ErrResponseTimedOut {
+		t.Fatalf("want ErrResponseTimedOut, got %v", err)
+	}
+}
+
+func TestTimeoutMiddleware_StreamPastTimeout(t *testing.T) {
+	s, srv := newTimeoutServer(t, map[string]interface{}{"timeout": "20ms"})
+
+	_ = s.PushRoute("GET", "/events", func(c context.Context, ctx ICtx) {
+		_ = ctx.SSE(0, func(sse ISSE) error {
+			for i := 0; i < 4; i++ {
+				select {
+				case <-c.Done():
+					return sse.Send(SSEEvent{Event: "canceled"})
+
+				case <-time.After(15 * time.Millisecond):
+				}
+
+				if err := sse.Send(SSEEvent{Data: strconv.Itoa(i)}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/events")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want 200, got %d", resp.StatusCode)
+	}
+
+	if want := "data: 0\n\ndata: 1\n\ndata: 2\n\ndata: 3\n\n"; string(body) != want {
+		t.Fatalf("the stream was cut by the timeout: %q", body)
+	}
+}
+
+func TestTimeoutMiddleware_Panic(t *testing.T) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+	_ = timeout.Init(nil, map[string]interface{}{"timeout": "1s"})
+
+	s, srv := newStreamServer(t, map[string]interface{}{
+		"middlewares": map[string]IMiddleware{
+			"restore": recoverMiddleware{},
+			"timeout": timeout,
+		},
+		"base_middleware": []string{"restore", "timeout"},
+	})
+
+	_ = s.PushRoute("GET", "/panic", func(_ context.Context, _ ICtx) {
+		panic("boom")
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/panic")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("want the panic recovered by RestoreMiddleware, got %d", resp.StatusCode)
+	}
+}
+
+// safeBuffer collects the logs written by handlers outliving the request
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (t *safeBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.buf.Write(p)
+}
+
+func (t *safeBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.buf.String()
+}
+
+func TestTimeoutMiddleware_PanicAfterTimeout(t *testing.T) {
+	var logs safeBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(prev)
+
+	s, srv := newTimeoutServer(t, map[string]interface{}{"timeout": "20ms"})
+	handled := make(chan struct{})
+
+	_ = s.PushRoute("GET", "/late", func(c context.Context, _ ICtx) {
+		defer close(handled)
+		<-c.Done()
+		time.Sleep(20 * time.Millisecond)
+		panic("late boom")
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/late")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("want 504, got %d", resp.StatusCode)
+	}
+
+	<-handled
+
+	deadline := time.Now().Add(time.Second)
+
+	for !strings.Contains(logs.String(), "late boom") {
+		if time.Now().After(deadline) {
+			t.Fatalf("panic after the timeout not logged: %q", logs.String())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestTimeoutMiddleware_Load mixes timed out and fast requests, it is meant to run with -race
+func TestTimeoutMiddleware_Load(t *testing.T) {
+	s, srv := newTimeoutServer(t, map[string]interface{}{"timeout": "50ms"})
+
+	_ = s.PushRoute("GET", "/work/{id}", func(_ context.Context, ctx ICtx) {
+		id, _ := strconv.Atoi(ctx.GetRouterValue("id"))
+
+		if id%2 == 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		ctx.PushUserValue("id", id)
+		ctx.GetResponse().Header().Set("X-Id", ctx.GetRouterValue("id"))
+		ctx.GetResponse().SetBody([]byte(ctx.GetRequest().GetURI()))
+	}, nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			path := "/work/" + strconv.Itoa(id)
+			resp, err := http.Get(srv.URL + path)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			switch resp.StatusCode {
+			case http.StatusGatewayTimeout:
+				if resp.Header.Get("X-Id") != "" {
+					t.Errorf("%s: timed out response written by the handler", path)
+				}
+
+			case http.StatusOK:
+				if resp.Header.Get("X-Id") != strconv.Itoa(id) || string(body) != path {
+					t.Errorf("%s: response of another request %q %q", path, resp.Header.Get("X-Id"), body)
+				}
+
+			default:
+				t.Errorf("%s: unexpected status %d", path, resp.StatusCode)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// recoverMiddleware answers 500 on a panic like RestoreMiddleware, without the logger of the engine
+type recoverMiddleware struct{}
+
+func (recoverMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		defer func() {
+			if recover() != nil {
+				ctx.GetResponse().SetStatus(http.StatusInternalServerError)
+			}
+		}()
+
+		next(c, ctx)
+	}
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// TrustedProxiesMiddleware resolves the client IP of requests coming through trusted proxies from
+// the Forwarded and X-Forwarded-For headers, GetRemoteIP returns it afterwards. Put it before the
+// middlewares using the IP, e.g. RateLimitMiddleware with the ip key strategy.
+type TrustedProxiesMiddleware struct {
+	name   string
+	config TrustedProxiesMiddlewareConfig
+
+	trusted []*net.IPNet
+}
+
+type TrustedProxiesMiddlewareConfig struct {
+	Trusted []string `yaml:"trusted"` // CIDRs or IPs of the proxies, e.g. 10.0.0.0/8
+	Headers []string `yaml:"headers"` // forwarded, x-forwarded-for or x-real-ip by priority, default forwarded, x-forwarded-for
+}
+
+func NewTrustedProxiesMiddleware(name string) interfaces.IMiddleware {
+	return &TrustedProxiesMiddleware{
+		name: name,
+	}
+}
+
+func (t *TrustedProxiesMiddleware) Init(app interfaces.IEngine, cfg map[string]interface{}) error {
+	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	if err != nil {
+		return err
+	}
+
+	if len(t.config.Headers) == 0 {
+		t.config.Headers = []string{"forwarded", "x-forwarded-for"}
+	}
+
+	for _, h := range t.config.Headers {
+		switch strings.ToLower(h) {
+		case "forwarded", "x-forwarded-for", "x-real-ip":
+		default:
+			return fmt.Errorf("trusted proxies: unknown header %s", h)
+		}
+	}
+
+	t.trusted = make([]*net.IPNet, 0, len(t.config.Trusted))
+
+	for _, v := range t.config.Trusted {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return fmt.Errorf("trusted proxies: %w", err)
+		}
+
+		t.trusted = append(t.trusted, network)
+	}
+
+	return nil
+}
+
+func (t *TrustedProxiesMiddleware) Stop() error {
+	return nil
+}
+
+func (t *TrustedProxiesMiddleware) Name() string {
+	return t.name
+}
+
+func (t *TrustedProxiesMiddleware) Type() string {
+	return "middleware"
+}
+
+func (t *TrustedProxiesMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		if r, ok := ctx.GetRequest().(*HttpRequest); ok && r.request != nil {
+			r.clientIP = t.clientIP(remoteHost(r.request.RemoteAddr), r.request.Header)
+		}
+
+		next(c, ctx)
+	}
+}
+
+// clientIP walks the forwarded chain from the peer towards the client, the first address
+// not trusted is the client. A chain of trusted proxies only ends with its first address.
+func (t *TrustedProxiesMiddleware) clientIP(peer string, header map[string][]string) string {
+	if !t.isTrusted(peer) {
+		return peer
+	}
+
+	for _, name := range t.config.Headers {
+		chain := forwardedChain(strings.ToLower(name), header)
+
+		if len(chain) == 0 {
+			continue
+		}
+
+		client := peer
+
+		for i := len(chain) - 1; i >= 0; i-- {
+			if net.ParseIP(chain[i]) == nil {
+				// unknown or obfuscated, the hops before it cannot be verified
+				return client
+			}
+
+			client = chain[i]
+
+			if !t.isTrusted(client) {
+				return client
+			}
+		}
+
+		return client
+	}
+
+	return peer
+}
+
+func (t *TrustedProxiesMiddleware) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range t.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedChain returns the addresses of a forwarding header from the client to the last proxy
+func forwardedChain(name string, header map[string][]string) []string {
+	res := make([]string, 0, 2)
+
+	switch name {
+	case "x-real-ip":
+		if v := strings.TrimSpace(first(header["X-Real-Ip"])); v != "" {
+			res = append(res, v)
+		}
+
+	case "x-forwarded-for":
+		for _, line := range header["X-Forwarded-For"] {
+			for _, v := range strings.Split(line, ",") {
+				res = append(res, strings.TrimSpace(v))
+			}
+		}
+
+	case "forwarded":
+		// RFC 7239: for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"
+		for _, line := range header["Forwarded"] {
+			for _, element := range strings.Split(line, ",") {
+				addr := ""
+
+				for _, pair := range strings.Split(element, ";") {
+					k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+
+					if strings.EqualFold(k, "for") {
+						addr = forwardedNode(v)
+					}
+				}
+
+				res = append(res, addr)
+			}
+		}
+	}
+
+	return res
+}
+
+// forwardedNode returns the IP of a Forwarded node, quotes, brackets and the port removed
+func forwardedNode(v string) string {
+	v = strings.Trim(strings.TrimSpace(v), `"`)
+
+	if strings.HasPrefix(v, "[") {
+		end := strings.Index(v, "]")
+
+		if end == -1 {
+			return ""
+		}
+
+		return v[1:end]
+	}
+
+	if host, _, err := net.SplitHostPort(v); err == nil {
+		return host
+	}
+
+	return v
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxiesMiddleware(t *testing.T) {
+	proxies := NewTrustedProxiesMiddleware("proxies").(*TrustedProxiesMiddleware)
+
+	err := proxies.Init(nil, map[string]interface{}{
+		"trusted": []string{"10.0.0.0/8", "2001:db8::1"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("proxies")
+
+	err = s.Init(map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"proxies": proxies},
+		"base_middleware": []string{"proxies"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.PushRoute("GET", "/ip", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte(ctx.GetRequest().GetRemoteIP()))
+	}, nil)
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		xff       string
+		want      string
+	}{
+		{"direct", "192.0.2.1:1234", "", "", "192.0.2.1"},
+		{"untrusted peer", "192.0.2.1:1234", "", "203.0.113.9", "192.0.2.1"},
+		{"xff", "10.0.0.1:1234", "", "203.0.113.9, 10.1.1.1", "203.0.113.9"},
+		{"xff spoofed", "10.0.0.1:1234", "", "1.1.1.1, 203.0.113.9, 10.1.1.1", "203.0.113.9"},
+		{"all trusted", "10.0.0.1:1234", "", "10.2.2.2, 10.1.1.1", "10.2.2.2"},
+		{"forwarded", "[2001:db8::1]:443", `for="[2001:db8:cafe::17]:4711";proto=https, for=10.1.1.1`, "198.51.100.1", "2001:db8:cafe::17"},
+		{"forwarded unknown", "10.0.0.1:1234", "for=unknown, for=10.1.1.1", "", "10.1.1.1"},
+		{"no header", "10.0.0.1:1234", "", "", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = tt.remote
+
+		if tt.forwarded != "" {
+			req.Header.Set("Forwarded", tt.forwarded)
+		}
+
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if w.Body.String() != tt.want {
+			t.Fatalf("%s: want %s, got %s", tt.name, tt.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package http
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// multipart forms above this size are stored in temporary files
+const bindMaxMemory = 32 << 20
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// FieldError describes a field failing a validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by Bind when fields fail their validate rules, it maps to 422
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (t *ValidationError) Error() string {
+	msg := make([]string, 0, len(t.Fields))
+
+	for _, f := range t.Fields {
+		msg = append(msg, f.Field+" "+f.Message)
+	}
+
+	return strings.Join(msg, "; ")
+}
+
+// Bind fills the struct dst points to and validates it.
+// The body is decoded by its content type: JSON into json tags, urlencoded and multipart forms into form tags.
+// Fields tagged path, query and header are set from router values, the query string and request headers.
+// Rules of the validate tag are comma separated: required, min=N, max=N, len=N, oneof=a b c, email.
+// min, max and len compare numbers by value and strings, slices and maps by length.
+// Rules other than required are skipped for empty fields.
+func (t *HttpCtx) Bind(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: dst must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	r, ok := t.request.(*HttpRequest)
+
+	if !ok || r.request == nil {
+		return errors.New("bind: request is not set")
+	}
+
+	if err := bindBody(r.request, dst); err != nil {
+		return err
+	}
+
+	query := r.request.URL.Query()
+
+	// later sources override earlier ones for fields tagged in several
+	sources := []bindSource{
+		{"form", func(key string) []string {
+			if r.request.MultipartForm != nil {
+				return r.request.MultipartForm.Value[key]
+			}
+
+			return r.request.PostForm[key]
+		}},
+		{"query", func(key string) []string {
+			return query[key]
+		}},
+		{"header", func(key string) []string {
+			return r.request.Header.Values(key)
+		}},
+		{"path", func(key string) []string {
+			if v, ok := t.routerValues[key]; ok {
+				return []string{v}
+			}
+
+			return nil
+		}},
+	}
+
+	if err := bindValues(rv.Elem(), sources); err != nil {
+		return err
+	}
+
+	var fields []FieldError
+	validateStruct(rv.Elem(), "", &fields)
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
+
+type bindSource struct {
+	tag    string
+	values func(key string) []string
+}
+
+func bindBody(req *http.Request, dst interface{}) error {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return nil
+	}
+
+	ct := req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(ct)
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err := json.NewDecoder(req.Body).Decode(dst)
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var maxErr *http.MaxBytesError
+
+		if errors.As(err, &maxErr) {
+			return NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", maxErr.Limit))
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: invalid JSON body: %v", ErrBadRequest, err)
+		}
+
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return fmt.Errorf("%w: invalid form body: %v", ErrBadRequest, err)
+		}
+
+	case mediaType == "multipart/form-data":
+		if err := req.ParseMultipartForm(bindMaxMemory); err != nil {
+			return fmt.Errorf("%w: invalid multipart body: %v", ErrBadRequest, err)
+		}
+
+	case req.ContentLength > 0 || mediaType != "":
+		return fmt.Errorf("%w: %q", ErrUnsupportedMedia, ct)
+	}
+
+	return nil
+}
+
+func bindValues(v reflect.Value, sources []bindSource) error {
+	rt := v.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if sf.Anonymous && fv.Kind() == reflect.Struct {
+			if err := bindValues(fv, sources); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for _, source := range sources {
+			key := tagName(sf, source.tag)
+
+			if key == "" {
+				continue
+			}
+
+			values := source.values(key)
+
+			if len(values) == 0 {
+				continue
+			}
+
+			if err := setField(fv, values); err != nil {
+				return fmt.Errorf("%w: %s %q: %v", ErrBadRequest, source.tag, key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+
+		return setField(fv.Elem(), values)
+	}
+
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		res := reflect.MakeSlice(fv.Type(), len(values), len(values))
+
+		for i, s := range values {
+			if err := setValue(res.Index(i), s); err != nil {
+				return err
+			}
+		}
+
+		fv.Set(res)
+
+		return nil
+	}
+
+	return setValue(fv, values[0])
+}
+
+func setValue(fv reflect.Value, s string) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch fv.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+
+		return nil
+
+	case timeType:
+		tm, err := time.Parse(time.RFC3339, s)
+
+		if err != nil {
+			return err
+		}
+
+		fv.Set(reflect.ValueOf(tm))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(n)
+
+	case reflect.Slice:
+		// []byte
+		fv.SetBytes([]byte(s))
+
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
+
+// tagName returns the name of the field in source, "" if the field is not bound from it
+func tagName(sf reflect.StructField, source string) string {
+	tag, ok := sf.Tag.Lookup(source)
+
+	if !ok {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// fieldName is the name used in validation errors, the first bound tag or the Go name
+func fieldName(sf reflect.StructField) string {
+	for _, source := range []string{"json", "path", "query", "form", "header"} {
+		if name := tagName(sf, source); name != "" {
+			return name
+		}
+	}
+
+	return sf.Name
+}
+
+func validateStruct(v reflect.Value, prefix string, fields *[]FieldError) {
+	rt := v.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		name := prefix + fieldName(sf)
+
+		if sf.Anonymous {
+			name = strings.TrimSuffix(prefix, ".")
+		}
+
+		if rules := sf.Tag.Get("validate"); rules != "" && rules != "-" {
+			validateField(fv, name, rules, fields)
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			if name != "" {
+				name += "."
+			}
+
+			validateStruct(fv, name, fields)
+		}
+	}
+}
+
+func validateField(fv reflect.Value, name string, rules string, fields *[]FieldError) {
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			if strings.Contains(","+rules+",", ",required,") {
+				*fields = append(*fields, FieldError{Field: name, Rule: "required", Message: "is required"})
+			}
+
+			// optional fields are validated only when set
+			return
+		}
+
+		fv = fv.Elem()
+	}
+
+	if fv.IsZero() {
+		if strings.Contains(","+rules+",", ",required,") {
+			*fields = append(*fields, FieldError{Field: name, Rule: "required", Message: "is required"})
+		}
+
+		// empty optional fields are not validated
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if msg := checkRule(fv, rule, param); msg != "" {
+			*fields = append(*fields, FieldError{Field: name, Rule: rule, Message: msg})
+		}
+	}
+}
+
+// checkRule returns the failure message of rule, "" when fv passes
+func checkRule(fv reflect.Value, rule string, param string) string {
+	switch rule {
+	case "":
+		return ""
+
+	case "required":
+		// checked by validateField
+
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+
+		if err != nil {
+			return "has an invalid " + rule + " rule"
+		}
+
+		n, isLen, ok := measure(fv)
+
+		if !ok {
+			return "does not support " + rule
+		}
+
+		what := "be"
+
+		if isLen {
+			what = "have length"
+		}
+
+		switch {
+		case rule == "min" && n < limit:
+			return "must " + what + " at least " + param
+		case rule == "max" && n > limit:
+			return "must " + what + " at most " + param
+		case rule == "len" && n != limit:
+			return "must " + what + " " + param
+		}
+
+	case "oneof":
+		s := fmt.Sprint(fv.Interface())
+
+		for _, v := range strings.Fields(param) {
+			if s == v {
+				return ""
+			}
+		}
+
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+
+	case "email":
+		if fv.Kind() != reflect.String {
+			return "does not support email"
+		}
+
+		if s := fv.String(); s != "" {
+			if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+				return "must be a valid email address"
+			}
+		}
+
+	default:
+		return "has an unknown rule " + rule
+	}
+
+	return ""
+}
+
+// measure returns the value of numbers and the length of strings, slices and maps
+func measure(fv reflect.Value) (float64, bool, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(len([]rune(fv.String()))), true, true
+
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true, true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false, true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false, true
+
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false, true
+	}
+
+	return 0, false, false
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type bindUser struct {
+	ID      int          `path:"id" validate:"min=1"`
+	Page    int          `query:"page" validate:"min=1,max=100"`
+	Tags    []string     `query:"tag" validate:"max=2"`
+	Trace   string       `header:"X-Trace"`
+	Name    string       `json:"name" form:"name" validate:"required,min=2"`
+	Email   string       `json:"email" form:"email" validate:"email"`
+	Role    string       `json:"role" form:"role" validate:"oneof=admin user"`
+	Age     *int         `json:"age" validate:"min=18"`
+	Address *bindAddress `json:"address"`
+}
+
+func newBindServer(t *testing.T, handler RouteFuncE) *Http {
+	s := New("bind")
+
+	if err := s.Init(map[string]interface{}{"middlewares": map[string]IMiddleware{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PushRouteE("POST", "/users/{id:int}", handler, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func bindRequest(s *Http, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/users/7?page=2&tag=a&tag=b", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Trace", "abc")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestHttpCtx_Bind(t *testing.T) {
+	var got bindUser
+
+	s := newBindServer(t, func(_ context.Context, ctx ICtx) error {
+		got = bindUser{}
+
+		if err := ctx.Bind(&got); err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusCreated, map[string]int{"id": got.ID})
+	})
+
+	w := bindRequest(s, "application/json", `{"name":"alex","email":"a@b.io","role":"admin","age":20,"address":{"city":"Oslo"}}`)
+
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != "application/json; charset=utf-8" || w.Body.String() != `{"id":7}` {
+		t.Fatalf("unexpected response %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	if got.Page != 2 || fmt.Sprint(got.Tags) != "[a b]" || got.Trace != "abc" || got.Name != "alex" || *got.Age != 20 || got.Address.City != "Oslo" {
+		t.Fatalf("unexpected bind %+v", got)
+	}
+
+	w = bindRequest(s, "application/x-www-form-urlencoded", "name=bob&role=user")
+
+	if w.Code != http.StatusCreated || got.Name != "bob" || got.Role != "user" {
+		t.Fatalf("unexpected form bind %d %+v", w.Code, got)
+	}
+}
+
+func TestHttpCtx_BindProblems(t *testing.T) {
+	s := newBindServer(t, func(_ context.Context, ctx ICtx) error {
+		var dst bindUser
+
+		return ctx.Bind(&dst)
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		fields      []string
+	}{
+		{"validation", "application/json", `{"name":"a","email":"nope","role":"root","age":3,"address":{}}`, 422, []string{"name", "email", "role", "age", "address.city"}},
+		{"required", "application/json", `{"role":"user"}`, 422, []string{"name"}},
+		{"invalid json", "application/json", `{"name":`, 400, nil},
+		{"wrong type", "application/json", `{"name":1}`, 400, nil},
+		{"unsupported media", "text/plain", `name`, 415, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := bindRequest(s, tt.contentType, tt.body)
+
+			if w.Code != tt.status || w.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf("unexpected response %d %q %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+			}
+
+			var p struct {
+				Status   int          `json:"status"`
+				Title    string       `json:"title"`
+				Instance string       `json:"instance"`
+				Errors   []FieldError `json:"errors"`
+			}
+
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+
+			if p.Status != tt.status || p.Title != http.StatusText(tt.status) || p.Instance != "/users/7" {
+				t.Fatalf("unexpected problem %s", w.Body.String())
+			}
+
+			fields := make([]string, 0, len(p.Errors))
+
+			for _, f := range p.Errors {
+				fields = append(fields, f.Field)
+			}
+
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("want fields %v, got %s", tt.fields, w.Body.String())
+			}
+		})
+	}
+}
+
+type quotaError struct{}
+
+func (quotaError) Error() string   { return "quota exceeded" }
+func (quotaError) HTTPStatus() int { return http.StatusPaymentRequired }
+
+func TestRouteFuncE_ErrorMapping(t *testing.T) {
+	s := New("problem")
+
+	if err := s.Init(map[string]interface{}{"middlewares": map[string]IMiddleware{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := map[string]error{
+		"not-found": fmt.Errorf("user 7: %w", ErrNotFound),
+		"typed":     quotaError{},
+		"problem":   &Problem{Type: "https://example.com/out-of-stock", Title: "Out of stock", Status: 409, Extensions: map[string]interface{}{"sku": "x1"}},
+		"internal":  errors.New("db password is hunter2"),
+		"timeout":   context.DeadlineExceeded,
+	}
+
+	for name, err := range errs {
+		err := err
+
+		_ = s.PushRouteE("GET", "/"+name, func(context.Context, ICtx) error { return err }, nil)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/not-found", 404, `"detail":"user 7: not found"`},
+		{"/typed", 402, `"detail":"quota exceeded"`},
+		{"/problem", 409, `"sku":"x1"`},
+		{"/internal", 500, `"title":"Internal Server Error"`},
+		{"/timeout", 504, `"status":504`},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Fatalf("%s: unexpected response %d %s", tt.path, w.Code, w.Body.String())
+		}
+
+		if strings.Contains(w.Body.String(), "hunter2") {
+			t.Fatalf("internal error details leaked: %s", w.Body.String())
+		}
+	}
+}
+
+func TestHttp_ContentTypeDetection(t *testing.T) {
+	s := New("content")
+
+	if err := s.Init(map[string]interface{}{"middlewares": map[string]IMiddleware{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := map[string]string{
+		"/json":  `{"a":1}`,
+		"/text":  "hello",
+		"/html":  "<!DOCTYPE html><html></html>",
+		"/empty": "",
+	}
+
+	for path, body := range bodies {
+		body := body
+
+		_ = s.PushRoute("GET", path, func(_ context.Context, ctx ICtx) {
+			if body != "" {
+				ctx.GetResponse().SetBody([]byte(body))
+			}
+		}, nil)
+	}
+
+	tests := map[string]string{
+		"/json":  "application/json; charset=utf-8",
+		"/text":  "text/plain; charset=utf-8",
+		"/html":  "text/html; charset=utf-8",
+		"/empty": "",
+	}
+
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if got := w.Header().Get("Content-Type"); got != want {
+			t.Fatalf("%s: want %q, got %q", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHttp_Drain(t *testing.T) {
+	s := New("drain")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares":    map[string]IMiddleware{},
+		"drain_timeout":  300 * time.Millisecond,
+		"drain_delay":    200 * time.Millisecond,
+		"readiness_path": "/readyz",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stuckErr := make(chan error, 1)
+
+	_ = s.PushRoute("GET", "/slow", func(_ context.Context, ctx ICtx) {
+		time.Sleep(50 * time.Millisecond)
+		ctx.GetResponse().SetBody([]byte("done"))
+	}, nil)
+
+	_ = s.PushRoute("GET", "/stuck", func(c context.Context, ctx ICtx) {
+		<-c.Done()
+		stuckErr <- c.Err()
+	}, nil)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(srv.URL + path)
+
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if status := get("/readyz"); status != http.StatusOK {
+		t.Fatalf("want ready, got %d", status)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		get("/stuck")
+	}()
+
+	for s.drain.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- s.Stop()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if status := get("/readyz"); status != http.StatusServiceUnavailable {
+		t.Fatalf("want readiness failing while draining, got %d", status)
+	}
+
+	if s.Ready() {
+		t.Fatal("server must not be ready")
+	}
+
+	if status := get("/slow"); status != http.StatusOK {
+		t.Fatalf("requests must be served during drain_delay, got %d", status)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if status := get("/slow"); status != http.StatusServiceUnavailable {
+		t.Fatalf("new requests must be rejected after drain_delay, got %d", status)
+	}
+
+	select {
+	case err = <-stuckErr:
+		if err != context.Canceled {
+			t.Fatalf("want canceled handler context, got %v", err)
+		}
+
+	case <-time.After(2 * time.Second):
+		t.Fatal("stuck handler not canceled")
+	}
+
+	if err = <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+
+	if s.Aborted() != 1 {
+		t.Fatalf("want 1 aborted request, got %d", s.Aborted())
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import "strings"
+
+// Group registers routes under a common path prefix with a shared middleware chain.
+// The chain runs after base_middleware and before the middlewares of the route itself.
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []string
+	listeners   []string
+}
+
+// Group returns a route group for prefix, middlewares are names registered in the server config
+func (t *Router) Group(prefix string, middlewares ...string) *Group {
+	return &Group{
+		router:      t,
+		prefix:      joinPath("", prefix),
+		middlewares: middlewares,
+	}
+}
+
+// Group returns a nested group, its prefix and middlewares are appended to the parent ones
+func (t *Group) Group(prefix string, middlewares ...string) *Group {
+	md := make([]string, 0, len(t.middlewares)+len(middlewares))
+	md = append(md, t.middlewares...)
+	md = append(md, middlewares...)
+
+	return &Group{
+		router:      t.router,
+		prefix:      joinPath(t.prefix, prefix),
+		middlewares: md,
+		listeners:   t.listeners,
+	}
+}
+
+// Listeners returns a copy of the group serving its routes only on the named listeners
+func (t *Group) Listeners(names ...string) *Group {
+	return &Group{
+		router:      t.router,
+		prefix:      t.prefix,
+		middlewares: t.middlewares,
+		listeners:   names,
+	}
+}
+
+// PushRoute adds a route relative to the group prefix
+func (t *Group) PushRoute(method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error {
+	md := make([]string, 0, len(t.middlewares)+len(middlewares))
+	md = append(md, t.middlewares...)
+	md = append(md, middlewares...)
+
+	return t.router.pushRoute(method, joinPath(t.prefix, path), handler, md, t.listeners, meta)
+}
+
+// PushRouteE adds a route returning an error relative to the group prefix
+func (t *Group) PushRouteE(method string, path string, handler RouteFuncE, middlewares []string, meta ...RouteMeta) error {
+	return t.PushRoute(method, path, routeE(handler), middlewares, meta...)
+}
+
+// Prefix returns the full path prefix of the group
+func (t *Group) Prefix() string {
+	return t.prefix
+}
+
+func joinPath(prefix string, path string) string {
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		if prefix == "" {
+			return "/"
+		}
+
+		return prefix
+	}
+
+	return strings.TrimSuffix(prefix, "/") + "/" + path
+}
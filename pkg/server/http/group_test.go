@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"testing"
+)
+
+type tagMiddleware string
+
+func (t tagMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody(append(ctx.GetResponse().GetBody(), t...))
+		next(c, ctx)
+	}
+}
+
+func TestGroup_Nested(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{
+		"api":   tagMiddleware("api,"),
+		"admin": tagMiddleware("admin,"),
+		"route": tagMiddleware("route,"),
+	})
+
+	api := r.Group("/api/v1", "api")
+	admin := api.Group("admin/", "admin")
+
+	handler := func(c context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody(append(ctx.GetResponse().GetBody(), "handler"...))
+	}
+
+	if err := api.PushRoute("GET", "/status", handler, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := admin.PushRoute("GET", "/users/{id}", handler, []string{"route"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := admin.PushRoute("POST", "", handler, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if admin.Prefix() != "/api/v1/admin" {
+		t.Fatalf("unexpected prefix %s", admin.Prefix())
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"GET", "/api/v1/status", "api,handler"},
+		{"GET", "/api/v1/admin/users/7", "api,admin,route,handler"},
+		{"POST", "/api/v1/admin", "api,admin,handler"},
+	}
+
+	for _, tt := range tests {
+		route, props := r.Find(tt.method, tt.path)
+
+		if route == nil {
+			t.Fatalf("%s %s: route not found", tt.method, tt.path)
+		}
+
+		ctx := HttpCtxPool.Get().(*HttpCtx)
+		ctx.GetResponse().Clear()
+		ctx.SetRouteProps(props)
+		route(context.Background(), ctx)
+
+		if string(ctx.GetResponse().GetBody()) != tt.body {
+			t.Fatalf("%s %s: want %s, got %s", tt.method, tt.path, tt.body, ctx.GetResponse().GetBody())
+		}
+
+		if tt.path == "/api/v1/admin/users/7" && ctx.GetRouterValue("id") != "7" {
+			t.Fatalf("want id 7, got %s", ctx.GetRouterValue("id"))
+		}
+
+		HttpCtxPool.Put(ctx)
+	}
+}
+
+func TestGroup_UnknownMiddleware(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{})
+
+	if err := r.Group("/api", "missing").PushRoute("GET", "/x", func(context.Context, ICtx) {}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -2,9 +2,18 @@ package http
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/drain"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/listener"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -15,17 +24,50 @@ type Http struct {
 	name   string
 	config Config
 
-	router *Router
-	server *http.Server
-	md     func(RouteFunc) RouteFunc
-
-	counter      metric.Int64Counter
-	counterError metric.Int64Counter
-	timeCounter  metric.Int64Histogram
+	router    *Router
+	server    *http.Server
+	listeners []*listener.Listener
+	tls       *tlsReloader
+	drain     *drain.Drain
+	md        func(RouteFunc) RouteFunc
+
+	counter           metric.Int64Counter
+	counterError      metric.Int64Counter
+	timeCounter       metric.Int64Histogram
+	streamCounter     metric.Int64UpDownCounter
+	streamTimeCounter metric.Int64Histogram
+	abortedCounter    metric.Int64Counter
+
+	upgrader   websocket.Upgrader
+	wsMu       sync.Mutex
+	wsConns    map[*wsConn]struct{}
+	wsWG       sync.WaitGroup
+	wsStopping bool
+
+	wsCounter       metric.Int64UpDownCounter
+	wsMessagesIn    metric.Int64Counter
+	wsMessagesOut   metric.Int64Counter
+	wsSlowConsumers metric.Int64Counter
 }
 
 type Config struct {
-	Port string `yaml:"port"`
+	Port      string            `yaml:"port"`
+	Listeners []listener.Config `yaml:"-"` // listeners key, replaces port, all listeners serve the same router
+
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // default 5s
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // default read_timeout
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // default 10s
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // default 5s
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`    // default 1MB
+	MaxBodySize       int64         `yaml:"max_body_size"`       // bytes, larger bodies get 413, 0 - unlimited
+
+	DrainTimeout  time.Duration `yaml:"drain_timeout"`  // in-flight requests are canceled after it on stop, default 10s
+	DrainDelay    time.Duration `yaml:"drain_delay"`    // requests are still served with failing readiness for it on stop
+	ReadinessPath string        `yaml:"readiness_path"` // answers 200 or 503 while draining, e.g. /readyz
+
+	TLS          TLSConfig `yaml:"tls"`
+	DisableHTTP2 bool      `yaml:"disable_http2"` // HTTP/2 is negotiated over TLS unless disabled
+	H2C          bool      `yaml:"h2c"`           // HTTP/2 without TLS (prior knowledge)
 
 	CookieDomain   string `yaml:"cookie_domain"`
 	CookieSameSite string `yaml:"cookie_same_site"`
@@ -33,6 +75,9 @@ type Config struct {
 	CookieSecure   bool   `yaml:"cookie_secure"`
 
 	BaseMiddleware []string `yaml:"base_middleware"`
+
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi"`
 }
 
 func New(name string) *Http {
@@ -60,16 +105,50 @@ func (t *Http) Init(cfg map[string]interface{}) error {
 		delete(cfg, "middlewares")
 	}
 
-	err := decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
+	listeners, err := listener.Pop(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = decode.Decode(cfg, &t.config, "yaml", decode.DecoderStrongFoundDst)
 	if err != nil {
 		return err
 	}
 
+	t.config.Listeners = listeners
+
 	if t.config.Port == "" {
 		t.config.Port = "80"
 	}
 
+	if t.config.ReadTimeout <= 0 {
+		t.config.ReadTimeout = 5 * time.Second
+	}
+
+	if t.config.WriteTimeout <= 0 {
+		t.config.WriteTimeout = 10 * time.Second
+	}
+
+	if t.config.IdleTimeout <= 0 {
+		t.config.IdleTimeout = 5 * time.Second
+	}
+
+	if t.config.MaxHeaderBytes <= 0 {
+		t.config.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+
+	if t.config.DrainTimeout <= 0 {
+		t.config.DrainTimeout = 10 * time.Second
+	}
+
+	t.drain = drain.New()
+
 	t.router = NewRouter(middlewares)
+	t.initWebSocket()
+
+	if err = t.initOpenAPI(); err != nil {
+		return err
+	}
 
 	if t.config.BaseMiddleware == nil {
 		t.config.BaseMiddleware = []string{}
@@ -92,25 +171,74 @@ func (t *Http) Init(cfg map[string]interface{}) error {
 		Addr:                         ":" + t.config.Port,
 		Handler:                      otelhttp.NewHandler(t, t.name),
 		DisableGeneralOptionsHandler: false,
-		ReadTimeout:                  5 * time.Second,
-		WriteTimeout:                 10 * time.Second,
-		IdleTimeout:                  5 * time.Second,
+		ReadTimeout:                  t.config.ReadTimeout,
+		ReadHeaderTimeout:            t.config.ReadHeaderTimeout,
+		WriteTimeout:                 t.config.WriteTimeout,
+		IdleTimeout:                  t.config.IdleTimeout,
+		MaxHeaderBytes:               t.config.MaxHeaderBytes,
+		Protocols:                    new(http.Protocols),
+		ConnContext: func(c context.Context, conn net.Conn) context.Context {
+			return listener.WithName(c, listener.NameOf(conn))
+		},
+	}
+
+	t.server.Protocols.SetHTTP1(true)
+	t.server.Protocols.SetUnencryptedHTTP2(t.config.H2C)
+
+	if t.config.TLS.enabled() {
+		nextProtos := []string{"http/1.1"}
+
+		if !t.config.DisableHTTP2 {
+			t.server.Protocols.SetHTTP2(true)
+			nextProtos = []string{"h2", "http/1.1"}
+		}
+
+		t.tls, err = newTLSReloader(t.config.TLS, nextProtos)
+		if err != nil {
+			return err
+		}
+
+		t.server.TLSConfig = t.tls.TLSConfig()
 	}
 
 	t.counter, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".count")
 	t.counterError, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".count_error")
 	t.timeCounter, _ = otel.Meter("").Int64Histogram("server_http." + t.name + ".time")
+	t.streamCounter, _ = otel.Meter("").Int64UpDownCounter("server_http." + t.name + ".streams")
+	t.streamTimeCounter, _ = otel.Meter("").Int64Histogram("server_http." + t.name + ".stream_time")
+	t.abortedCounter, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".aborted")
+	t.wsCounter, _ = otel.Meter("").Int64UpDownCounter("server_http." + t.name + ".websocket_connections")
+	t.wsMessagesIn, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".websocket_messages_in")
+	t.wsMessagesOut, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".websocket_messages_out")
+	t.wsSlowConsumers, _ = otel.Meter("").Int64Counter("server_http." + t.name + ".websocket_slow_consumers")
 
 	return nil
 
 }
 
 func (t *Http) Start() error {
-	listenErr := make(chan error, 1)
+	listeners, err := listener.ListenAll(listener.Defaults(t.config.Listeners, t.config.Port))
 
-	go func() {
-		listenErr <- t.server.ListenAndServe()
-	}()
+	if err != nil {
+		return err
+	}
+
+	t.listeners = listeners
+	listenErr := make(chan error, len(listeners))
+
+	if t.tls != nil {
+		t.tls.Start()
+	}
+
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			if t.tls != nil {
+				listenErr <- t.server.ServeTLS(l, "", "")
+			} else {
+				listenErr <- t.server.Serve(l)
+			}
+		}(l)
+	}
 
 	select {
 	case err := <-listenErr:
@@ -123,14 +251,58 @@ func (t *Http) Start() error {
 	return nil
 }
 
+// Stop drains the server: readiness fails, after drain_delay new requests are rejected and the listeners closed,
+// in-flight requests are waited for up to drain_timeout and then canceled
 func (t *Http) Stop() error {
-	err := t.server.Shutdown(context.TODO())
+	t.drain.SetNotReady()
+
+	if t.config.DrainDelay > 0 {
+		time.Sleep(t.config.DrainDelay)
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), t.config.DrainTimeout)
+	defer cancel()
 
-	time.Sleep(time.Second)
+	t.drain.Close()
+
+	shutdown := make(chan error, 1)
+
+	go func() {
+		shutdown <- t.server.Shutdown(c)
+	}()
+
+	// hijacked connections are not tracked by Shutdown
+	t.closeWebSockets()
+
+	if aborted := t.drain.Wait(c); aborted > 0 {
+		t.abortedCounter.Add(context.Background(), aborted)
+		slog.Default().Warn("http: requests aborted on stop", slog.String("server", t.name), slog.Int64("aborted", aborted))
+	}
+
+	err := <-shutdown
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		// connections of the aborted requests
+		err = t.server.Close()
+	}
+
+	if t.tls != nil {
+		t.tls.Stop()
+	}
 
 	return err
 }
 
+// Ready is false once the server starts draining
+func (t *Http) Ready() bool {
+	return t.drain != nil && t.drain.Ready()
+}
+
+// Aborted returns the number of requests canceled by the drain deadline on stop
+func (t *Http) Aborted() int64 {
+	return t.drain.AbortedCount()
+}
+
 func (t *Http) Name() string {
 	return t.name
 }
@@ -140,62 +312,175 @@ func (t *Http) Type() string {
 }
 
 func (t *Http) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if t.config.ReadinessPath != "" && req.URL.Path == t.config.ReadinessPath {
+		if t.Ready() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		return
+	}
+
+	c, done, ok := t.drain.Begin(req.Context())
+
+	if !ok {
+		// draining, the client retries on another connection
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		t.counterError.Add(context.Background(), 1)
+		return
+	}
+
+	// handlers and streams see the cancellation of the drain deadline
+	req = req.WithContext(c)
+
+	ctx := HttpCtxPool.Get().(*HttpCtx)
+	ctx.Fill(req)
+	ctx.writer = w
+	ctx.server = t
+
+	// a handler left running by TimeoutMiddleware stays in flight until it returns
+	ctx.finish = done
+	defer ctx.release()
+
 	defer func(s time.Time) {
+		if ctx.upgraded.Load() {
+			// websocket connections are measured by their own metrics
+			return
+		}
+
+		if ctx.streaming.Load() {
+			// streams are kept out of the request time, they last as long as the client listens
+			t.streamCounter.Add(context.Background(), -1)
+			t.streamTimeCounter.Record(context.Background(), time.Since(s).Milliseconds())
+			return
+		}
+
 		t.timeCounter.Record(context.Background(), time.Since(s).Milliseconds())
 	}(time.Now())
 	t.counter.Add(context.Background(), 1)
 
-	ctx := HttpCtxPool.Get().(*HttpCtx)
-	defer HttpCtxPool.Put(ctx)
-	ctx.Fill(req)
+	if t.config.MaxBodySize > 0 {
+		if req.ContentLength > t.config.MaxBodySize {
+			ctx.GetResponse().SetStatus(http.StatusRequestEntityTooLarge)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			t.counterError.Add(context.Background(), 1)
+			return
+		}
+
+		// chunked bodies fail on read past the limit
+		req.Body = http.MaxBytesReader(w, req.Body, t.config.MaxBodySize)
+	}
 
-	route, props := t.router.Find(req.Method, req.URL.Path)
+	route, props, pattern := t.router.FindRoute(req.Method, req.URL.Path)
+
+	if route == nil && req.Method == http.MethodHead {
+		// the response body is discarded by net/http for HEAD requests
+		route, props, pattern = t.router.FindRoute(http.MethodGet, req.URL.Path)
+	}
+
+	if route == nil {
+		if allowed := t.router.Allowed(req.URL.Path); len(allowed) > 0 {
+			route = allowHandler(req.Method, strings.Join(allowed, ", "))
+		}
+	}
 
 	if route == nil {
 		ctx.GetResponse().SetStatus(404)
 		w.WriteHeader(404)
 	} else {
 		ctx.SetRouteProps(props)
+		ctx.route = pattern
 
 		t.md(route)(req.Context(), ctx)
 
-		header := ctx.GetResponse().Header().GetAsMap()
-
-		if _, ok := header["Content-Type"]; !ok {
-			header["Content-Type"] = []string{"application/json; charset=utf-8"}
-		}
+		// a streamed response has already been written, an upgraded connection is hijacked
+		if !ctx.IsStreaming() {
+			body := ctx.GetResponse().GetBody()
+			status := ctx.GetResponse().GetStatus()
+
+			if body != nil && len(body) > 0 {
+				t.writeHeader(w, ctx, status, detectContentType(body))
+				w.Write(body)
+			} else {
+				if status == http.StatusOK {
+					status = http.StatusNoContent
+				}
 
-		for k, v := range ctx.GetResponse().Header().GetAsMap() {
-			for _, v2 := range v {
-				w.Header().Set(k, v2)
+				t.writeHeader(w, ctx, status, "")
 			}
 		}
+	}
 
-		cookie := ctx.GetResponse().Cookie().(*HttpCookie).ToHttp(t.config.CookieDomain, t.config.CookieSameSite, t.config.CookieHttpOnly, t.config.CookieSecure)
+	if ctx.GetResponse().GetStatus() >= 400 {
+		t.counterError.Add(context.Background(), 1)
+	}
+}
 
-		for i := 0; i < len(cookie); i++ {
-			w.Header().Add("Set-Cookie", cookie[i])
-		}
+// detectContentType returns the content type of a body without one, JSON documents are recognized
+// before falling back to http.DetectContentType
+func detectContentType(body []byte) string {
+	if json.Valid(body) {
+		return "application/json; charset=utf-8"
+	}
 
-		body := ctx.GetResponse().GetBody()
-		status := ctx.GetResponse().GetStatus()
-		if body != nil && len(body) > 0 {
-			w.WriteHeader(status)
-			w.Write(body)
-		} else {
-			if status == http.StatusOK {
-				status = http.StatusNoContent
-			}
+	return http.DetectContentType(body)
+}
 
-			w.WriteHeader(status)
+// writeHeader sends the status, the response headers and cookies, contentType is used when not set
+func (t *Http) writeHeader(w http.ResponseWriter, ctx ICtx, status int, contentType string) {
+	header := ctx.GetResponse().Header().GetAsMap()
+
+	if _, ok := header["Content-Type"]; !ok && contentType != "" {
+		header["Content-Type"] = []string{contentType}
+	}
+
+	for k, v := range ctx.GetResponse().Header().GetAsMap() {
+		for _, v2 := range v {
+			w.Header().Set(k, v2)
 		}
 	}
 
-	if ctx.GetResponse().GetStatus() >= 400 {
-		t.counterError.Add(context.Background(), 1)
+	cookie := ctx.GetResponse().Cookie().(*HttpCookie).ToHttp(t.config.CookieDomain, t.config.CookieSameSite, t.config.CookieHttpOnly, t.config.CookieSecure)
+
+	for i := 0; i < len(cookie); i++ {
+		w.Header().Add("Set-Cookie", cookie[i])
+	}
+
+	w.WriteHeader(status)
+}
+
+// PushRoute adds a route, meta documents it in the OpenAPI document
+func (t *Http) PushRoute(method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error {
+	return t.router.PushRoute(method, path, handler, middlewares, meta...)
+}
+
+// PushRouteOn adds a route served only on the named listeners, other listeners answer 404
+func (t *Http) PushRouteOn(listeners []string, method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error {
+	return t.router.pushRoute(method, path, handler, middlewares, listeners, meta)
+}
+
+// PushRouteE adds a route returning an error, the error is written as an RFC 7807 problem
+func (t *Http) PushRouteE(method string, path string, handler RouteFuncE, middlewares []string, meta ...RouteMeta) error {
+	return t.router.PushRoute(method, path, routeE(handler), middlewares, meta...)
+}
+
+// allowHandler answers OPTIONS requests and methods without a route for a known path with the Allow header
+func allowHandler(method string, allow string) RouteFunc {
+	return func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().Header().Set("Allow", allow)
+
+		if method == http.MethodOptions {
+			ctx.GetResponse().SetStatus(http.StatusNoContent)
+			return
+		}
+
+		ctx.GetResponse().SetStatus(http.StatusMethodNotAllowed)
 	}
 }
 
-func (t *Http) PushRoute(method string, path string, handler RouteFunc, middlewares []string) {
-	t.router.PushRoute(method, path, handler, middlewares)
+// Group returns a route group sharing the path prefix and the middlewares
+func (t *Http) Group(prefix string, middlewares ...string) *Group {
+	return t.router.Group(prefix, middlewares...)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 type HttpCtx struct {
@@ -12,8 +13,25 @@ type HttpCtx struct {
 	values       map[string]interface{}
 	done         chan struct{}
 	routerValues map[string]string
+	route        string // template of the matched route
+
+	writer    http.ResponseWriter
+	server    *Http
+	streaming atomic.Bool
+	upgraded  atomic.Bool
+
+	refs   atomic.Int32  // ServeHTTP and the handlers outliving it, the context is pooled at zero
+	finish func()        // called once the context is released
+	guard  *atomic.Int32 // response state shared with TimeoutMiddleware, nil without it
 }
 
+// response states of a context run by TimeoutMiddleware
+const (
+	responsePending int32 = iota
+	responseCommitted
+	responseTimedOut
+)
+
 var HttpCtxPool = sync.Pool{
 	New: func() interface{} {
 		return &HttpCtx{
@@ -30,6 +48,98 @@ func (t *HttpCtx) Fill(request *http.Request) {
 	t.response.Clear()
 	t.values = make(map[string]interface{}, 10)
 	t.routerValues = nil
+	t.route = ""
+	t.writer = nil
+	t.server = nil
+	t.streaming.Store(false)
+	t.upgraded.Store(false)
+	t.refs.Store(1)
+	t.finish = nil
+	t.guard = nil
+}
+
+// hold keeps the context out of HttpCtxPool until the returned func is called
+func (t *HttpCtx) hold() func() {
+	t.refs.Add(1)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(t.release)
+	}
+}
+
+// release drops a reference, the last one returns the context to HttpCtxPool
+func (t *HttpCtx) release() {
+	if t.refs.Add(-1) > 0 {
+		return
+	}
+
+	if t.finish != nil {
+		t.finish()
+		t.finish = nil
+	}
+
+	HttpCtxPool.Put(t)
+}
+
+// commit claims the connection for a stream or an upgrade, false once TimeoutMiddleware has answered
+func (t *HttpCtx) commit() bool {
+	if t.guard == nil {
+		return true
+	}
+
+	return t.guard.CompareAndSwap(responsePending, responseCommitted) || t.guard.Load() == responseCommitted
+}
+
+// fork returns a copy of the context for a handler run by TimeoutMiddleware. The request is shared,
+// the response and the user values are copied so the handler never touches what ServeHTTP writes.
+func (t *HttpCtx) fork(guard *atomic.Int32) *HttpCtx {
+	f := &HttpCtx{
+		request:      t.request,
+		response:     copyResponse(t.response),
+		values:       make(map[string]interface{}, len(t.values)),
+		routerValues: t.routerValues,
+		route:        t.route,
+		writer:       t.writer,
+		server:       t.server,
+		guard:        guard,
+	}
+
+	for k, v := range t.values {
+		f.values[k] = v
+	}
+
+	return f
+}
+
+// join takes the response and the user values of a fork whose handler has returned
+func (t *HttpCtx) join(f *HttpCtx) {
+	t.response = f.response
+	t.values = f.values
+	t.streaming.Store(f.streaming.Load())
+	t.upgraded.Store(f.upgraded.Load())
+}
+
+func copyResponse(r IResponse) IResponse {
+	res := &HttpResponse{
+		Body:       append([]byte(nil), r.GetBody()...),
+		StatusCode: r.GetStatus(),
+		headers:    &HttpHeader{make(http.Header, len(r.Header().GetAsMap()))},
+		cookie:     &HttpCookie{data: make(map[string]cookieItem)},
+	}
+
+	for k, v := range r.Header().GetAsMap() {
+		res.headers.(*HttpHeader).Header[k] = append([]string(nil), v...)
+	}
+
+	if c, ok := r.Cookie().(*HttpCookie); ok {
+		for k, v := range c.data {
+			res.cookie.(*HttpCookie).data[k] = v
+		}
+	}
+
+	return res
 }
 
 func (t *HttpCtx) GetRequest() IRequest {
@@ -65,3 +175,7 @@ func (t *HttpCtx) GetRouterValue(key string) string {
 func (t *HttpCtx) SetRouteProps(values map[string]string) {
 	t.routerValues = values
 }
+
+func (t *HttpCtx) GetRoute() string {
+	return t.route
+}
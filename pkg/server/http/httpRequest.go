@@ -2,17 +2,20 @@ package http
 
 import (
 	"io"
+	"net"
 	"net/http"
 )
 
 type HttpRequest struct {
-	request *http.Request
-	cookies ICookie
-	headers IHeader
+	request  *http.Request
+	cookies  ICookie
+	headers  IHeader
+	clientIP string // resolved by TrustedProxiesMiddleware
 }
 
 func (t *HttpRequest) Fill(request *http.Request) {
 	t.request = request
+	t.clientIP = ""
 
 	if t.cookies == nil {
 		t.cookies = &HttpCookie{}
@@ -59,8 +62,23 @@ func (t *HttpRequest) GetQuery() IQuery {
 	return t.request.URL.Query()
 }
 
+// GetRemoteIP returns the client IP without the port, the one resolved by TrustedProxiesMiddleware
+// behind trusted proxies
 func (t *HttpRequest) GetRemoteIP() string {
-	return t.request.RemoteAddr
+	if t.clientIP != "" {
+		return t.clientIP
+	}
+
+	return remoteHost(t.request.RemoteAddr)
+}
+
+// remoteHost returns the host of a remote address, addresses without a port are returned as is
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
 }
 
 func (t *HttpRequest) GetRemoteHost() string {
@@ -70,3 +88,11 @@ func (t *HttpRequest) GetRemoteHost() string {
 func (t *HttpRequest) GetUserAgent() string {
 	return t.request.Header.Get("User-Agent")
 }
+
+func (t *HttpRequest) GetClientIdentity() *ClientIdentity {
+	if t.request.TLS == nil || len(t.request.TLS.VerifiedChains) == 0 || len(t.request.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return newClientIdentity(t.request.TLS.VerifiedChains[0][0])
+}
@@ -0,0 +1,221 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClientGone is returned by stream writes after the client has disconnected
+var ErrClientGone = errors.New("client disconnected")
+
+// ErrResponseTimedOut is returned by Stream and SSE once TimeoutMiddleware has answered the request
+var ErrResponseTimedOut = errors.New("response timed out")
+
+// SSEEvent is a server-sent event, empty fields are not sent
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration // reconnection delay hint for the client
+}
+
+// ISSE sends server-sent events to a client
+type ISSE interface {
+	// Send writes the event and flushes it to the client
+	Send(event SSEEvent) error
+
+	// LastEventID returns the Last-Event-ID header sent by a reconnecting client
+	LastEventID() string
+
+	// Done is closed when the client disconnects
+	Done() <-chan struct{}
+}
+
+// Stream writes the response status, headers and cookies and passes the connection to fn.
+// Data written to w is sent as it is flushed, the write timeout of the server does not apply.
+// The body set on the response is ignored, Content-Type defaults to application/octet-stream.
+func (t *HttpCtx) Stream(fn func(w io.Writer, flush func()) error) error {
+	if t.writer == nil || t.server == nil {
+		return errors.New("streaming is not supported")
+	}
+
+	if t.upgraded.Load() || !t.streaming.CompareAndSwap(false, true) {
+		return errors.New("response is already streamed")
+	}
+
+	if !t.commit() {
+		t.streaming.Store(false)
+		return ErrResponseTimedOut
+	}
+
+	t.server.streamCounter.Add(context.Background(), 1)
+
+	rc := http.NewResponseController(t.writer)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	t.server.writeHeader(t.writer, t, t.response.GetStatus(), "application/octet-stream")
+
+	flush := func() {
+		_ = rc.Flush()
+	}
+
+	flush()
+
+	return fn(&streamWriter{w: t.writer, done: t.requestContext().Done()}, flush)
+}
+
+// SSE streams server-sent events. A comment is sent every heartbeat to keep proxies from closing
+// an idle connection, 0 disables it. fn should return once Done is closed.
+func (t *HttpCtx) SSE(heartbeat time.Duration, fn func(s ISSE) error) error {
+	header := t.response.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+
+	return t.Stream(func(w io.Writer, flush func()) error {
+		s := &sse{
+			w:           w,
+			flush:       flush,
+			done:        t.requestContext().Done(),
+			lastEventID: t.request.GetHeader().Get("Last-Event-ID"),
+		}
+
+		if heartbeat > 0 {
+			var wg sync.WaitGroup
+			stop := make(chan struct{})
+
+			// nothing may be written once the handler returns
+			defer wg.Wait()
+			defer close(stop)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.heartbeat(heartbeat, stop)
+			}()
+		}
+
+		return fn(s)
+	})
+}
+
+// IsStreaming reports whether the response is streamed by Stream or SSE or the connection is upgraded to WebSocket
+func (t *HttpCtx) IsStreaming() bool {
+	return t.streaming.Load() || t.upgraded.Load()
+}
+
+func (t *HttpCtx) requestContext() context.Context {
+	if r, ok := t.request.(*HttpRequest); ok && r.request != nil {
+		return r.request.Context()
+	}
+
+	return context.Background()
+}
+
+// streamWriter fails writes with ErrClientGone after the client has disconnected
+type streamWriter struct {
+	w    io.Writer
+	done <-chan struct{}
+}
+
+func (t *streamWriter) Write(p []byte) (int, error) {
+	select {
+	case <-t.done:
+		return 0, ErrClientGone
+	default:
+	}
+
+	return t.w.Write(p)
+}
+
+type sse struct {
+	mu          sync.Mutex
+	w           io.Writer
+	flush       func()
+	done        <-chan struct{}
+	lastEventID string
+}
+
+func (t *sse) Send(event SSEEvent) error {
+	var b strings.Builder
+
+	if event.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(sseLine(event.ID))
+		b.WriteByte('\n')
+	}
+
+	if event.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(sseLine(event.Event))
+		b.WriteByte('\n')
+	}
+
+	if event.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(event.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	b.WriteByte('\n')
+
+	return t.write(b.String())
+}
+
+func (t *sse) LastEventID() string {
+	return t.lastEventID
+}
+
+func (t *sse) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *sse) write(s string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := io.WriteString(t.w, s); err != nil {
+		return err
+	}
+
+	t.flush()
+
+	return nil
+}
+
+func (t *sse) heartbeat(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if t.write(": ping\n\n") != nil {
+				return
+			}
+
+		case <-stop:
+			return
+
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// sseLine removes line breaks, which would end the field
+func sseLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
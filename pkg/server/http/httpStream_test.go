@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStreamServer(t *testing.T, cfg map[string]interface{}) (*Http, *httptest.Server) {
+	s := New("stream")
+
+	if err := s.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	return s, srv
+}
+
+func TestHttpCtx_StreamWithTimeout(t *testing.T) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+	timeout.config.Timeout = 20 * time.Millisecond
+
+	s, srv := newStreamServer(t, map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"timeout": timeout},
+		"base_middleware": []string{"timeout"},
+	})
+
+	_ = s.PushRoute("GET", "/export", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().Header().Set("Content-Type", "text/csv")
+
+		_ = ctx.Stream(func(w io.Writer, flush func()) error {
+			for i := 0; i < 5; i++ {
+				if _, err := fmt.Fprintf(w, "%d\n", i); err != nil {
+					return err
+				}
+
+				flush()
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			return nil
+		})
+	}, nil)
+
+	resp, err := http.Get(srv.URL + "/export")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/csv" || string(body) != "0\n1\n2\n3\n4\n" {
+		t.Fatalf("unexpected response %d %q %q", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+}
+
+func TestHttpCtx_SSE(t *testing.T) {
+	s, srv := newStreamServer(t, map[string]interface{}{
+		"middlewares": map[string]IMiddleware{},
+	})
+
+	gone := make(chan error, 1)
+
+	_ = s.PushRoute("GET", "/events", func(_ context.Context, ctx ICtx) {
+		_ = ctx.SSE(10*time.Millisecond, func(events ISSE) error {
+			err := events.Send(SSEEvent{ID: "7", Event: "update", Data: "line1\nline2", Retry: 3 * time.Second})
+
+			if err != nil {
+				return err
+			}
+
+			<-events.Done()
+
+			gone <- events.Send(SSEEvent{Data: events.LastEventID()})
+
+			return nil
+		})
+	}, nil)
+
+	req, _ := http.NewRequest("GET", srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "6")
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+
+	for len(lines) < 8 {
+		line, err := r.ReadString('\n')
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+
+	event := strings.Join(lines[:6], "|")
+
+	if event != "id: 7|event: update|retry: 3000|data: line1|data: line2|" {
+		t.Fatalf("unexpected event %q", event)
+	}
+
+	if lines[6] != ": ping" {
+		t.Fatalf("expected heartbeat, got %q", lines[6])
+	}
+
+	_ = resp.Body.Close()
+
+	select {
+	case err = <-gone:
+		if err != ErrClientGone {
+			t.Fatalf("want ErrClientGone, got %v", err)
+		}
+
+	case <-time.After(2 * time.Second):
+		t.Fatal("client disconnect not detected")
+	}
+}
+
+func TestHttpCtx_StreamNotSupported(t *testing.T) {
+	ctx := HttpCtxPool.Get().(*HttpCtx)
+	defer HttpCtxPool.Put(ctx)
+	ctx.Fill(httptest.NewRequest("GET", "/", nil))
+
+	if err := ctx.Stream(func(io.Writer, func()) error { return nil }); err == nil {
+		t.Fatal("expected error outside of a server")
+	}
+}
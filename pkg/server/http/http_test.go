@@ -35,7 +35,7 @@ func TestHTTP_Fetch(t1 *testing.T) {
 			wantStatusCode: 200,
 		},
 		{
-			name: "base test 404",
+			name: "base test 405",
 			path: "/test",
 			args: args{
 				"POST",
@@ -44,7 +44,7 @@ func TestHTTP_Fetch(t1 *testing.T) {
 				nil,
 			},
 			wantBody:       []byte(""),
-			wantStatusCode: 404,
+			wantStatusCode: 405,
 		},
 		{
 			name: "test dynamic",
@@ -8,8 +8,24 @@ import (
 
 // IHttp defines the interface for HTTP server operations
 type IHttp interface {
-	// PushRoute adds a new route to the HTTP server
-	PushRoute(method string, path string, handler RouteFunc, middlewares []string)
+	// PushRoute adds a new route to the HTTP server, it fails on unknown middlewares and conflicting routes.
+	// The optional meta documents the route in the OpenAPI document.
+	PushRoute(method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error
+
+	// PushRouteOn adds a route served only on the named listeners
+	PushRouteOn(listeners []string, method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error
+
+	// PushRouteE adds a route returning an error, the error is mapped to a problem+json response
+	PushRouteE(method string, path string, handler RouteFuncE, middlewares []string, meta ...RouteMeta) error
+
+	// Group returns a route group with a common path prefix and middleware chain
+	Group(prefix string, middlewares ...string) *Group
+
+	// PushWebSocket adds a route upgrading requests to WebSocket connections
+	PushWebSocket(path string, handler WebSocketFunc, middlewares []string) error
+
+	// OpenAPI returns the OpenAPI 3.1 document of the registered routes as JSON
+	OpenAPI() ([]byte, error)
 }
 
 // IHeader defines the interface for HTTP headers
@@ -74,7 +90,7 @@ type IRequest interface {
 	// GetQuery returns the query parameters of the request
 	GetQuery() IQuery
 
-	// GetRemoteIP returns the remote IP address of the request
+	// GetRemoteIP returns the IP address of the client without the port
 	GetRemoteIP() string
 
 	// GetRemoteHost returns the remote host of the request
@@ -82,6 +98,9 @@ type IRequest interface {
 
 	// GetUserAgent returns the user agent of the request
 	GetUserAgent() string
+
+	// GetClientIdentity returns the verified client certificate of an mTLS connection, nil without one
+	GetClientIdentity() *ClientIdentity
 }
 
 // IResponse defines the interface for an HTTP response
@@ -127,6 +146,30 @@ type ICtx interface {
 
 	// SetRouteProps sets the router properties
 	SetRouteProps(values map[string]string)
+
+	// GetRoute returns the template of the matched route, e.g. /users/{id:int}, "" without one
+	GetRoute() string
+
+	// Stream writes the response head and passes the connection to fn for a streamed body
+	Stream(fn func(w io.Writer, flush func()) error) error
+
+	// SSE streams server-sent events with a heartbeat comment every heartbeat interval
+	SSE(heartbeat time.Duration, fn func(s ISSE) error) error
+
+	// IsStreaming reports whether the response is streamed or the connection is upgraded
+	IsStreaming() bool
+
+	// Bind decodes the body, query, path params and headers into the tagged struct dst and validates it
+	Bind(dst interface{}) error
+
+	// JSON sets v encoded as JSON as the response body with status
+	JSON(status int, v interface{}) error
+
+	// Problem sets err as an RFC 7807 application/problem+json response
+	Problem(err error)
+
+	// Negotiate sets v as the response body with status in the type chosen by Accept: JSON, msgpack or protobuf
+	Negotiate(status int, v interface{}) error
 }
 
 // RouteFunc defines the function type for a route
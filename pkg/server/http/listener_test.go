@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestHttp_Listeners(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "admin.sock")
+	s := New("listeners")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{},
+		"listeners": []interface{}{
+			map[string]interface{}{"name": "public", "address": "127.0.0.1:0"},
+			map[string]interface{}{"name": "admin", "network": "unix", "address": sock, "mode": "0600"},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hello := func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte("hello"))
+	}
+
+	_ = s.PushRoute("GET", "/hello", hello, nil)
+	_ = s.Group("/admin").Listeners("admin").PushRoute("GET", "/stats", hello, nil)
+	_ = s.PushRouteOn([]string{"public"}, "GET", "/public", hello, nil)
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Stop()
+
+	public := &http.Client{}
+	admin := &http.Client{Transport: &http.Transport{
+		DialContext: func(c context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(c, "unix", sock)
+		},
+	}}
+
+	publicURL := "http://" + s.listeners[0].Addr().String()
+
+	tests := []struct {
+		client *http.Client
+		url    string
+		status int
+	}{
+		{public, publicURL + "/hello", 200},
+		{admin, "http://admin/hello", 200},
+		{public, publicURL + "/admin/stats", 404},
+		{admin, "http://admin/admin/stats", 200},
+		{public, publicURL + "/public", 200},
+		{admin, "http://admin/public", 404},
+	}
+
+	for _, tt := range tests {
+		resp, err := tt.client.Get(tt.url)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Fatalf("%s: want %d, got %d %q", tt.url, tt.status, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// encoder writes values of a media type, aliases are other names of the type accepted by clients
+type encoder struct {
+	mediaType string
+	aliases   []string
+	supports  func(v interface{}) bool
+	encode    func(v interface{}) ([]byte, error)
+}
+
+// encoders in server preference, the first one is used without an Accept header
+var encoders = []encoder{
+	{
+		mediaType: "application/json",
+		supports:  func(interface{}) bool { return true },
+		encode:    json.Marshal,
+	},
+	{
+		mediaType: "application/msgpack",
+		aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		supports:  func(interface{}) bool { return true },
+		encode: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+
+			enc := msgpack.NewEncoder(&buf)
+			// the same field names as JSON
+			enc.SetCustomStructTag("json")
+
+			err := enc.Encode(v)
+
+			return buf.Bytes(), err
+		},
+	},
+	{
+		mediaType: "application/x-protobuf",
+		aliases:   []string{"application/protobuf", "application/vnd.google.protobuf"},
+		supports: func(v interface{}) bool {
+			_, ok := v.(proto.Message)
+			return ok
+		},
+		encode: func(v interface{}) ([]byte, error) {
+			return proto.Marshal(v.(proto.Message))
+		},
+	},
+}
+
+// Negotiate writes v with status in the media type preferred by the Accept header: JSON, msgpack
+// or protobuf for proto.Message values. It returns ErrNotAcceptable when no type is acceptable.
+func (t *HttpCtx) Negotiate(status int, v interface{}) error {
+	t.response.Header().Add("Vary", "Accept")
+
+	enc := negotiateEncoder(t.request.GetHeader().Get("Accept"), v)
+
+	if enc == nil {
+		return fmt.Errorf("%w: %s", ErrNotAcceptable, t.request.GetHeader().Get("Accept"))
+	}
+
+	body, err := enc.encode(v)
+
+	if err != nil {
+		return err
+	}
+
+	contentType := enc.mediaType
+
+	if contentType == "application/json" {
+		contentType += "; charset=utf-8"
+	}
+
+	t.response.Header().Set("Content-Type", contentType)
+	t.response.SetStatus(status)
+	t.response.SetBody(body)
+
+	return nil
+}
+
+// negotiateEncoder returns the encoder of v with the highest q value in header, ties are broken
+// by the server preference, nil when none is acceptable
+func negotiateEncoder(header string, v interface{}) *encoder {
+	if strings.TrimSpace(header) == "" {
+		return &encoders[0]
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	ranges := make([]accepted, 0, 4)
+
+	for _, part := range strings.Split(header, ",") {
+		mediaType, q := parseQuality(part)
+
+		if mediaType != "" {
+			ranges = append(ranges, accepted{strings.ToLower(mediaType), q})
+		}
+	}
+
+	var best *encoder
+	bestQ := 0.0
+
+	for i := range encoders {
+		e := &encoders[i]
+
+		if !e.supports(v) {
+			continue
+		}
+
+		// the most specific range matching the type sets its q value
+		q, specificity := 0.0, -1
+
+		for _, r := range ranges {
+			s := -1
+
+			switch {
+			case r.mediaType == e.mediaType || contains(e.aliases, r.mediaType):
+				s = 2
+			case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(e.mediaType, strings.TrimSuffix(r.mediaType, "*")):
+				s = 1
+			case r.mediaType == "*/*":
+				s = 0
+			}
+
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+
+	return best
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
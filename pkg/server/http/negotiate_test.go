@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestHttpCtx_Negotiate(t *testing.T) {
+	s := New("negotiate")
+
+	if err := s.Init(map[string]interface{}{"middlewares": map[string]IMiddleware{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	_ = s.PushRouteE("GET", "/item", func(_ context.Context, ctx ICtx) error {
+		return ctx.Negotiate(http.StatusOK, item{Name: "box"})
+	}, nil)
+
+	_ = s.PushRouteE("GET", "/proto", func(_ context.Context, ctx ICtx) error {
+		return ctx.Negotiate(http.StatusOK, wrapperspb.String("box"))
+	}, nil)
+
+	tests := []struct {
+		path        string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"/item", "", 200, "application/json; charset=utf-8"},
+		{"/item", "*/*", 200, "application/json; charset=utf-8"},
+		{"/item", "application/x-msgpack", 200, "application/msgpack"},
+		{"/item", "application/json;q=0.5, application/msgpack", 200, "application/msgpack"},
+		{"/item", "application/*;q=0.2, application/json;q=0.1", 200, "application/msgpack"},
+		{"/item", "application/protobuf", 406, "application/problem+json"},
+		{"/proto", "application/protobuf, application/json;q=0.9", 200, "application/x-protobuf"},
+		{"/proto", "text/html, */*;q=0.1", 200, "application/json; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		if w.Code != tt.status || w.Header().Get("Content-Type") != tt.contentType || w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("%s %q: unexpected response %d %q %q", tt.path, tt.accept, w.Code, w.Header().Get("Content-Type"), w.Header().Get("Vary"))
+		}
+
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var got item
+
+		switch tt.contentType {
+		case "application/msgpack":
+			dec := msgpack.NewDecoder(w.Body)
+			dec.SetCustomStructTag("json")
+			err := dec.Decode(&got)
+
+			if err != nil || got.Name != "box" {
+				t.Fatalf("%q: unexpected msgpack body %v %v", tt.accept, got, err)
+			}
+
+		case "application/x-protobuf":
+			var msg wrapperspb.StringValue
+
+			if err := proto.Unmarshal(w.Body.Bytes(), &msg); err != nil || msg.GetValue() != "box" {
+				t.Fatalf("%q: unexpected protobuf body %v %v", tt.accept, msg.GetValue(), err)
+			}
+
+		default:
+			if tt.path == "/item" && (json.Unmarshal(w.Body.Bytes(), &got) != nil || got.Name != "box") {
+				t.Fatalf("%q: unexpected json body %q", tt.accept, w.Body.String())
+			}
+		}
+	}
+}
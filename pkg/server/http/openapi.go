@@ -0,0 +1,610 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RouteMeta documents a route in the OpenAPI document of the server, path params are taken from the route
+type RouteMeta struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+
+	// Request is a value of the type the handler binds: fields tagged path, query and header
+	// are parameters, form fields an urlencoded body and the other fields a JSON body
+	Request interface{}
+
+	// Response is a value of the type of the JSON body of a successful response
+	Response interface{}
+
+	// Responses are the other responses by status, a nil value has no body
+	Responses map[int]interface{}
+
+	Deprecated bool
+	Hidden     bool // the route is left out of the document
+}
+
+type OpenAPIConfig struct {
+	Path        string `yaml:"path"`    // serves the document, e.g. /openapi.json, empty - not served
+	UIPath      string `yaml:"ui_path"` // serves a Swagger UI page for the document, empty - not served
+	Title       string `yaml:"title"`   // default the server name
+	Version     string `yaml:"version"` // default 1.0.0
+	Description string `yaml:"description"`
+}
+
+// swaggerUI loads Swagger UI from a CDN, the page has no assets of its own
+var swaggerUI = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+	window.ui = SwaggerUIBundle({url: {{.URL}}, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
+
+var openAPIMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+func (t *Http) initOpenAPI() error {
+	if t.config.OpenAPI.Title == "" {
+		t.config.OpenAPI.Title = t.name
+	}
+
+	if t.config.OpenAPI.Version == "" {
+		t.config.OpenAPI.Version = "1.0.0"
+	}
+
+	if t.config.OpenAPI.UIPath != "" && t.config.OpenAPI.Path == "" {
+		t.config.OpenAPI.Path = "/openapi.json"
+	}
+
+	hidden := RouteMeta{Hidden: true}
+
+	if t.config.OpenAPI.Path != "" {
+		err := t.router.PushRoute(http.MethodGet, t.config.OpenAPI.Path, func(_ context.Context, ctx ICtx) {
+			doc, err := t.OpenAPI()
+
+			if err != nil {
+				ctx.Problem(err)
+				return
+			}
+
+			ctx.GetResponse().Header().Set("Content-Type", "application/json; charset=utf-8")
+			ctx.GetResponse().SetBody(doc)
+		}, nil, hidden)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.config.OpenAPI.UIPath != "" {
+		var page strings.Builder
+
+		err := swaggerUI.Execute(&page, map[string]string{"Title": t.config.OpenAPI.Title, "URL": t.config.OpenAPI.Path})
+		if err != nil {
+			return err
+		}
+
+		body := []byte(page.String())
+
+		err = t.router.PushRoute(http.MethodGet, t.config.OpenAPI.UIPath, func(_ context.Context, ctx ICtx) {
+			ctx.GetResponse().Header().Set("Content-Type", "text/html; charset=utf-8")
+			ctx.GetResponse().SetBody(body)
+		}, nil, hidden)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the registered routes as JSON.
+// Routes behind a JWTMiddleware, in base_middleware or their own chain, require its bearer security scheme.
+func (t *Http) OpenAPI() ([]byte, error) {
+	info := map[string]interface{}{
+		"title":   t.config.OpenAPI.Title,
+		"version": t.config.OpenAPI.Version,
+	}
+
+	if t.config.OpenAPI.Description != "" {
+		info["description"] = t.config.OpenAPI.Description
+	}
+
+	g := &schemaGen{
+		schemas: map[string]interface{}{},
+		names:   map[reflect.Type]string{},
+	}
+
+	paths := map[string]interface{}{}
+	security := map[string]interface{}{}
+
+	for _, r := range t.router.routes {
+		if r.meta.Hidden || !openAPIMethods[r.method] || r.pattern == "*" {
+			continue
+		}
+
+		p, params := openAPIPath(r.pattern)
+		op := g.operation(r, params)
+
+		requirements := make([]interface{}, 0, 1)
+
+		for _, name := range append(append([]string{}, t.config.BaseMiddleware...), r.middlewares...) {
+			if _, ok := t.router.middleware[name].(*JWTMiddleware); ok {
+				security[name] = map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				}
+
+				requirements = append(requirements, map[string]interface{}{name: []string{}})
+			}
+		}
+
+		if len(requirements) > 0 {
+			op["security"] = requirements
+		}
+
+		item, ok := paths[p].(map[string]interface{})
+
+		if !ok {
+			item = map[string]interface{}{}
+			paths[p] = item
+		}
+
+		item[strings.ToLower(r.method)] = op
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+	}
+
+	components := map[string]interface{}{}
+
+	if len(g.schemas) > 0 {
+		components["schemas"] = g.schemas
+	}
+
+	if len(security) > 0 {
+		components["securitySchemes"] = security
+	}
+
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+
+	return json.Marshal(doc)
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path and the schemas of its params in order
+func openAPIPath(pattern string) (string, []openAPIParam) {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	params := make([]openAPIParam, 0, 2)
+
+	for i, s := range segments {
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			continue
+		}
+
+		name, constraint, _ := strings.Cut(s[1:len(s)-1], ":")
+		name = strings.TrimSuffix(name, "...")
+		segments[i] = "{" + name + "}"
+
+		params = append(params, openAPIParam{name: name, schema: paramSchema(constraint)})
+	}
+
+	return "/" + strings.Join(segments, "/"), params
+}
+
+type openAPIParam struct {
+	name   string
+	schema map[string]interface{}
+}
+
+func paramSchema(constraint string) map[string]interface{} {
+	switch constraint {
+	case "":
+		return map[string]interface{}{"type": "string"}
+	case "int":
+		return map[string]interface{}{"type": "integer"}
+	case "uint":
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+
+	return map[string]interface{}{"type": "string", "pattern": "^(?:" + constraintExpr(constraint) + ")$"}
+}
+
+// schemaGen builds JSON schemas of Go types, named structs go to components/schemas
+type schemaGen struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func (t *schemaGen) operation(r routeInfo, pathParams []openAPIParam) map[string]interface{} {
+	op := map[string]interface{}{}
+
+	if r.meta.Summary != "" {
+		op["summary"] = r.meta.Summary
+	}
+
+	if r.meta.Description != "" {
+		op["description"] = r.meta.Description
+	}
+
+	if len(r.meta.Tags) > 0 {
+		op["tags"] = r.meta.Tags
+	}
+
+	if r.meta.OperationID != "" {
+		op["operationId"] = r.meta.OperationID
+	}
+
+	if r.meta.Deprecated {
+		op["deprecated"] = true
+	}
+
+	params := make([]interface{}, 0, len(pathParams))
+
+	for _, p := range pathParams {
+		params = append(params, map[string]interface{}{
+			"name":     p.name,
+			"in":       "path",
+			"required": true,
+			"schema":   p.schema,
+		})
+	}
+
+	if r.meta.Request != nil {
+		params = append(params, t.request(op, reflect.TypeOf(r.meta.Request))...)
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	responses := map[string]interface{}{}
+
+	if r.meta.Response != nil {
+		responses["200"] = t.response(http.StatusOK, r.meta.Response)
+	}
+
+	for status, v := range r.meta.Responses {
+		responses[strconv.Itoa(status)] = t.response(status, v)
+	}
+
+	if len(responses) == 0 {
+		responses["default"] = map[string]interface{}{"description": "Response"}
+	}
+
+	op["responses"] = responses
+
+	return op
+}
+
+// request adds the body of the request type to op and returns its query and header parameters
+func (t *schemaGen) request(op map[string]interface{}, rt reflect.Type) []interface{} {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt.Kind() != reflect.Struct {
+		op["requestBody"] = jsonContent(t.schema(rt), true)
+		return nil
+	}
+
+	params := make([]interface{}, 0)
+	form := make([]reflect.StructField, 0)
+	body := make([]reflect.StructField, 0)
+
+	for _, sf := range fields(rt) {
+		in, name := "", ""
+
+		for _, tag := range []string{"query", "header"} {
+			if v := tagName(sf, tag); v != "" {
+				in, name = tag, v
+			}
+		}
+
+		switch {
+		case in != "":
+			param := map[string]interface{}{
+				"name":   name,
+				"in":     in,
+				"schema": t.field(sf),
+			}
+
+			if hasRule(sf, "required") {
+				param["required"] = true
+			}
+
+			params = append(params, param)
+
+		case tagName(sf, "path") != "":
+			// path params are described by the route pattern
+
+		case tagName(sf, "form") != "":
+			form = append(form, sf)
+
+		default:
+			body = append(body, sf)
+		}
+	}
+
+	switch {
+	case len(form) > 0:
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/x-www-form-urlencoded": map[string]interface{}{"schema": t.object(form, "form")},
+			},
+		}
+
+	case len(body) > 0 && len(body) == len(fields(rt)):
+		op["requestBody"] = jsonContent(t.schema(rt), true)
+
+	case len(body) > 0:
+		op["requestBody"] = jsonContent(t.object(body, "json"), true)
+	}
+
+	return params
+}
+
+func (t *schemaGen) response(status int, v interface{}) map[string]interface{} {
+	description := http.StatusText(status)
+
+	if description == "" {
+		description = "Response"
+	}
+
+	if v == nil {
+		return map[string]interface{}{"description": description}
+	}
+
+	res := jsonContent(t.schema(reflect.TypeOf(v)), false)
+	res["description"] = description
+
+	switch v.(type) {
+	case Problem, *Problem:
+		res["content"] = map[string]interface{}{
+			"application/problem+json": res["content"].(map[string]interface{})["application/json"],
+		}
+	}
+
+	return res
+}
+
+func jsonContent(schema map[string]interface{}, required bool) map[string]interface{} {
+	res := map[string]interface{}{
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+
+	if required {
+		res["required"] = true
+	}
+
+	return res
+}
+
+var schemaNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// schema returns the JSON schema of rt, a reference for named structs
+func (t *schemaGen) schema(rt reflect.Type) map[string]interface{} {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	switch {
+	case rt == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rt.Implements(textUnmarshalerType) || reflect.PointerTo(rt).Implements(textUnmarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+
+		return map[string]interface{}{"type": "array", "items": t.schema(rt.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": t.schema(rt.Elem())}
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return t.object(fields(rt), "json")
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.component(rt)}
+	}
+
+	return map[string]interface{}{}
+}
+
+// component registers the schema of a named struct once and returns its name
+func (t *schemaGen) component(rt reflect.Type) string {
+	if name, ok := t.names[rt]; ok {
+		return name
+	}
+
+	name := schemaNameReplacer.ReplaceAllString(rt.Name(), "_")
+
+	if _, ok := t.schemas[name]; ok {
+		// the same name in another package
+		name = schemaNameReplacer.ReplaceAllString(path.Base(rt.PkgPath())+"."+rt.Name(), "_")
+	}
+
+	// registered before the fields for recursive types
+	t.names[rt] = name
+	t.schemas[name] = map[string]interface{}{}
+	t.schemas[name] = t.object(fields(rt), "json")
+
+	return name
+}
+
+// object returns the object schema of fields named by tag, required by the validate rules
+func (t *schemaGen) object(fields []reflect.StructField, tag string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := make([]string, 0)
+
+	for _, sf := range fields {
+		name := tagName(sf, tag)
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		properties[name] = t.field(sf)
+
+		if hasRule(sf, "required") {
+			required = append(required, name)
+		}
+	}
+
+	res := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		res["required"] = required
+	}
+
+	return res
+}
+
+// field returns the schema of a struct field with the constraints of its validate rules
+func (t *schemaGen) field(sf reflect.StructField) map[string]interface{} {
+	schema := t.schema(sf.Type)
+
+	rules := sf.Tag.Get("validate")
+
+	if rules == "" || rules == "-" || schema["$ref"] != nil {
+		return schema
+	}
+
+	ft := sf.Type
+
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+
+	var minKey, maxKey string
+
+	switch ft.Kind() {
+	case reflect.String:
+		minKey, maxKey = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		minKey, maxKey = "minItems", "maxItems"
+	case reflect.Map:
+		minKey, maxKey = "minProperties", "maxProperties"
+	default:
+		minKey, maxKey = "minimum", "maximum"
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, numErr := strconv.ParseFloat(arg, 64)
+
+		switch name {
+		case "min":
+			if numErr == nil {
+				schema[minKey] = n
+			}
+		case "max":
+			if numErr == nil {
+				schema[maxKey] = n
+			}
+		case "len":
+			if numErr == nil {
+				schema[minKey], schema[maxKey] = n, n
+			}
+		case "oneof":
+			schema["enum"] = strings.Fields(arg)
+		case "email":
+			schema["format"] = "email"
+		}
+	}
+
+	return schema
+}
+
+// fields returns the exported fields of a struct, embedded structs without a name are flattened
+func fields(rt reflect.Type) []reflect.StructField {
+	res := make([]reflect.StructField, 0, rt.NumField())
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			ft := sf.Type
+
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				res = append(res, fields(ft)...)
+				continue
+			}
+		}
+
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+
+		res = append(res, sf)
+	}
+
+	return res
+}
+
+func hasRule(sf reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(sf.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiUser struct {
+	ID      int64     `json:"id"`
+	Login   string    `json:"login" validate:"required,min=3"`
+	Role    string    `json:"role,omitempty" validate:"oneof=admin user"`
+	Created time.Time `json:"created"`
+	Friends []apiUser `json:"friends,omitempty"`
+}
+
+type apiCreateUser struct {
+	Org    string `path:"org"`
+	DryRun bool   `query:"dry_run"`
+	Trace  string `header:"X-Trace" validate:"required"`
+	Login  string `json:"login" validate:"required"`
+}
+
+func TestHttp_OpenAPI(t *testing.T) {
+	s := New("api")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{
+			"jwt": &JWTMiddleware{name: "jwt"},
+		},
+		"openapi": map[string]interface{}{
+			"ui_path": "/docs",
+			"title":   "Users",
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := func(_ context.Context, _ ICtx) {}
+
+	_ = s.PushRoute("GET", "/users/{id:int}", h, nil, RouteMeta{
+		Summary:   "Get a user",
+		Tags:      []string{"users"},
+		Response:  apiUser{},
+		Responses: map[int]interface{}{http.StatusNotFound: Problem{}},
+	})
+
+	_ = s.Group("/orgs/{org}", "jwt").PushRoute("POST", "/users", h, nil, RouteMeta{
+		OperationID: "createUser",
+		Request:     apiCreateUser{},
+		Response:    &apiUser{},
+	})
+
+	_ = s.PushRoute("GET", "/internal", h, nil, RouteMeta{Hidden: true})
+	_ = s.PushRoute("GET", "/plain", h, nil)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		OpenAPI string                                       `json:"openapi"`
+		Info    map[string]string                            `json:"info"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+
+		Components struct {
+			Schemas         map[string]map[string]interface{} `json:"schemas"`
+			SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+		} `json:"components"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info["title"] != "Users" || doc.Info["version"] != "1.0.0" {
+		t.Fatalf("unexpected header %q %v", doc.OpenAPI, doc.Info)
+	}
+
+	if _, ok := doc.Paths["/internal"]; ok {
+		t.Fatal("hidden route documented")
+	}
+
+	if _, ok := doc.Paths["/openapi.json"]; ok {
+		t.Fatal("document route documented")
+	}
+
+	if _, ok := doc.Paths["/plain"]["get"]["responses"]; !ok {
+		t.Fatal("route without meta must be documented")
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+
+	if get["summary"] != "Get a user" || get["security"] != nil {
+		t.Fatalf("unexpected get operation %v", get)
+	}
+
+	if p := get["parameters"].([]interface{})[0].(map[string]interface{}); p["name"] != "id" || p["in"] != "path" || p["schema"].(map[string]interface{})["type"] != "integer" {
+		t.Fatalf("unexpected path param %v", p)
+	}
+
+	if _, ok := get["responses"].(map[string]interface{})["404"].(map[string]interface{})["content"].(map[string]interface{})["application/problem+json"]; !ok {
+		t.Fatalf("missing 404 response %v", get["responses"])
+	}
+
+	post := doc.Paths["/orgs/{org}/users"]["post"]
+
+	if post["operationId"] != "createUser" || len(post["security"].([]interface{})) != 1 {
+		t.Fatalf("unexpected post operation %v", post)
+	}
+
+	params := post["parameters"].([]interface{})
+
+	if len(params) != 3 || params[1].(map[string]interface{})["name"] != "dry_run" || params[2].(map[string]interface{})["required"] != true {
+		t.Fatalf("unexpected params %v", params)
+	}
+
+	body, _ := json.Marshal(post["requestBody"])
+
+	if !strings.Contains(string(body), `"properties":{"login":{"type":"string"}},"required":["login"]`) {
+		t.Fatalf("unexpected request body %s", body)
+	}
+
+	user := doc.Components.Schemas["apiUser"]
+
+	if user == nil || user["required"].([]interface{})[0] != "login" {
+		t.Fatalf("unexpected user schema %v", doc.Components.Schemas)
+	}
+
+	props, _ := json.Marshal(user["properties"])
+
+	for _, want := range []string{
+		`"created":{"format":"date-time","type":"string"}`,
+		`"friends":{"items":{"$ref":"#/components/schemas/apiUser"},"type":"array"}`,
+		`"login":{"minLength":3,"type":"string"}`,
+		`"role":{"enum":["admin","user"],"type":"string"}`,
+	} {
+		if !strings.Contains(string(props), want) {
+			t.Fatalf("missing %s in %s", want, props)
+		}
+	}
+
+	if doc.Components.SecuritySchemes["jwt"]["scheme"] != "bearer" {
+		t.Fatalf("unexpected security schemes %v", doc.Components.SecuritySchemes)
+	}
+
+	resp, err = http.Get(srv.URL + "/docs")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/html; charset=utf-8" || !strings.Contains(string(page), `url: "/openapi.json"`) {
+		t.Fatalf("unexpected ui page %q", page)
+	}
+}
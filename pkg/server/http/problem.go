@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Errors mapped to status codes by Problem and RouteFuncE handlers, wrap them to add details
+var (
+	ErrBadRequest       = errors.New("bad request")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrNotFound         = errors.New("not found")
+	ErrConflict         = errors.New("conflict")
+	ErrTooManyRequests  = errors.New("too many requests")
+	ErrUnavailable      = errors.New("service unavailable")
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+	ErrNotAcceptable    = errors.New("not acceptable")
+)
+
+var errorStatus = []struct {
+	err    error
+	status int
+}{
+	{ErrBadRequest, http.StatusBadRequest},
+	{ErrUnauthorized, http.StatusUnauthorized},
+	{ErrForbidden, http.StatusForbidden},
+	{ErrNotFound, http.StatusNotFound},
+	{ErrConflict, http.StatusConflict},
+	{ErrTooManyRequests, http.StatusTooManyRequests},
+	{ErrUnavailable, http.StatusServiceUnavailable},
+	{ErrUnsupportedMedia, http.StatusUnsupportedMediaType},
+	{ErrNotAcceptable, http.StatusNotAcceptable},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout},
+}
+
+// RouteFuncE is a route returning an error, the error is written as a problem response
+type RouteFuncE func(c context.Context, ctx ICtx) error
+
+// IStatusError is implemented by errors carrying their own response status
+type IStatusError interface {
+	error
+	HTTPStatus() int
+}
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type       string                 `json:"type,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"` // additional members, e.g. errors
+}
+
+// NewProblem returns a problem with the standard title of status
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (t *Problem) Error() string {
+	if t.Detail != "" {
+		return t.Title + ": " + t.Detail
+	}
+
+	return t.Title
+}
+
+func (t *Problem) HTTPStatus() int {
+	return t.Status
+}
+
+func (t *Problem) MarshalJSON() ([]byte, error) {
+	res := make(map[string]interface{}, len(t.Extensions)+5)
+
+	for k, v := range t.Extensions {
+		res[k] = v
+	}
+
+	type problem Problem
+	b, err := json.Marshal((*problem)(t))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(res)
+}
+
+// ProblemFrom converts err to a problem. Status comes from IStatusError, the package errors
+// or defaults to 500, the detail of unknown errors is not exposed.
+func ProblemFrom(err error) *Problem {
+	var p *Problem
+
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var ve *ValidationError
+
+	if errors.As(err, &ve) {
+		res := NewProblem(http.StatusUnprocessableEntity, ve.Error())
+		res.Extensions = map[string]interface{}{"errors": ve.Fields}
+
+		return res
+	}
+
+	var se IStatusError
+
+	if errors.As(err, &se) {
+		return NewProblem(se.HTTPStatus(), err.Error())
+	}
+
+	for _, v := range errorStatus {
+		if errors.Is(err, v.err) {
+			return NewProblem(v.status, err.Error())
+		}
+	}
+
+	return NewProblem(http.StatusInternalServerError, "")
+}
+
+// JSON writes v as a JSON response with status
+func (t *HttpCtx) JSON(status int, v interface{}) error {
+	body, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	t.response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	t.response.SetStatus(status)
+	t.response.SetBody(body)
+
+	return nil
+}
+
+// Problem writes err as an application/problem+json response
+func (t *HttpCtx) Problem(err error) {
+	p := ProblemFrom(err)
+
+	if p.Instance == "" {
+		if r, ok := t.request.(*HttpRequest); ok && r.request != nil {
+			// a copy, the problem may be shared
+			cp := *p
+			cp.Instance = r.request.URL.Path
+			p = &cp
+		}
+	}
+
+	body, mErr := json.Marshal(p)
+
+	if mErr != nil {
+		body = []byte(`{"title":` + strconv.Quote(http.StatusText(p.Status)) + `,"status":` + strconv.Itoa(p.Status) + `}`)
+	}
+
+	t.response.Header().Set("Content-Type", "application/problem+json")
+	t.response.SetStatus(p.Status)
+	t.response.SetBody(body)
+}
+
+// routeE adapts a RouteFuncE, a returned error replaces the response with a problem
+func routeE(handler RouteFuncE) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		if err := handler(c, ctx); err != nil && !ctx.IsStreaming() {
+			ctx.Problem(err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gitlab.com/devpro_studio/go_utils/decode"
+)
+
+// RateLimitPolicy is a limit of the requests it matches, policies are tried in order and the
+// first one matching applies. Requests matching no policy get the limit of the middleware.
+type RateLimitPolicy struct {
+	Name      string         `yaml:"name"`
+	Match     RateLimitMatch `yaml:"match"`
+	Key       []string       `yaml:"key"`       // ip, global, method, route, header:<name> or claim:<name>, default ip
+	Requests  int            `yaml:"requests"`  // per interval, requests in flight for concurrency
+	Interval  time.Duration  `yaml:"interval"`  // default the middleware interval
+	Burst     int            `yaml:"burst"`     // token bucket capacity, default requests
+	Algorithm string         `yaml:"algorithm"` // token_bucket|fixed_window|sliding_window|concurrency, default the middleware one
+	Unlimited bool           `yaml:"unlimited"` // the matched requests are not limited
+	Lease     time.Duration  `yaml:"lease"`     // concurrency slot lease of the redis store, default the middleware one
+
+	limit   RateLimit
+	claims  map[string]string
+	headers map[string]string
+}
+
+// RateLimitMatch matches the requests matching all its set fields, any of the values of a field
+type RateLimitMatch struct {
+	Routes  []string               `yaml:"routes"` // route templates as registered, e.g. /users/{id:int}
+	Methods []string               `yaml:"methods"`
+	Claims  map[string]interface{} `yaml:"claims"`  // JWT claims by value, * for any, array claims match by element
+	Headers map[string]interface{} `yaml:"headers"` // headers by value, * for any
+}
+
+// popRateLimitPolicies returns the config without the policies key and the decoded policies
+func popRateLimitPolicies(cfg map[string]interface{}) (map[string]interface{}, []RateLimitPolicy, error) {
+	v, ok := cfg["policies"]
+
+	if !ok {
+		return cfg, nil, nil
+	}
+
+	rest := make(map[string]interface{}, len(cfg))
+
+	for k, item := range cfg {
+		if k != "policies" {
+			rest[k] = item
+		}
+	}
+
+	var items []interface{}
+
+	switch v := v.(type) {
+	case nil:
+		return rest, nil, nil
+
+	case []RateLimitPolicy:
+		return rest, v, nil
+
+	case []map[string]interface{}:
+		for _, item := range v {
+			items = append(items, item)
+		}
+
+	case []interface{}:
+		items = v
+
+	default:
+		return nil, nil, fmt.Errorf("policies: unexpected type %T", v)
+	}
+
+	res := make([]RateLimitPolicy, len(items))
+
+	for i, item := range items {
+		if err := decode.Decode(item, &res[i], "yaml", decode.DecoderStrongFoundDst); err != nil {
+			return nil, nil, fmt.Errorf("policies[%d]: %w", i, err)
+		}
+	}
+
+	return rest, res, nil
+}
+
+func (t *RateLimitPolicy) init(cfg RateLimitMiddlewareConfig) error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(t.Match.Claims) > 0 && cfg.ClaimsCtxKey == "" {
+		return errors.New("claims need claims_ctx_key")
+	}
+
+	t.claims = make(map[string]string, len(t.Match.Claims))
+	for k, v := range t.Match.Claims {
+		t.claims[k] = fmt.Sprint(v)
+	}
+
+	t.headers = make(map[string]string, len(t.Match.Headers))
+	for k, v := range t.Match.Headers {
+		t.headers[k] = fmt.Sprint(v)
+	}
+
+	if t.Unlimited {
+		return nil
+	}
+
+	if t.Requests <= 0 {
+		return errors.New("requests must be positive")
+	}
+	if t.Interval <= 0 {
+		t.Interval = cfg.Interval
+	}
+	if t.Burst <= 0 {
+		t.Burst = t.Requests
+	}
+	if t.Algorithm == "" {
+		t.Algorithm = cfg.Algorithm
+	}
+	if t.Lease <= 0 {
+		t.Lease = cfg.Lease
+	}
+	if len(t.Key) == 0 {
+		t.Key = []string{"ip"}
+	}
+
+	if !validAlgorithm(t.Algorithm) {
+		return fmt.Errorf("unknown algorithm %s", t.Algorithm)
+	}
+
+	for _, k := range t.Key {
+		switch {
+		case k == "ip", k == "global", k == "method", k == "route":
+		case strings.HasPrefix(k, "header:") && len(k) > len("header:"):
+		case strings.HasPrefix(k, "claim:") && len(k) > len("claim:"):
+			if cfg.ClaimsCtxKey == "" {
+				return errors.New("claim keys need claims_ctx_key")
+			}
+		default:
+			return fmt.Errorf("unknown key %s", k)
+		}
+	}
+
+	t.limit = RateLimit{
+		Algorithm: t.Algorithm,
+		Requests:  t.Requests,
+		Interval:  t.Interval,
+		Burst:     t.Burst,
+		Lease:     t.Lease,
+	}
+
+	return nil
+}
+
+func (t *RateLimitPolicy) matches(ctx ICtx, claims jwt.MapClaims) bool {
+	if len(t.Match.Routes) > 0 && !contains(t.Match.Routes, ctx.GetRoute()) {
+		return false
+	}
+
+	if len(t.Match.Methods) > 0 {
+		found := false
+
+		for _, m := range t.Match.Methods {
+			if strings.EqualFold(m, ctx.GetRequest().GetMethod()) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for name, want := range t.headers {
+		v := ctx.GetRequest().GetHeader().Get(name)
+
+		if v == "" || (want != "*" && v != want) {
+			return false
+		}
+	}
+
+	for name, want := range t.claims {
+		if !claimMatches(claims[name], want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// key returns the store key of the request, prefixed by the policy name. Each part is length-prefixed,
+// values holding the separator, e.g. headers a=x|y b=z and a=x b=y|z, must not share a key.
+func (t *RateLimitPolicy) key(ctx ICtx, claims jwt.MapClaims) string {
+	var b strings.Builder
+
+	b.WriteString("policy:")
+	b.WriteString(t.Name)
+
+	for _, k := range t.Key {
+		var part string
+
+		switch {
+		case k == "ip":
+			part = ctx.GetRequest().GetRemoteIP()
+		case k == "global":
+			part = "global"
+		case k == "method":
+			part = ctx.GetRequest().GetMethod()
+		case k == "route":
+			part = routeOf(ctx)
+		case strings.HasPrefix(k, "header:"):
+			part = ctx.GetRequest().GetHeader().Get(strings.TrimPrefix(k, "header:"))
+		case strings.HasPrefix(k, "claim:"):
+			if v, ok := claims[strings.TrimPrefix(k, "claim:")]; ok && v != nil {
+				part = fmt.Sprint(v)
+			}
+		}
+
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(len(part)))
+		b.WriteByte(':')
+		b.WriteString(part)
+	}
+
+	return b.String()
+}
+
+func claimMatches(v interface{}, want string) bool {
+	if v == nil {
+		return false
+	}
+
+	if want == "*" {
+		return true
+	}
+
+	if list, ok := v.([]interface{}); ok {
+		for _, e := range list {
+			if fmt.Sprint(e) == want {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return fmt.Sprint(v) == want
+}
+
+// routeOf returns the route template of the request, the path without one
+func routeOf(ctx ICtx) string {
+	if r := ctx.GetRoute(); r != "" {
+		return r
+	}
+
+	path, _, _ := strings.Cut(ctx.GetRequest().GetURI(), "?")
+
+	return path
+}
+
+// claimsFrom returns the claims put in the context by JWTMiddleware under key
+func claimsFrom(c context.Context, key string) jwt.MapClaims {
+	if key == "" {
+		return nil
+	}
+
+	switch v := c.Value(key).(type) {
+	case jwt.MapClaims:
+		return v
+	case map[string]interface{}:
+		return v
+	}
+
+	return nil
+}
+
+func validAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case RateLimitTokenBucket, RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitConcurrency:
+		return true
+	}
+
+	return false
+}
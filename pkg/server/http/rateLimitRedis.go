@@ -0,0 +1,271 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitScripter is implemented by the redis cache package
+type rateLimitScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// redisRateLimitStore keeps the keys in Redis, each request is a single atomic script using the
+// Redis clock, so the limits are shared by all the replicas and survive deploys
+type redisRateLimitStore struct {
+	cache   rateLimitScripter
+	prefix  string
+	timeout time.Duration
+}
+
+// tokenBucketScript: KEYS[1] hash of the tokens and the last refill, ARGV rate per ms and burst.
+// Returns allowed, remaining, retry after ms and reset ms.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+local retry = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) / rate)}
+`
+
+// fixedWindowScript: KEYS[1] counter of the window, ARGV limit and window ms.
+// Returns allowed, remaining, retry after ms and reset ms.
+const fixedWindowScript = `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], window)
+end
+
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+end
+
+if count <= limit then
+	return {1, limit - count, 0, ttl}
+end
+
+return {0, 0, ttl, ttl}
+`
+
+// slidingWindowScript: KEYS[1] sorted set of the request times, ARGV limit and window ms.
+// Returns allowed, remaining, retry after ms and reset ms.
+const slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, t[1] .. '.' .. t[2] .. '.' .. count)
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0, window}
+end
+
+if count == 0 then
+	-- limit <= 0, no request to wait for
+	return {0, 0, window, window}
+end
+
+local first = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+
+return {0, 0, tonumber(first[2]) + window - now, tonumber(last[2]) + window - now}
+`
+
+// concurrencyScript: KEYS[1] sorted set of the slots by expiry, ARGV limit, lease ms and slot id.
+// Held slots are renewed, slots of crashed replicas expire after the lease. Returns allowed, remaining, retry after ms and reset ms.
+const concurrencyScript = `
+local limit = tonumber(ARGV[1])
+local lease = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+local count = redis.call('ZCARD', KEYS[1])
+
+if count >= limit then
+	return {0, 0, 0, 0}
+end
+
+redis.call('ZADD', KEYS[1], now + lease, ARGV[3])
+redis.call('PEXPIRE', KEYS[1], lease)
+
+return {1, limit - count - 1, 0, 0}
+`
+
+// renewScript: KEYS[1] sorted set of the slots, ARGV lease ms and slot id. Extends a slot still held.
+const renewScript = `
+local lease = tonumber(ARGV[1])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return 0
+end
+
+redis.call('ZADD', KEYS[1], now + lease, ARGV[2])
+redis.call('PEXPIRE', KEYS[1], lease)
+
+return 1
+`
+
+const releaseScript = `return redis.call('ZREM', KEYS[1], ARGV[1])`
+
+func newRedisRateLimitStore(cache rateLimitScripter, prefix string, timeout time.Duration) *redisRateLimitStore {
+	return &redisRateLimitStore{
+		cache:   cache,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+func (t *redisRateLimitStore) Stop() error {
+	// the cache is stopped by the engine
+	return nil
+}
+
+func (t *redisRateLimitStore) Take(c context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	c, cancel := context.WithTimeout(c, t.timeout)
+	defer cancel()
+
+	window := max(limit.Interval.Milliseconds(), 1)
+
+	keys := []string{t.prefix + key}
+	slot := ""
+
+	var reply any
+	var err error
+
+	switch limit.Algorithm {
+	case RateLimitFixedWindow:
+		reply, err = t.cache.Eval(c, fixedWindowScript, keys, limit.Requests, window)
+	case RateLimitSlidingWindow:
+		reply, err = t.cache.Eval(c, slidingWindowScript, keys, limit.Requests, window)
+	case RateLimitConcurrency:
+		if limit.Lease <= 0 {
+			limit.Lease = defaultRateLimitLease
+		}
+
+		slot = newRequestID()
+		reply, err = t.cache.Eval(c, concurrencyScript, keys, limit.Requests, max(limit.Lease.Milliseconds(), 1), slot)
+	default:
+		rate := strconv.FormatFloat(float64(limit.Requests)/float64(window), 'g', -1, 64)
+		reply, err = t.cache.Eval(c, tokenBucketScript, keys, rate, limit.Burst)
+	}
+
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return RateLimitResult{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+	}
+
+	var n [4]int64
+
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return RateLimitResult{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+		}
+	}
+
+	res := RateLimitResult{
+		Allowed:    n[0] == 1,
+		Remaining:  int(n[1]),
+		RetryAfter: time.Duration(n[2]) * time.Millisecond,
+		Reset:      time.Duration(n[3]) * time.Millisecond,
+	}
+
+	if limit.Algorithm == RateLimitConcurrency {
+		if !res.Allowed {
+			res.RetryAfter = concurrencyRetryAfter
+		} else {
+			res.Release = t.hold(c, keys, slot, limit.Lease)
+		}
+	}
+
+	return res, nil
+}
+
+// hold renews the lease of a concurrency slot until the returned func frees it, so that a slot
+// expires only with a crashed replica. On failure the slot expires with its lease.
+func (t *redisRateLimitStore) hold(c context.Context, keys []string, slot string, lease time.Duration) func() {
+	c = context.WithoutCancel(c)
+	ms := max(lease.Milliseconds(), 1)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(max(lease/3, time.Millisecond))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				rc, cancel := context.WithTimeout(c, t.timeout)
+				_, _ = t.cache.Eval(rc, renewScript, keys, ms, slot)
+				cancel()
+
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+
+			rc, cancel := context.WithTimeout(c, t.timeout)
+			defer cancel()
+
+			_, _ = t.cache.Eval(rc, releaseScript, keys, slot)
+		})
+	}
+}
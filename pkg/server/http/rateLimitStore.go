@@ -0,0 +1,279 @@
+package http
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitFixedWindow   = "fixed_window"
+	RateLimitSlidingWindow = "sliding_window"
+	RateLimitConcurrency   = "concurrency"
+)
+
+// defaultRateLimitLease is the lease of a concurrency slot in a shared store
+const defaultRateLimitLease = 30 * time.Second
+
+// concurrencyRetryAfter is sent to clients over a concurrency limit, when a slot frees is unknown
+const concurrencyRetryAfter = time.Second
+
+// RateLimitStore keeps the state of the rate limited keys. The default store is in memory and
+// per process, the redis store shares the limits between the replicas of a service.
+type RateLimitStore interface {
+	// Take consumes a request for the key, an error means the state could not be read
+	Take(c context.Context, key string, limit RateLimit) (RateLimitResult, error)
+	Stop() error
+}
+
+// RateLimit is the limit applied to a key
+type RateLimit struct {
+	Algorithm string // one of the RateLimit algorithm constants
+	Requests  int    // per Interval, or requests in flight for RateLimitConcurrency
+	Interval  time.Duration
+	Burst     int           // bucket capacity of the token bucket
+	Lease     time.Duration // of a RateLimitConcurrency slot in a shared store, renewed until released
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // until the next request is allowed when not allowed
+	Reset      time.Duration // until the limit is fully available again
+	Release    func()        // frees the concurrency slot once the request is done, nil for other algorithms
+}
+
+// memoryRateLimitStore keeps the keys in a process local map, idle keys are evicted
+type memoryRateLimitStore struct {
+	mu      sync.RWMutex
+	buckets map[string]*bucket
+
+	evictAfter    time.Duration
+	stopCh        chan struct{}
+	cleanupTicker *time.Ticker
+	wg            sync.WaitGroup
+}
+
+type bucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	lastUsed   time.Time
+	log        []time.Time // requests of the sliding window
+	start      time.Time   // of the fixed window
+	count      int         // requests of the fixed window
+	inflight   int         // requests holding a concurrency slot
+}
+
+func newMemoryRateLimitStore(cleanupInterval time.Duration, evictAfter time.Duration) *memoryRateLimitStore {
+	t := &memoryRateLimitStore{
+		buckets:       make(map[string]*bucket, 128),
+		evictAfter:    evictAfter,
+		stopCh:        make(chan struct{}),
+		cleanupTicker: time.NewTicker(cleanupInterval),
+	}
+
+	t.wg.Add(1)
+	go t.cleanupLoop()
+
+	return t
+}
+
+func (t *memoryRateLimitStore) cleanupLoop() {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-t.cleanupTicker.C:
+			t.cleanupOnce()
+		case <-t.stopCh:
+			t.cleanupTicker.Stop()
+			return
+		}
+	}
+}
+
+func (t *memoryRateLimitStore) cleanupOnce() {
+	now := time.Now()
+
+	// Copy entries under RLock
+	t.mu.RLock()
+	type pair struct {
+		key string
+		b   *bucket
+	}
+	items := make([]pair, 0, len(t.buckets))
+	for k, v := range t.buckets {
+		items = append(items, pair{key: k, b: v})
+	}
+	t.mu.RUnlock()
+
+	// Decide which to evict
+	toEvict := make([]string, 0)
+	for i := 0; i < len(items); i++ {
+		b := items[i].b
+		b.mu.Lock()
+		idle := now.Sub(b.lastUsed)
+		inflight := b.inflight
+		b.mu.Unlock()
+		if idle >= t.evictAfter && inflight == 0 {
+			toEvict = append(toEvict, items[i].key)
+		}
+	}
+
+	if len(toEvict) == 0 {
+		return
+	}
+
+	// Delete selected keys
+	t.mu.Lock()
+	for _, k := range toEvict {
+		delete(t.buckets, k)
+	}
+	t.mu.Unlock()
+}
+
+func (t *memoryRateLimitStore) Stop() error {
+	close(t.stopCh)
+	t.wg.Wait()
+	return nil
+}
+
+func (t *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+
+	// Get or create bucket
+	t.mu.RLock()
+	b := t.buckets[key]
+	t.mu.RUnlock()
+	if b == nil {
+		t.mu.Lock()
+		// Re-check after acquiring write lock
+		if b = t.buckets[key]; b == nil {
+			b = &bucket{
+				tokens:     float64(limit.Burst),
+				lastRefill: now,
+			}
+			t.buckets[key] = b
+		}
+		t.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastUsed = now
+
+	switch limit.Algorithm {
+	case RateLimitFixedWindow:
+		return b.fixedWindow(now, limit), nil
+	case RateLimitSlidingWindow:
+		return b.slidingWindow(now, limit), nil
+	case RateLimitConcurrency:
+		return b.concurrency(limit), nil
+	default:
+		return b.tokenBucket(now, limit), nil
+	}
+}
+
+func (b *bucket) tokenBucket(now time.Time, limit RateLimit) RateLimitResult {
+	rate := float64(limit.Requests) / limit.Interval.Seconds()
+	burst := float64(limit.Burst)
+
+	// Refill and consume
+	dt := now.Sub(b.lastRefill).Seconds()
+	if dt > 0 {
+		b.tokens = math.Min(burst, b.tokens+dt*rate)
+		b.lastRefill = now
+	}
+
+	if b.tokens >= 1.0 {
+		b.tokens -= 1.0
+
+		return RateLimitResult{
+			Allowed:   true,
+			Remaining: int(math.Floor(b.tokens)),
+			Reset:     seconds((burst - b.tokens) / rate),
+		}
+	}
+
+	// Not enough tokens
+	return RateLimitResult{
+		RetryAfter: seconds((1.0 - b.tokens) / rate),
+		Reset:      seconds((burst - b.tokens) / rate),
+	}
+}
+
+// fixedWindow counts the requests of a window starting with the first request after the previous one
+func (b *bucket) fixedWindow(now time.Time, limit RateLimit) RateLimitResult {
+	if now.Sub(b.start) >= limit.Interval {
+		b.start = now
+		b.count = 0
+	}
+
+	reset := b.start.Add(limit.Interval).Sub(now)
+
+	if b.count < limit.Requests {
+		b.count++
+
+		return RateLimitResult{
+			Allowed:   true,
+			Remaining: limit.Requests - b.count,
+			Reset:     reset,
+		}
+	}
+
+	return RateLimitResult{
+		RetryAfter: reset,
+		Reset:      reset,
+	}
+}
+
+func (b *bucket) slidingWindow(now time.Time, limit RateLimit) RateLimitResult {
+	// drop the requests out of the window
+	i := 0
+	for i < len(b.log) && now.Sub(b.log[i]) >= limit.Interval {
+		i++
+	}
+	b.log = b.log[i:]
+
+	if len(b.log) < limit.Requests {
+		b.log = append(b.log, now)
+
+		return RateLimitResult{
+			Allowed:   true,
+			Remaining: limit.Requests - len(b.log),
+			Reset:     limit.Interval,
+		}
+	}
+
+	retry := b.log[0].Add(limit.Interval).Sub(now)
+
+	return RateLimitResult{
+		RetryAfter: retry,
+		Reset:      b.log[len(b.log)-1].Add(limit.Interval).Sub(now),
+	}
+}
+
+func (b *bucket) concurrency(limit RateLimit) RateLimitResult {
+	if b.inflight >= limit.Requests {
+		return RateLimitResult{RetryAfter: concurrencyRetryAfter}
+	}
+
+	b.inflight++
+
+	return RateLimitResult{
+		Allowed:   true,
+		Remaining: limit.Requests - b.inflight,
+		Release: func() {
+			b.mu.Lock()
+			b.inflight--
+			b.mu.Unlock()
+		},
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"gitlab.com/devpro_studio/Paranoia/pkg/cache/redis"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Redis) {
+	mr := miniredis.RunT(t)
+
+	cache := redis.New("rate_limit")
+
+	if err := cache.Init(map[string]interface{}{"hosts": mr.Addr()}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = cache.Stop() })
+
+	return mr, cache
+}
+
+func TestRateLimitStore(t *testing.T) {
+	mr, cache := newTestRedis(t)
+
+	stores := map[string]RateLimitStore{
+		"memory": newMemoryRateLimitStore(time.Minute, time.Minute),
+		"redis":  newRedisRateLimitStore(cache, "test:", time.Second),
+	}
+
+	for name, store := range stores {
+		for _, algorithm := range []string{RateLimitTokenBucket, RateLimitFixedWindow, RateLimitSlidingWindow} {
+			limit := RateLimit{Algorithm: algorithm, Requests: 2, Interval: 200 * time.Millisecond, Burst: 2}
+			key := "key_" + algorithm
+
+			for i := 1; i >= 0; i-- {
+				res, err := store.Take(context.Background(), key, limit)
+
+				if err != nil || !res.Allowed || res.Remaining != i {
+					t.Fatalf("%s %s: want allowed with %d remaining, got %+v %v", name, algorithm, i, res, err)
+				}
+			}
+
+			res, err := store.Take(context.Background(), key, limit)
+
+			if err != nil || res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > limit.Interval {
+				t.Fatalf("%s %s: want limited, got %+v %v", name, algorithm, res, err)
+			}
+
+			time.Sleep(res.RetryAfter + 10*time.Millisecond)
+			// miniredis expires keys on fast forward only
+			mr.FastForward(res.RetryAfter + 10*time.Millisecond)
+
+			if res, err = store.Take(context.Background(), key, limit); err != nil || !res.Allowed {
+				t.Fatalf("%s %s: want allowed after %v, got %+v %v", name, algorithm, res.RetryAfter, res, err)
+			}
+		}
+
+		limit := RateLimit{Algorithm: RateLimitConcurrency, Requests: 2, Interval: time.Minute}
+		slots := make([]RateLimitResult, 0, 2)
+
+		for i := 1; i >= 0; i-- {
+			res, err := store.Take(context.Background(), "slots", limit)
+
+			if err != nil || !res.Allowed || res.Remaining != i || res.Release == nil {
+				t.Fatalf("%s concurrency: want a slot with %d remaining, got %+v %v", name, i, res, err)
+			}
+
+			slots = append(slots, res)
+		}
+
+		if res, err := store.Take(context.Background(), "slots", limit); err != nil || res.Allowed {
+			t.Fatalf("%s concurrency: want limited, got %+v %v", name, res, err)
+		}
+
+		slots[0].Release()
+
+		if res, err := store.Take(context.Background(), "slots", limit); err != nil || !res.Allowed {
+			t.Fatalf("%s concurrency: want the released slot, got %+v %v", name, res, err)
+		}
+
+		// a request outliving the interval and the lease keeps its slot
+		limit = RateLimit{Algorithm: RateLimitConcurrency, Requests: 1, Interval: 50 * time.Millisecond, Lease: 100 * time.Millisecond}
+
+		long, err := store.Take(context.Background(), "long", limit)
+
+		if err != nil || !long.Allowed {
+			t.Fatalf("%s concurrency: want a slot, got %+v %v", name, long, err)
+		}
+
+		time.Sleep(400 * time.Millisecond)
+
+		if res, err := store.Take(context.Background(), "long", limit); err != nil || res.Allowed {
+			t.Fatalf("%s concurrency: want the slot still held after %v, got %+v %v", name, limit.Lease, res, err)
+		}
+
+		long.Release()
+
+		if res, err := store.Take(context.Background(), "long", limit); err != nil || !res.Allowed {
+			t.Fatalf("%s concurrency: want the released slot, got %+v %v", name, res, err)
+		}
+
+		_ = store.Stop()
+	}
+}
+
+func TestRateLimitMiddleware_Redis(t *testing.T) {
+	mr, cache := newTestRedis(t)
+
+	// two replicas sharing the limit
+	newReplica := func(failClosed bool) *Http {
+		rl := NewRateLimitMiddleware("rate_limit").(*RateLimitMiddleware)
+		rl.SetStore(newRedisRateLimitStore(cache, "ratelimit:rate_limit:", 100*time.Millisecond))
+
+		err := rl.Init(nil, map[string]interface{}{
+			"requests":     2,
+			"interval":     "1m",
+			"key_strategy": "global",
+			"algorithm":    "sliding_window",
+			"fail_closed":  failClosed,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := New("replica")
+
+		err = s.Init(map[string]interface{}{
+			"middlewares":     map[string]IMiddleware{"rate_limit": rl},
+			"base_middleware": []string{"rate_limit"},
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = s.PushRoute("GET", "/rl", func(_ context.Context, ctx ICtx) {
+			ctx.GetResponse().SetBody([]byte("{}"))
+		}, nil)
+
+		return s
+	}
+
+	open, closed := newReplica(false), newReplica(true)
+
+	do := func(s *Http) int {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/rl", nil))
+		return w.Code
+	}
+
+	if code := do(open); code != 200 {
+		t.Fatalf("want 200, got %d", code)
+	}
+
+	if code := do(closed); code != 200 {
+		t.Fatalf("want 200, got %d", code)
+	}
+
+	if code := do(open); code != 429 {
+		t.Fatalf("limit not shared, want 429, got %d", code)
+	}
+
+	if !mr.Exists("ratelimit:rate_limit:global") {
+		t.Fatalf("redis key not found: %v", mr.Keys())
+	}
+
+	mr.Close()
+
+	if code := do(open); code != 200 {
+		t.Fatalf("fail open: want 200, got %d", code)
+	}
+
+	if code := do(closed); code != 503 {
+		t.Fatalf("fail closed: want 503, got %d", code)
+	}
+}
+
+func TestRateLimitStore_ZeroLimit(t *testing.T) {
+	_, cache := newTestRedis(t)
+	store := newRedisRateLimitStore(cache, "test:", time.Second)
+
+	limit := RateLimit{Algorithm: RateLimitSlidingWindow, Requests: 0, Interval: time.Second}
+
+	if res, err := store.Take(context.Background(), "zero", limit); err != nil || res.Allowed {
+		t.Fatalf("want limited, got %+v %v", res, err)
+	}
+}
@@ -1,36 +1,78 @@
 package http
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
 	"strings"
+
+	"gitlab.com/devpro_studio/Paranoia/paranoia/listener"
 )
 
+// paramTypes are the named constraints of route params, e.g. {id:int}, other constraints are regular expressions
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+}
+
 type dynamicItem struct {
-	name string
-	next dynamicRouter
+	name       string
+	constraint string
+	match      *regexp.Regexp
+	next       dynamicRouter
 }
 
 type dynamicRouter struct {
-	static  map[string]dynamicRouter
-	dynamic []dynamicItem
-	hande   RouteFunc
+	static   map[string]dynamicRouter
+	dynamic  []dynamicItem
+	catchAll *dynamicItem
+	hande    RouteFunc
+	pattern  string
 }
 
 type Router struct {
-	static     map[string]map[string]RouteFunc
+	static     map[string]map[string]staticRoute
 	dynamic    map[string]dynamicRouter
 	middleware map[string]IMiddleware
+	routes     []routeInfo // registration order, for the OpenAPI document
+}
+
+type staticRoute struct {
+	handler RouteFunc
+	pattern string
+}
+
+// routeInfo is a registered route as described by OpenAPI
+type routeInfo struct {
+	method      string
+	pattern     string
+	middlewares []string
+	meta        RouteMeta
 }
 
 func NewRouter(middleware map[string]IMiddleware) *Router {
 	return &Router{
-		static:     make(map[string]map[string]RouteFunc, 5),
+		static:     make(map[string]map[string]staticRoute, 5),
 		dynamic:    make(map[string]dynamicRouter, 5),
 		middleware: middleware,
 	}
 }
 
-func (t *Router) PushRoute(method string, path string, handler RouteFunc, middlewares []string) error {
+// PushRoute adds a route. Path segments may be params: {name}, {name:int} with a named type
+// (int, uint, uuid, alpha, alnum) or {name:regexp}, and the last segment may be a catch-all {name...}.
+// Routes overlapping ambiguously with a registered one are rejected. meta documents the route in OpenAPI.
+func (t *Router) PushRoute(method string, path string, handler RouteFunc, middlewares []string, meta ...RouteMeta) error {
+	return t.pushRoute(method, path, handler, middlewares, nil, meta)
+}
+
+// pushRoute adds a route, with listeners it is served only on the named listeners
+func (t *Router) pushRoute(method string, path string, handler RouteFunc, middlewares []string, listeners []string, meta []RouteMeta) error {
 	var md func(RouteFunc) RouteFunc = nil
 	var err error
 
@@ -41,6 +83,8 @@ func (t *Router) PushRoute(method string, path string, handler RouteFunc, middle
 		}
 	}
 
+	pattern := path
+
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
@@ -53,12 +97,20 @@ func (t *Router) PushRoute(method string, path string, handler RouteFunc, middle
 		h = md(handler)
 	}
 
+	if len(listeners) > 0 {
+		h = onListeners(listeners, h)
+	}
+
 	if idx == -1 {
 		if _, ok := t.static[method]; !ok {
-			t.static[method] = make(map[string]RouteFunc, 20)
+			t.static[method] = make(map[string]staticRoute, 20)
+		}
+
+		if _, ok := t.static[method][path]; ok {
+			return fmt.Errorf("route %s %s already registered", method, pattern)
 		}
 
-		t.static[method][path] = h
+		t.static[method][path] = staticRoute{handler: h, pattern: pattern}
 	} else {
 		p := strings.Split(path, "/")
 
@@ -71,40 +123,105 @@ func (t *Router) PushRoute(method string, path string, handler RouteFunc, middle
 			}
 
 			router := t.dynamic[method]
-			router.Push(p[1:], h)
+			err = router.Push(p[1:], h, pattern)
 			t.dynamic[method] = router
+
+			if err != nil {
+				return fmt.Errorf("route %s %s: %w", method, pattern, err)
+			}
 		}
 	}
 
+	info := routeInfo{
+		method:      method,
+		pattern:     pattern,
+		middlewares: middlewares,
+	}
+
+	if len(meta) > 0 {
+		info.meta = meta[0]
+	}
+
+	t.routes = append(t.routes, info)
+
 	return nil
 }
 
-func (t *dynamicRouter) Push(path []string, handler RouteFunc) {
+func (t *dynamicRouter) Push(path []string, handler RouteFunc, pattern string) error {
 	if len(path) == 0 || path[0] == "" {
+		if t.hande != nil {
+			return fmt.Errorf("conflicts with %s", t.pattern)
+		}
+
 		t.hande = handler
-		return
+		t.pattern = pattern
+		return nil
 	}
 
 	if strings.HasPrefix(path[0], "{") && strings.HasSuffix(path[0], "}") {
-		// Dynamic segment: reuse existing node with the same name if present
-		name := path[0][1 : len(path[0])-1]
+		name, constraint, _ := strings.Cut(path[0][1:len(path[0])-1], ":")
+
+		if strings.HasSuffix(name, "...") {
+			return t.pushCatchAll(strings.TrimSuffix(name, "..."), path[1:], handler, pattern)
+		}
+
+		if name == "" {
+			return errors.New("empty param name")
+		}
+
+		// Dynamic segment: reuse existing node with the same constraint, the names must match.
+		// Named constraints are compared by their expression, {id:uint} and {id:[0-9]+} are the same param.
 		for i := range t.dynamic {
-			if t.dynamic[i].name == name {
-				t.dynamic[i].next.Push(path[1:], handler)
-				return
+			if constraintExpr(t.dynamic[i].constraint) != constraintExpr(constraint) {
+				continue
+			}
+
+			if t.dynamic[i].name != name {
+				return fmt.Errorf("param {%s} conflicts with {%s} of %s", name, t.dynamic[i].name, t.dynamic[i].next.first())
 			}
+
+			return t.dynamic[i].next.Push(path[1:], handler, pattern)
 		}
+
 		// Not found, create new dynamic branch
 		r := dynamicItem{
-			name: name,
+			name:       name,
+			constraint: constraint,
 			next: dynamicRouter{
 				static:  make(map[string]dynamicRouter, 5),
 				dynamic: make([]dynamicItem, 0, 5),
 			},
 		}
-		r.next.Push(path[1:], handler)
-		t.dynamic = append(t.dynamic, r)
-		return
+
+		if constraint != "" {
+			re, err := regexp.Compile("^(?:" + constraintExpr(constraint) + ")$")
+
+			if err != nil {
+				return fmt.Errorf("param {%s}: %w", name, err)
+			}
+
+			r.match = re
+		}
+
+		if err := r.next.Push(path[1:], handler, pattern); err != nil {
+			return err
+		}
+
+		// constrained params are tried in the order they are registered, before the unconstrained one,
+		// a value matching several constraints, e.g. 5 for {id:int} and {n:uint}, goes to the first
+		pos := len(t.dynamic)
+
+		if constraint != "" {
+			for pos > 0 && t.dynamic[pos-1].constraint == "" {
+				pos--
+			}
+		}
+
+		t.dynamic = append(t.dynamic, dynamicItem{})
+		copy(t.dynamic[pos+1:], t.dynamic[pos:])
+		t.dynamic[pos] = r
+
+		return nil
 	}
 
 	// Static segment: merge into existing subtree if it exists
@@ -112,26 +229,110 @@ func (t *dynamicRouter) Push(path []string, handler RouteFunc) {
 		t.static = make(map[string]dynamicRouter, 5)
 	}
 	if child, ok := t.static[path[0]]; ok {
-		child.Push(path[1:], handler)
+		err := child.Push(path[1:], handler, pattern)
 		t.static[path[0]] = child
-		return
+		return err
 	}
 	child := dynamicRouter{
 		static:  make(map[string]dynamicRouter, 5),
 		dynamic: make([]dynamicItem, 0, 5),
 	}
-	child.Push(path[1:], handler)
+	if err := child.Push(path[1:], handler, pattern); err != nil {
+		return err
+	}
 	t.static[path[0]] = child
+
+	return nil
+}
+
+// constraintExpr returns the regular expression of a param constraint
+func constraintExpr(constraint string) string {
+	if expr, ok := paramTypes[constraint]; ok {
+		return expr
+	}
+
+	return constraint
+}
+
+func (t *dynamicRouter) pushCatchAll(name string, rest []string, handler RouteFunc, pattern string) error {
+	if len(rest) > 0 && rest[0] != "" {
+		return fmt.Errorf("catch-all param {%s...} must be the last segment", name)
+	}
+
+	if name == "" {
+		return errors.New("empty param name")
+	}
+
+	if t.catchAll != nil {
+		return fmt.Errorf("conflicts with %s", t.catchAll.next.pattern)
+	}
+
+	t.catchAll = &dynamicItem{
+		name: name,
+		next: dynamicRouter{hande: handler, pattern: pattern},
+	}
+
+	return nil
+}
+
+// first returns a pattern registered in the subtree, used in conflict errors
+func (t *dynamicRouter) first() string {
+	if t.hande != nil {
+		return t.pattern
+	}
+
+	for _, v := range t.static {
+		if p := v.first(); p != "" {
+			return p
+		}
+	}
+
+	for _, v := range t.dynamic {
+		if p := v.next.first(); p != "" {
+			return p
+		}
+	}
+
+	if t.catchAll != nil {
+		return t.catchAll.next.pattern
+	}
+
+	return ""
+}
+
+// onListeners answers 404 to requests accepted by listeners other than names, before the route middlewares
+func onListeners(names []string, handler RouteFunc) RouteFunc {
+	allowed := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		allowed[name] = true
+	}
+
+	return func(c context.Context, ctx ICtx) {
+		if !allowed[listener.FromContext(c)] {
+			ctx.GetResponse().SetStatus(http.StatusNotFound)
+			return
+		}
+
+		handler(c, ctx)
+	}
 }
 
 func (t *Router) Find(method string, path string) (RouteFunc, map[string]string) {
+	h, m, _ := t.FindRoute(method, path)
+
+	return h, m
+}
+
+// FindRoute is Find also returning the template the route was registered with, e.g. /users/{id:int}
+func (t *Router) FindRoute(method string, path string) (RouteFunc, map[string]string, string) {
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
 
 	if _, ok := t.static[method]; ok {
-		if handler, ok := t.static[method][path]; ok {
-			return handler, nil
+		if r, ok := t.static[method][path]; ok {
+			return r.handler, nil, r.pattern
 		}
 	}
 
@@ -139,50 +340,99 @@ func (t *Router) Find(method string, path string) (RouteFunc, map[string]string)
 		p := strings.Split(path, "/")
 
 		if dr, ok := t.dynamic[method]; ok {
-			h, m := dr.Find(p[1:])
+			h, m, pattern := dr.Find(p[1:])
 
 			if h != nil {
-				return h, m
+				return h, m, pattern
 			}
 		}
 	}
 
 	if _, ok := t.static[method]; ok {
-		if handler, ok := t.static[method]["*/"]; ok {
-			return handler, nil
+		if r, ok := t.static[method]["*/"]; ok {
+			return r.handler, nil, r.pattern
 		}
 	}
 
-	return nil, nil
+	return nil, nil, ""
 }
 
-func (t *dynamicRouter) Find(path []string) (RouteFunc, map[string]string) {
+// Allowed returns the methods having a route for path, HEAD is added for GET and OPTIONS for any.
+// It is empty when no method matches.
+func (t *Router) Allowed(path string) []string {
+	methods := make(map[string]bool, len(t.static)+len(t.dynamic))
+
+	for method := range t.static {
+		methods[method] = true
+	}
+
+	for method := range t.dynamic {
+		methods[method] = true
+	}
+
+	res := make([]string, 0, len(methods)+2)
+
+	for method := range methods {
+		if h, _ := t.Find(method, path); h != nil {
+			res = append(res, method)
+		}
+	}
+
+	if len(res) == 0 {
+		return res
+	}
+
+	if h, _ := t.Find("GET", path); h != nil && !methods["HEAD"] {
+		res = append(res, "HEAD")
+	}
+
+	if !methods["OPTIONS"] {
+		res = append(res, "OPTIONS")
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+func (t *dynamicRouter) Find(path []string) (RouteFunc, map[string]string, string) {
 	if len(path) == 0 || path[0] == "" {
 		if t.hande != nil {
-			return t.hande, map[string]string{}
+			return t.hande, map[string]string{}, t.pattern
 		}
 
-		return nil, nil
+		return nil, nil, ""
 	}
 
 	if v, ok := t.static[path[0]]; ok {
-		r, m := v.Find(path[1:])
+		r, m, pattern := v.Find(path[1:])
 
 		if r != nil {
-			return r, m
+			return r, m, pattern
 		}
 	}
 
 	for _, v := range t.dynamic {
-		r, m := v.next.Find(path[1:])
+		if v.match != nil && !v.match.MatchString(path[0]) {
+			continue
+		}
+
+		r, m, pattern := v.next.Find(path[1:])
 
 		if r != nil {
 			m[v.name] = path[0]
-			return r, m
+			return r, m, pattern
 		}
 	}
 
-	return nil, nil
+	if t.catchAll != nil {
+		// path ends with the empty segment of the trailing slash
+		return t.catchAll.next.hande, map[string]string{
+			t.catchAll.name: strings.Join(path[:len(path)-1], "/"),
+		}, t.catchAll.next.pattern
+	}
+
+	return nil, nil, ""
 }
 
 func (t *Router) HandlerMiddleware(middlewares []string) (func(routeFunc RouteFunc) RouteFunc, error) {
@@ -206,8 +456,9 @@ func (t *Router) HandlerFromList(middlewares []IMiddleware) func(routeFunc Route
 			return next
 		}
 
-		handler := middlewares[len(middlewares)-1].Invoke(t.HandlerFromList(middlewares[:len(middlewares)-1])(next))
-		for i := len(middlewares) - 2; i >= 0; i-- {
+		// the first middleware is the outermost one
+		handler := next
+		for i := len(middlewares) - 1; i >= 0; i-- {
 			handler = middlewares[i].Invoke(handler)
 		}
 		return handler
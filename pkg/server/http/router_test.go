@@ -0,0 +1,267 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func routeBody(body string) RouteFunc {
+	return func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte(body))
+	}
+}
+
+func TestRouter_Params(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{})
+
+	routes := map[string]string{
+		"/users/me":                 "me",
+		"/users/{id:int}":           "int",
+		"/users/{login}":            "login",
+		"/orders/{code:[A-Z]{3}}":   "code",
+		"/files/{path...}":          "files",
+		"/items/{id:uuid}/comments": "comments",
+	}
+
+	for path, body := range routes {
+		if err := r.PushRoute("GET", path, routeBody(body), nil); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		path  string
+		body  string
+		param string
+		value string
+	}{
+		{"/users/me", "me", "", ""},
+		{"/users/42", "int", "id", "42"},
+		{"/users/alex", "login", "login", "alex"},
+		{"/orders/ABC", "code", "code", "ABC"},
+		{"/orders/ABCD", "", "", ""},
+		{"/files/a/b/c.txt", "files", "path", "a/b/c.txt"},
+		{"/files/", "", "", ""},
+		{"/items/123e4567-e89b-12d3-a456-426614174000/comments", "comments", "id", "123e4567-e89b-12d3-a456-426614174000"},
+		{"/items/1/comments", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		route, props := r.Find("GET", tt.path)
+
+		if tt.body == "" {
+			if route != nil {
+				t.Fatalf("%s: must not match", tt.path)
+			}
+
+			continue
+		}
+
+		if route == nil {
+			t.Fatalf("%s: route not found", tt.path)
+		}
+
+		ctx := HttpCtxPool.Get().(*HttpCtx)
+		ctx.GetResponse().Clear()
+		ctx.SetRouteProps(props)
+		route(context.Background(), ctx)
+
+		if string(ctx.GetResponse().GetBody()) != tt.body {
+			t.Fatalf("%s: want %s, got %s", tt.path, tt.body, ctx.GetResponse().GetBody())
+		}
+
+		if tt.param != "" && ctx.GetRouterValue(tt.param) != tt.value {
+			t.Fatalf("%s: want %s=%s, got %s", tt.path, tt.param, tt.value, ctx.GetRouterValue(tt.param))
+		}
+
+		HttpCtxPool.Put(ctx)
+	}
+}
+
+func TestRouter_Conflicts(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{})
+	h := routeBody("")
+
+	_ = r.PushRoute("GET", "/users", h, nil)
+	_ = r.PushRoute("GET", "/users/{id}/orders", h, nil)
+	_ = r.PushRoute("GET", "/files/{path...}", h, nil)
+
+	conflicts := []string{
+		"/users",
+		"/users/",
+		"/users/{name}",
+		"/users/{id}/orders",
+		"/files/{rest...}",
+		"/docs/{path...}/edit",
+		"/users/{id:[}",
+	}
+
+	for _, path := range conflicts {
+		if err := r.PushRoute("GET", path, h, nil); err == nil {
+			t.Fatalf("%s: expected conflict", path)
+		}
+	}
+
+	allowed := []string{
+		"/users/{id:int}",
+		"/users/{id}/profile",
+		"/files/{path...}/",
+	}
+
+	for i, path := range allowed {
+		method := "GET"
+
+		if i == len(allowed)-1 {
+			method = "PUT"
+		}
+
+		if err := r.PushRoute(method, path, h, nil); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestRouter_ConstraintOrder(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{})
+
+	_ = r.PushRoute("GET", "/a/{id:int}", routeBody("a int"), nil)
+	_ = r.PushRoute("GET", "/a/{n:uint}", routeBody("a uint"), nil)
+	_ = r.PushRoute("GET", "/b/{n:uint}", routeBody("b uint"), nil)
+	_ = r.PushRoute("GET", "/b/{id:int}", routeBody("b int"), nil)
+
+	// the same expression as a named constraint is the same param
+	for _, path := range []string{"/a/{n:[0-9]+}", "/a/{m:[0-9]+}", "/b/{id:-?[0-9]+}"} {
+		if err := r.PushRoute("GET", path, routeBody(""), nil); err == nil {
+			t.Fatalf("%s: expected conflict", path)
+		}
+	}
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/a/5", "a int"},
+		{"/a/-5", "a int"},
+		{"/b/5", "b uint"},
+		{"/b/-5", "b int"},
+	}
+
+	for _, tt := range tests {
+		route, props := r.Find("GET", tt.path)
+
+		if route == nil {
+			t.Fatalf("%s: route not found", tt.path)
+		}
+
+		ctx := HttpCtxPool.Get().(*HttpCtx)
+		ctx.GetResponse().Clear()
+		ctx.SetRouteProps(props)
+		route(context.Background(), ctx)
+
+		if string(ctx.GetResponse().GetBody()) != tt.body {
+			t.Fatalf("%s: want %s, got %s", tt.path, tt.body, ctx.GetResponse().GetBody())
+		}
+
+		HttpCtxPool.Put(ctx)
+	}
+}
+
+func TestHTTP_MethodNotAllowed(t *testing.T) {
+	s := New("test")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.PushRoute("GET", "/items/{id}", routeBody("item"), nil)
+	_ = s.PushRoute("DELETE", "/items/{id}", routeBody(""), nil)
+
+	do := func(method string, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+		return w
+	}
+
+	if w := do("POST", "/items/1"); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "DELETE, GET, HEAD, OPTIONS" {
+		t.Fatalf("want 405 with Allow, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	if w := do("OPTIONS", "/items/1"); w.Code != http.StatusNoContent || w.Header().Get("Allow") != "DELETE, GET, HEAD, OPTIONS" {
+		t.Fatalf("want 204 with Allow, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	if w := do("HEAD", "/items/1"); w.Code != http.StatusOK {
+		t.Fatalf("want 200 for HEAD, got %d", w.Code)
+	}
+
+	if w := do("POST", "/unknown"); w.Code != http.StatusNotFound {
+		t.Fatalf("want 404, got %d", w.Code)
+	}
+}
+
+func TestRouter_FindRoute(t *testing.T) {
+	r := NewRouter(map[string]IMiddleware{})
+
+	for _, path := range []string{"/login", "/users/{id:int}", "/files/{path...}", "/api/{version}/items/"} {
+		if err := r.PushRoute("GET", path, routeBody("ok"), nil); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	tests := map[string]string{
+		"/login":          "/login",
+		"/login/":         "/login",
+		"/users/42":       "/users/{id:int}",
+		"/files/a/b":      "/files/{path...}",
+		"/api/v2/items":   "/api/{version}/items/",
+		"/users/me/other": "",
+	}
+
+	for path, want := range tests {
+		if _, _, pattern := r.FindRoute("GET", path); pattern != want {
+			t.Fatalf("%s: want %q, got %q", path, want, pattern)
+		}
+	}
+}
+
+// traceMiddleware records entering and leaving the handler it wraps
+type traceMiddleware struct {
+	name  string
+	calls *[]string
+}
+
+func (t *traceMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		*t.calls = append(*t.calls, t.name+">")
+		next(c, ctx)
+		*t.calls = append(*t.calls, "<"+t.name)
+	}
+}
+
+func TestRouter_HandlerFromList(t *testing.T) {
+	var calls []string
+
+	r := NewRouter(map[string]IMiddleware{})
+	md := r.HandlerFromList([]IMiddleware{
+		&traceMiddleware{name: "a", calls: &calls},
+		&traceMiddleware{name: "b", calls: &calls},
+		&traceMiddleware{name: "c", calls: &calls},
+	})
+
+	md(func(_ context.Context, _ ICtx) {
+		calls = append(calls, "handler")
+	})(context.Background(), nil)
+
+	// each middleware runs once, the first one is the outermost
+	if got, want := strings.Join(calls, " "), "a> b> c> handler <c <b <a"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,266 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TLSConfig enables HTTPS, the certificate, key and CA files are reloaded when they change
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	ClientCAFile   string        `yaml:"client_ca_file"`  // CA bundle verifying client certificates
+	ClientAuth     string        `yaml:"client_auth"`     // none, request, require, verify_if_given, require_and_verify; default require_and_verify with client_ca_file
+	MinVersion     string        `yaml:"min_version"`     // 1.2 or 1.3, default 1.2
+	ReloadInterval time.Duration `yaml:"reload_interval"` // files check interval, default 10s
+}
+
+func (t *TLSConfig) enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// ClientIdentity is the verified client certificate of an mTLS connection
+type ClientIdentity struct {
+	CommonName     string
+	Organization   []string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string // e.g. SPIFFE ids
+	SerialNumber   string
+	Fingerprint    string // hex SHA-256 of the certificate
+	Certificate    *x509.Certificate
+}
+
+func newClientIdentity(cert *x509.Certificate) *ClientIdentity {
+	sum := sha256.Sum256(cert.Raw)
+
+	res := &ClientIdentity{
+		CommonName:     cert.Subject.CommonName,
+		Organization:   cert.Subject.Organization,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		SerialNumber:   cert.SerialNumber.String(),
+		Fingerprint:    hex.EncodeToString(sum[:]),
+		Certificate:    cert,
+	}
+
+	for _, u := range cert.URIs {
+		res.URIs = append(res.URIs, u.String())
+	}
+
+	return res
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsReloader serves the current certificate and client CA pool and swaps them when the files change
+type tlsReloader struct {
+	config     TLSConfig
+	clientAuth tls.ClientAuthType
+	minVersion uint16
+	nextProtos []string
+
+	cert atomic.Pointer[tls.Certificate]
+	pool atomic.Pointer[x509.CertPool]
+
+	mu      sync.Mutex
+	stamps  map[string]string
+	lastErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newTLSReloader(cfg TLSConfig, nextProtos []string) (*tlsReloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls: cert_file and key_file are required")
+	}
+
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = 10 * time.Second
+	}
+
+	if cfg.MinVersion == "" {
+		cfg.MinVersion = "1.2"
+	}
+
+	if cfg.ClientAuth == "" {
+		cfg.ClientAuth = "none"
+
+		if cfg.ClientCAFile != "" {
+			cfg.ClientAuth = "require_and_verify"
+		}
+	}
+
+	res := &tlsReloader{
+		config:     cfg,
+		nextProtos: nextProtos,
+		stamps:     make(map[string]string),
+	}
+
+	var ok bool
+
+	if res.clientAuth, ok = clientAuthTypes[cfg.ClientAuth]; !ok {
+		return nil, fmt.Errorf("tls: unknown client_auth %q", cfg.ClientAuth)
+	}
+
+	if res.minVersion, ok = tlsVersions[cfg.MinVersion]; !ok {
+		return nil, fmt.Errorf("tls: unknown min_version %q", cfg.MinVersion)
+	}
+
+	if res.clientAuth >= tls.VerifyClientCertIfGiven && cfg.ClientCAFile == "" {
+		return nil, fmt.Errorf("tls: client_auth %s requires client_ca_file", cfg.ClientAuth)
+	}
+
+	// the files are stamped before loading, a change during the load is picked up by the next check
+	res.changed()
+
+	if err := res.load(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// TLSConfig returns the server config, the connections get the certificate and CA pool current at handshake
+func (t *tlsReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: t.minVersion,
+		NextProtos: t.nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				MinVersion:   t.minVersion,
+				NextProtos:   t.nextProtos,
+				Certificates: []tls.Certificate{*t.cert.Load()},
+				ClientCAs:    t.pool.Load(),
+				ClientAuth:   t.clientAuth,
+			}, nil
+		},
+	}
+}
+
+func (t *tlsReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(t.config.CertFile, t.config.KeyFile)
+
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	var pool *x509.CertPool
+
+	if t.config.ClientCAFile != "" {
+		data, err := os.ReadFile(t.config.ClientCAFile)
+
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+
+		pool = x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("tls: no certificates in %s", t.config.ClientCAFile)
+		}
+	}
+
+	t.cert.Store(&cert)
+	t.pool.Store(pool)
+
+	return nil
+}
+
+// changed stats the files and reports whether any of them differs from the previous check
+func (t *tlsReloader) changed() bool {
+	res := false
+
+	for _, name := range []string{t.config.CertFile, t.config.KeyFile, t.config.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+
+		stamp := ""
+
+		if fi, err := os.Stat(name); err == nil {
+			stamp = fi.ModTime().String() + "/" + fmt.Sprint(fi.Size())
+		}
+
+		if t.stamps[name] != stamp {
+			t.stamps[name] = stamp
+			res = true
+		}
+	}
+
+	return res
+}
+
+// reload loads the files if they changed, the previous certificate is kept on errors
+func (t *tlsReloader) reload() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.changed() {
+		return
+	}
+
+	if t.lastErr = t.load(); t.lastErr != nil {
+		// files may be replaced one by one, retried on the next check
+		t.stamps = make(map[string]string)
+	}
+}
+
+// Err returns the error of the last failed reload, nil after a successful one
+func (t *tlsReloader) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.lastErr
+}
+
+func (t *tlsReloader) Start() {
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.config.ReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.reload()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (t *tlsReloader) Stop() {
+	if t.stop == nil {
+		return
+	}
+
+	close(t.stop)
+	<-t.done
+	t.stop = nil
+}
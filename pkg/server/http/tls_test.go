@@ -0,0 +1,301 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, client bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"paranoia"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	signer, signerKey := tmpl, key
+
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{"localhost"}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+
+		if client {
+			tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if keyFile == "" {
+		return
+	}
+
+	der, _ := x509.MarshalECPrivateKey(c.key)
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHttp_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, "ca", nil, false)
+	ca.write(t, caFile, "")
+	newTestCert(t, "server-1", ca, false).write(t, certFile, keyFile)
+
+	s := New("tls")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{},
+		"port":        "0",
+		"tls": map[string]interface{}{
+			"cert_file":       certFile,
+			"key_file":        keyFile,
+			"client_ca_file":  caFile,
+			"reload_interval": 20 * time.Millisecond,
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.PushRoute("GET", "/whoami", func(_ context.Context, ctx ICtx) {
+		id := ctx.GetRequest().GetClientIdentity()
+
+		if id == nil {
+			ctx.GetResponse().SetStatus(http.StatusUnauthorized)
+			return
+		}
+
+		ctx.GetResponse().SetBody([]byte(id.CommonName + " " + id.Organization[0]))
+	}, nil)
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Stop()
+
+	url := fmt.Sprintf("https://127.0.0.1:%d/whoami", s.listeners[0].Addr().(*net.TCPAddr).Port)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		}}
+	}
+
+	if _, err = client().Get(url); err == nil {
+		t.Fatal("a client without certificate must be rejected")
+	}
+
+	stranger := newTestCert(t, "stranger", newTestCert(t, "other-ca", nil, false), true)
+
+	if _, err = client(stranger.pair).Get(url); err == nil {
+		t.Fatal("a certificate of an unknown CA must be rejected")
+	}
+
+	alice := newTestCert(t, "alice", ca, true)
+	resp, err := client(alice.pair).Get(url)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	_ = resp.Body.Close()
+
+	if resp.ProtoMajor != 2 || string(body[:n]) != "alice paranoia" {
+		t.Fatalf("unexpected response %s %q", resp.Proto, body[:n])
+	}
+
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "server-1" {
+		t.Fatalf("unexpected server certificate %s", cn)
+	}
+
+	newTestCert(t, "server-2", ca, false).write(t, certFile, keyFile)
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		resp, err = client(alice.pair).Get(url)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = resp.Body.Close()
+
+		if resp.TLS.PeerCertificates[0].Subject.CommonName == "server-2" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHttp_TLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	newTestCert(t, "server", newTestCert(t, "ca", nil, false), false).write(t, certFile, keyFile)
+
+	tests := map[string]map[string]interface{}{
+		"missing key":       {"cert_file": certFile},
+		"missing file":      {"cert_file": certFile, "key_file": filepath.Join(dir, "none.key")},
+		"verify without ca": {"cert_file": certFile, "key_file": keyFile, "client_auth": "require_and_verify"},
+		"unknown version":   {"cert_file": certFile, "key_file": keyFile, "min_version": "1.0"},
+	}
+
+	for name, cfg := range tests {
+		s := New("tls")
+
+		if err := s.Init(map[string]interface{}{"middlewares": map[string]IMiddleware{}, "tls": cfg}); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHttp_H2C(t *testing.T) {
+	s := New("h2c")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares": map[string]IMiddleware{},
+		"port":        "0",
+		"h2c":         true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.PushRoute("GET", "/proto", func(_ context.Context, ctx ICtx) {
+		ctx.GetResponse().SetBody([]byte(ctx.GetRequest().(*HttpRequest).request.Proto))
+	}, nil)
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Stop()
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+
+	resp, err := (&http.Client{Transport: transport}).Get(fmt.Sprintf("http://127.0.0.1:%d/proto", s.listeners[0].Addr().(*net.TCPAddr).Port))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("want HTTP/2, got %s", resp.Proto)
+	}
+}
+
+func TestHttp_MaxBodySize(t *testing.T) {
+	s := New("limits")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares":   map[string]IMiddleware{},
+		"max_body_size": 16,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.PushRouteE("POST", "/items", func(_ context.Context, ctx ICtx) error {
+		var dst struct {
+			Name string `json:"name"`
+		}
+
+		return ctx.Bind(&dst)
+	}, nil)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/items", strings.NewReader(`{"name":"a very long name"}`)))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("want 413 by content length, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/items", strings.NewReader(`{"name":"a very long name"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("want 413 while reading, got %d %s", w.Code, w.Body.String())
+	}
+}
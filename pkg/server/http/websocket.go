@@ -0,0 +1,355 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket message types
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// WebSocket close codes
+const (
+	CloseNormal        = websocket.CloseNormalClosure
+	CloseGoingAway     = websocket.CloseGoingAway
+	ClosePolicy        = websocket.ClosePolicyViolation
+	CloseTooLarge      = websocket.CloseMessageTooBig
+	CloseInternalError = websocket.CloseInternalServerErr
+	CloseTryAgainLater = websocket.CloseTryAgainLater
+)
+
+var (
+	// ErrSlowConsumer is returned by Send when the send buffer is full, the connection is closed
+	ErrSlowConsumer = errors.New("websocket send buffer is full")
+
+	// ErrConnectionClosed is returned by Send after the connection is closed
+	ErrConnectionClosed = errors.New("websocket connection closed")
+)
+
+// WebSocketConfig configures the connections of PushWebSocket routes
+type WebSocketConfig struct {
+	PingInterval   time.Duration `yaml:"ping_interval"`    // default 30s
+	PongWait       time.Duration `yaml:"pong_wait"`        // connection is closed without a pong or message in time, default 60s
+	WriteTimeout   time.Duration `yaml:"write_timeout"`    // default 10s
+	MaxMessageSize int64         `yaml:"max_message_size"` // bytes, default 1MB
+	SendBuffer     int           `yaml:"send_buffer"`      // queued outgoing messages per connection, default 64
+	AllowOrigins   []string      `yaml:"allow_origins"`    // default same origin only, * - any
+	CloseTimeout   time.Duration `yaml:"close_timeout"`    // time for handlers to return on stop, default 5s
+}
+
+// IWebSocket is an upgraded connection passed to a WebSocketFunc
+type IWebSocket interface {
+	// Read blocks until the next data message, control frames are handled internally
+	Read() (messageType int, data []byte, err error)
+
+	// Send queues a message, a full send buffer closes the connection as a slow consumer
+	Send(messageType int, data []byte) error
+
+	// Close sends a close frame with code and reason and closes the connection
+	Close(code int, reason string) error
+
+	// Done is closed when the connection is closed
+	Done() <-chan struct{}
+}
+
+// WebSocketFunc handles an upgraded connection, the connection is closed when it returns.
+// c keeps the values set by middlewares and is canceled when the connection is closed.
+type WebSocketFunc func(c context.Context, ctx ICtx, conn IWebSocket)
+
+type wsMessage struct {
+	messageType int
+	data        []byte
+}
+
+type wsConn struct {
+	conn   *websocket.Conn
+	server *Http
+	send   chan wsMessage
+	done   chan struct{}
+	once   sync.Once
+	closed atomic.Bool
+}
+
+// PushWebSocket adds a GET route upgrading requests to WebSocket connections.
+// Base middleware and middlewares run before the upgrade and can reject the request.
+func (t *Http) PushWebSocket(path string, handler WebSocketFunc, middlewares []string) error {
+	return t.router.PushRoute(http.MethodGet, path, t.webSocketRoute(handler), middlewares)
+}
+
+func (t *Http) webSocketRoute(handler WebSocketFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		hc, ok := ctx.(*HttpCtx)
+
+		if !ok || hc.writer == nil {
+			ctx.GetResponse().SetStatus(http.StatusInternalServerError)
+			return
+		}
+
+		req := hc.request.(*HttpRequest).request
+
+		if !websocket.IsWebSocketUpgrade(req) {
+			ctx.GetResponse().Header().Set("Upgrade", "websocket")
+			ctx.GetResponse().SetStatus(http.StatusUpgradeRequired)
+			return
+		}
+
+		upgrader := t.upgrader
+
+		upgrader.Error = func(_ http.ResponseWriter, _ *http.Request, status int, reason error) {
+			ctx.GetResponse().SetStatus(status)
+			ctx.GetResponse().SetBody([]byte(reason.Error()))
+		}
+
+		header := http.Header{}
+
+		for k, v := range ctx.GetResponse().Header().GetAsMap() {
+			header[k] = v
+		}
+
+		if !hc.commit() {
+			// TimeoutMiddleware has answered the request
+			return
+		}
+
+		// the hijacked connection is not written by ServeHTTP
+		hc.upgraded.Store(true)
+
+		conn, err := upgrader.Upgrade(hc.writer, req, header)
+
+		if err != nil {
+			hc.upgraded.Store(false)
+			return
+		}
+
+		t.serveWebSocket(c, ctx, conn, handler)
+	}
+}
+
+func (t *Http) serveWebSocket(c context.Context, ctx ICtx, conn *websocket.Conn, handler WebSocketFunc) {
+	ws := &wsConn{
+		conn:   conn,
+		server: t,
+		send:   make(chan wsMessage, t.config.WebSocket.SendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	t.wsMu.Lock()
+
+	if t.wsStopping {
+		t.wsMu.Unlock()
+		_ = ws.Close(CloseGoingAway, "server shutdown")
+		return
+	}
+
+	t.wsConns[ws] = struct{}{}
+	t.wsWG.Add(1)
+	t.wsMu.Unlock()
+
+	t.wsCounter.Add(context.Background(), 1)
+
+	defer func() {
+		_ = ws.Close(CloseNormal, "")
+
+		t.wsMu.Lock()
+		delete(t.wsConns, ws)
+		t.wsMu.Unlock()
+
+		t.wsCounter.Add(context.Background(), -1)
+		t.wsWG.Done()
+	}()
+
+	conn.SetReadLimit(t.config.WebSocket.MaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(t.config.WebSocket.PongWait))
+
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(t.config.WebSocket.PongWait))
+	})
+
+	go ws.writeLoop()
+
+	// the deadline of TimeoutMiddleware does not apply to the connection
+	wc, cancel := context.WithCancel(context.WithoutCancel(c))
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ws.done:
+			cancel()
+		case <-wc.Done():
+		}
+	}()
+
+	handler(wc, ctx, ws)
+}
+
+func (t *wsConn) Read() (int, []byte, error) {
+	mt, data, err := t.conn.ReadMessage()
+
+	if err != nil {
+		code := CloseGoingAway
+
+		var closeErr *websocket.CloseError
+
+		if errors.As(err, &closeErr) {
+			code = closeErr.Code
+		} else if errors.Is(err, websocket.ErrReadLimit) {
+			code = CloseTooLarge
+		}
+
+		_ = t.Close(code, "")
+
+		return 0, nil, err
+	}
+
+	_ = t.conn.SetReadDeadline(time.Now().Add(t.server.config.WebSocket.PongWait))
+	t.server.wsMessagesIn.Add(context.Background(), 1)
+
+	return mt, data, nil
+}
+
+func (t *wsConn) Send(messageType int, data []byte) error {
+	if t.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	select {
+	case t.send <- wsMessage{messageType: messageType, data: data}:
+		return nil
+
+	case <-t.done:
+		return ErrConnectionClosed
+
+	default:
+		t.server.wsSlowConsumers.Add(context.Background(), 1)
+		_ = t.Close(CloseTryAgainLater, "slow consumer")
+
+		return ErrSlowConsumer
+	}
+}
+
+func (t *wsConn) Close(code int, reason string) error {
+	var err error
+
+	t.once.Do(func() {
+		t.closed.Store(true)
+
+		// control frames may be written concurrently with the write loop
+		_ = t.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(t.server.config.WebSocket.WriteTimeout))
+
+		close(t.done)
+		err = t.conn.Close()
+	})
+
+	return err
+}
+
+func (t *wsConn) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *wsConn) writeLoop() {
+	ticker := time.NewTicker(t.server.config.WebSocket.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-t.send:
+			_ = t.conn.SetWriteDeadline(time.Now().Add(t.server.config.WebSocket.WriteTimeout))
+
+			if err := t.conn.WriteMessage(m.messageType, m.data); err != nil {
+				_ = t.Close(CloseInternalError, "")
+				return
+			}
+
+			t.server.wsMessagesOut.Add(context.Background(), 1)
+
+		case <-ticker.C:
+			if err := t.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(t.server.config.WebSocket.WriteTimeout)); err != nil {
+				_ = t.Close(CloseGoingAway, "")
+				return
+			}
+
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// closeWebSockets closes the connections with going away and waits for their handlers up to the close timeout
+func (t *Http) closeWebSockets() {
+	t.wsMu.Lock()
+	t.wsStopping = true
+
+	for ws := range t.wsConns {
+		_ = ws.Close(CloseGoingAway, "server shutdown")
+	}
+
+	t.wsMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		t.wsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(t.config.WebSocket.CloseTimeout):
+	}
+}
+
+func (t *Http) initWebSocket() {
+	cfg := &t.config.WebSocket
+
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+
+	if cfg.PongWait <= 0 {
+		cfg.PongWait = 60 * time.Second
+	}
+
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = 1 << 20
+	}
+
+	if cfg.SendBuffer <= 0 {
+		cfg.SendBuffer = 64
+	}
+
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = 5 * time.Second
+	}
+
+	t.upgrader = websocket.Upgrader{}
+
+	if len(cfg.AllowOrigins) > 0 {
+		origins := make(map[string]bool, len(cfg.AllowOrigins))
+
+		for _, o := range cfg.AllowOrigins {
+			origins[o] = true
+		}
+
+		t.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+
+			return origin == "" || origins["*"] || origins[origin]
+		}
+	}
+
+	t.wsConns = make(map[*wsConn]struct{})
+}
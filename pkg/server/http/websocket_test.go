@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type denyMiddleware struct{}
+
+func (t denyMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		if ctx.GetRequest().GetHeader().Get("Authorization") == "" {
+			ctx.GetResponse().SetStatus(http.StatusUnauthorized)
+			return
+		}
+
+		ctx.PushUserValue("user", "alex")
+		next(c, ctx)
+	}
+}
+
+func newWebSocketServer(t *testing.T, ws map[string]interface{}, handler WebSocketFunc) (*Http, string) {
+	s := New("ws")
+
+	err := s.Init(map[string]interface{}{
+		"middlewares":     map[string]IMiddleware{"auth": denyMiddleware{}},
+		"base_middleware": []string{"auth"},
+		"websocket":       ws,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.PushWebSocket("/ws/{room}", handler, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	return s, "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/lobby"
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": []string{"token"}})
+
+	if err != nil {
+		t.Fatalf("dial: %v %v", err, resp)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestWebSocket_Echo(t *testing.T) {
+	_, url := newWebSocketServer(t, map[string]interface{}{
+		"max_message_size": 16,
+	}, func(c context.Context, ctx ICtx, conn IWebSocket) {
+		user, _ := ctx.GetUserValue("user")
+
+		for {
+			mt, data, err := conn.Read()
+
+			if err != nil {
+				return
+			}
+
+			_ = conn.Send(mt, []byte(ctx.GetRouterValue("room")+":"+user.(string)+":"+string(data)))
+		}
+	})
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("base middleware must reject the upgrade, got %v", err)
+	}
+
+	conn := dial(t, url)
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte("hi"))
+
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "lobby:alex:hi" {
+		t.Fatalf("unexpected echo %q %v", data, err)
+	}
+
+	_ = conn.WriteMessage(websocket.TextMessage, bytes.Repeat([]byte("x"), 64))
+
+	_, _, err := conn.ReadMessage()
+
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Fatalf("want close 1009, got %v", err)
+	}
+}
+
+func TestWebSocket_Ping(t *testing.T) {
+	_, url := newWebSocketServer(t, map[string]interface{}{
+		"ping_interval": 10 * time.Millisecond,
+	}, func(c context.Context, ctx ICtx, conn IWebSocket) {
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	})
+
+	conn := dial(t, url)
+
+	var pings atomic.Int32
+
+	conn.SetPingHandler(func(string) error {
+		pings.Add(1)
+		return nil
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, _ = conn.ReadMessage()
+
+	if pings.Load() < 2 {
+		t.Fatalf("want pings, got %d", pings.Load())
+	}
+}
+
+func TestWebSocket_SlowConsumer(t *testing.T) {
+	result := make(chan error, 1)
+
+	_, url := newWebSocketServer(t, map[string]interface{}{
+		"send_buffer":   2,
+		"write_timeout": time.Second,
+	}, func(c context.Context, ctx ICtx, conn IWebSocket) {
+		data := bytes.Repeat([]byte("x"), 1<<20)
+
+		for i := 0; i < 1000; i++ {
+			if err := conn.Send(BinaryMessage, data); err != nil {
+				result <- err
+				return
+			}
+		}
+
+		result <- nil
+	})
+
+	// the client never reads
+	_ = dial(t, url)
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrSlowConsumer) {
+			t.Fatalf("want ErrSlowConsumer, got %v", err)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow consumer not detected")
+	}
+}
+
+func TestWebSocket_CloseOnStop(t *testing.T) {
+	finished := make(chan struct{})
+
+	s, url := newWebSocketServer(t, nil, func(c context.Context, ctx ICtx, conn IWebSocket) {
+		defer close(finished)
+
+		<-c.Done()
+	})
+
+	conn := dial(t, url)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = s.Stop()
+	}()
+
+	_, _, err := conn.ReadMessage()
+
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("want close 1001, got %v", err)
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler context not canceled")
+	}
+}
@@ -2,9 +2,21 @@ package kafka
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
 	interfaces2 "gitlab.com/devpro_studio/Paranoia/paranoia/interfaces"
 	"gitlab.com/devpro_studio/go_utils/decode"
-	"time"
+)
+
+// handler states of a message run by TimeoutMiddleware
+const (
+	handlerRunning int32 = iota
+	handlerDone
+	handlerTimedOut
 )
 
 type TimeoutMiddleware struct {
@@ -14,6 +26,8 @@ type TimeoutMiddleware struct {
 
 type TimeoutMiddlewareConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
+	Status  int           `yaml:"status"` // status of a timed out message, default 504
+	Body    string        `yaml:"body"`   // body of a timed out message
 }
 
 func NewTimeoutMiddleware(name string) interfaces2.IMiddleware {
@@ -32,6 +46,10 @@ func (t *TimeoutMiddleware) Init(app interfaces2.IEngine, cfg map[string]interfa
 		t.config.Timeout = time.Second
 	}
 
+	if t.config.Status == 0 {
+		t.config.Status = 504
+	}
+
 	return nil
 }
 
@@ -47,34 +65,77 @@ func (t *TimeoutMiddleware) Type() string {
 	return "middleware"
 }
 
+// Invoke runs next with a deadline. The handler is expected to return once c is done, until then
+// it works on a fork of the context: the timeout status is set without waiting for it,
+// and the pooled context is recycled only after it returns.
 func (t *TimeoutMiddleware) Invoke(next RouteFunc) RouteFunc {
 	return func(c context.Context, ctx ICtx) {
-		var end context.CancelFunc
-		c, end = context.WithTimeout(c, t.config.Timeout)
+		c, end := context.WithTimeout(c, t.config.Timeout)
 
-		done := make(chan interface{})
-		defer close(done)
-		defer end()
+		pc, ok := ctx.(*KafkaCtx)
 
-		go func() {
+		if !ok {
+			// the context is not pooled, the handler only gets the deadline
+			defer end()
 			next(c, ctx)
-			
-			select {
-			case <-c.Done():
-				return
-			default:
-				done <- nil
-			}
+			return
+		}
+
+		fork := pc.fork()
+		release := pc.hold()
+		done := make(chan struct{})
+		state := &atomic.Int32{}
+
+		var recovered interface{}
+
+		go func() {
+			defer release()
+			defer end()
+			defer close(done)
+
+			defer func() {
+				// re-raised for RestoreMiddleware unless the message has timed out
+				recovered = recover()
+
+				if !state.CompareAndSwap(handlerRunning, handlerDone) && recovered != nil {
+					t.lost(recovered)
+				}
+			}()
+
+			next(c, fork)
 		}()
 
 		select {
+		case <-done:
+
 		case <-c.Done():
-			time.Sleep(time.Millisecond)
-			ctx.GetResponse().SetStatus(499)
-			break
+			if state.CompareAndSwap(handlerRunning, handlerTimedOut) {
+				ctx.GetResponse().SetStatus(t.config.Status)
 
-		case <-done:
-			break
+				if t.config.Body != "" {
+					ctx.GetResponse().SetBody([]byte(t.config.Body))
+				}
+
+				return
+			}
+
+			// the handler has just returned
+			<-done
+		}
+
+		if recovered != nil {
+			panic(recovered)
 		}
+
+		pc.join(fork)
 	}
 }
+
+// lost logs a panic of a handler that outlived its timeout, there is nothing left to re-raise it to
+func (t *TimeoutMiddleware) lost(recovered interface{}) {
+	slog.Default().Error("kafka: handler panicked after timeout",
+		slog.String("middleware", t.name),
+		slog.String("panic", fmt.Sprint(recovered)),
+		slog.String("stack", string(debug.Stack())),
+	)
+}
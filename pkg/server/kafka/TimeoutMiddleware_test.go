@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/drain"
+	"go.opentelemetry.io/otel"
+)
+
+// TestTimeoutMiddleware_Load mixes timed out and fast messages, it is meant to run with -race
+func TestTimeoutMiddleware_Load(t *testing.T) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, map[string]interface{}{"timeout": "50ms", "status": 503}); err != nil {
+		t.Fatal(err)
+	}
+
+	route := timeout.Invoke(func(_ context.Context, ctx ICtx) {
+		id, _ := strconv.Atoi(ctx.GetRequest().GetHeader().Get("id"))
+
+		if id%2 == 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		ctx.PushUserValue("id", id)
+		ctx.GetResponse().SetBody([]byte(strconv.Itoa(id)))
+	})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			ctx := KafkaCtxPool.Get().(*KafkaCtx)
+			defer ctx.release()
+			ctx.Fill(&kafka.Message{Headers: []kafka.Header{{Key: "id", Value: []byte(strconv.Itoa(id))}}})
+
+			route(context.Background(), ctx)
+
+			status := ctx.GetResponse().GetStatus()
+			body := string(ctx.GetResponse().GetBody())
+
+			if id%2 == 0 && (status != 503 || body != "") {
+				t.Errorf("%d: want timed out, got %d %q", id, status, body)
+			}
+
+			if id%2 == 1 && (status != 200 || body != strconv.Itoa(id)) {
+				t.Errorf("%d: response of another message %d %q", id, status, body)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// TestKafka_DrainWaitsForTimedOutHandler stops the server while a timed out handler is still running,
+// the message stays in flight until the handler returns
+func TestKafka_DrainWaitsForTimedOutHandler(t *testing.T) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, map[string]interface{}{"timeout": "20ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("kafka")
+	s.drain = drain.New()
+	s.router = NewRouter(map[string]IMiddleware{"timeout": timeout})
+	s.md = func(routeFunc RouteFunc) RouteFunc { return routeFunc }
+	s.counter, _ = otel.Meter("").Int64Counter("test.count")
+	s.counterError, _ = otel.Meter("").Int64Counter("test.count_error")
+	s.timeCounter, _ = otel.Meter("").Int64Histogram("test.time")
+
+	finish := make(chan struct{})
+
+	err := s.PushRoute("topic", func(_ context.Context, _ ICtx) {
+		<-finish
+	}, []string{"timeout"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := "topic"
+	s.Handle(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}})
+
+	if n := s.drain.InFlight(); n != 1 {
+		t.Fatalf("timed out message must stay in flight, got %d", n)
+	}
+
+	stopped := make(chan int64, 1)
+
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stopped <- s.drain.Wait(c)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("drain returned while the handler is running")
+
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(finish)
+
+	if aborted := <-stopped; aborted != 0 {
+		t.Fatalf("want no aborted messages, got %d", aborted)
+	}
+}
@@ -8,8 +8,11 @@ import (
 
 // IKafka defines the interface for Kafka server operations
 type IKafka interface {
-	// PushRoute adds a new route to the Kafka server
-	PushRoute(path string, handler RouteFunc, middlewares []string)
+	// PushRoute adds a new route to the Kafka server, it fails on unknown middlewares
+	PushRoute(path string, handler RouteFunc, middlewares []string) error
+
+	// Group returns a route group with a common name prefix and middleware chain
+	Group(prefix string, middlewares ...string) *Group
 }
 
 type IHeader interface {
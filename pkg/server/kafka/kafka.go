@@ -5,10 +5,11 @@ import (
 	"errors"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/jurabek/otelkafka"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/drain"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
-	"sync"
+	"log/slog"
 	"time"
 )
 
@@ -19,13 +20,17 @@ type Kafka struct {
 
 	router   *Router
 	consumer *otelkafka.Consumer
+	logs     chan kafka.LogEvent
 	done     chan interface{}
-	w        sync.WaitGroup
+	stopped  chan interface{}
+	started  bool
+	drain    *drain.Drain
 	md       func(RouteFunc) RouteFunc
 
-	counter      metric.Int64Counter
-	counterError metric.Int64Counter
-	timeCounter  metric.Int64Histogram
+	counter        metric.Int64Counter
+	counterError   metric.Int64Counter
+	timeCounter    metric.Int64Histogram
+	abortedCounter metric.Int64Counter
 }
 
 type Config struct {
@@ -38,6 +43,8 @@ type Config struct {
 	Topics            []string `yaml:"topics"`
 	LimitMessageCount int64    `yaml:"limit_message_count"`
 	BaseMiddleware    []string `yaml:"base_middleware"`
+
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // in-flight messages are canceled after it on stop, default 10s
 }
 
 func New(name string) *Kafka {
@@ -75,7 +82,17 @@ func (t *Kafka) Init(cfg map[string]interface{}) error {
 		return errors.New("topics is required")
 	}
 
+	if t.config.LimitMessageCount <= 0 {
+		t.config.LimitMessageCount = 100
+	}
+
+	if t.config.DrainTimeout <= 0 {
+		t.config.DrainTimeout = 10 * time.Second
+	}
+
 	t.done = make(chan interface{})
+	t.stopped = make(chan interface{})
+	t.drain = drain.New()
 
 	t.router = NewRouter(middlewares)
 
@@ -102,6 +119,10 @@ func (t *Kafka) Init(cfg map[string]interface{}) error {
 		"auto.offset.reset": "earliest",
 	}
 
+	t.logs = make(chan kafka.LogEvent, 1000)
+	_ = cfgKafka.SetKey("go.logs.channel.enable", true)
+	_ = cfgKafka.SetKey("go.logs.channel", t.logs)
+
 	if t.config.User != "" {
 		if t.config.SecurityProtocol != "" {
 			cfgKafka.SetKey("security.protocol", t.config.SecurityProtocol)
@@ -118,63 +139,96 @@ func (t *Kafka) Init(cfg map[string]interface{}) error {
 	t.consumer, err = otelkafka.NewConsumer(&cfgKafka)
 
 	if err != nil {
+		close(t.logs)
 		return err
 	}
 
+	go forwardLogs(t.name, t.logs)
+
 	t.counter, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".count")
 	t.counterError, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".count_error")
 	t.timeCounter, _ = otel.Meter("").Int64Histogram("server_kafka." + t.name + ".time")
+	t.abortedCounter, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".aborted")
 
 	return t.consumer.SubscribeTopics(t.config.Topics, nil)
 }
 
 func (t *Kafka) Start() error {
+	t.started = true
+
 	go func() {
+		defer close(t.stopped)
+
 		limited := make(chan interface{}, t.config.LimitMessageCount)
-		defer close(limited)
 
-	forLoop:
 		for {
 			select {
 			case <-t.done:
-				break forLoop
-
-			default:
-				t.w.Add(1)
-				limited <- nil
-				msg, err := t.consumer.ReadMessage(time.Second)
-
-				if err != nil {
-					<-limited
-					t.w.Done()
-					continue
-				}
-
-				go func() {
-					t.Handle(msg)
-					<-limited
-					t.w.Done()
-				}()
+				return
+
+			case limited <- nil:
 			}
-		}
 
-		t.w.Wait()
+			msg, err := t.consumer.ReadMessage(time.Second)
+
+			if err != nil {
+				<-limited
+				continue
+			}
+
+			c, done, ok := t.drain.Begin(context.Background())
+
+			if !ok {
+				// draining, the uncommitted message is read again after the restart
+				<-limited
+				return
+			}
+
+			go func() {
+				t.handle(c, msg, done)
+				<-limited
+			}()
+		}
 	}()
 
 	return nil
 }
 
+// Stop drains the server: readiness fails, reading stops and in-flight messages are waited for
+// up to drain_timeout and then canceled
 func (t *Kafka) Stop() error {
-	_ = t.consumer.Unsubscribe()
+	t.drain.SetNotReady()
 	close(t.done)
-	t.w.Wait()
-	err := t.consumer.Close()
 
-	time.Sleep(time.Second)
+	if t.started {
+		<-t.stopped
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), t.config.DrainTimeout)
+	defer cancel()
+
+	if aborted := t.drain.Wait(c); aborted > 0 {
+		t.abortedCounter.Add(context.Background(), aborted)
+		slog.Default().Warn("kafka: messages aborted on stop", slog.String("kafka", t.name), slog.Int64("aborted", aborted))
+	}
+
+	_ = t.consumer.Unsubscribe()
+	err := t.consumer.Close()
+	close(t.logs)
 
 	return err
 }
 
+// Ready is false once the server starts draining
+func (t *Kafka) Ready() bool {
+	return t.drain != nil && t.drain.Ready()
+}
+
+// Aborted returns the number of messages canceled by the drain deadline on stop
+func (t *Kafka) Aborted() int64 {
+	return t.drain.AbortedCount()
+}
+
 func (t *Kafka) Name() string {
 	return t.name
 }
@@ -183,22 +237,35 @@ func (t *Kafka) Type() string {
 	return "server"
 }
 
-func (t *Kafka) PushRoute(path string, handler RouteFunc, middlewares []string) {
-	t.router.PushRoute(path, handler, middlewares)
+func (t *Kafka) PushRoute(path string, handler RouteFunc, middlewares []string) error {
+	return t.router.PushRoute(path, handler, middlewares)
 }
 
 func (t *Kafka) Handle(msg *kafka.Message) {
+	c, done, ok := t.drain.Begin(context.Background())
+
+	if !ok {
+		return
+	}
+
+	t.handle(c, msg, done)
+}
+
+// handle runs the route of msg. done is called once the context is released,
+// i.e. after a handler left running by TimeoutMiddleware returns.
+func (t *Kafka) handle(c context.Context, msg *kafka.Message, done func()) {
 	defer func(s time.Time) {
 		t.timeCounter.Record(context.Background(), time.Since(s).Milliseconds())
 	}(time.Now())
 	t.counter.Add(context.Background(), 1)
 
-	c, tr := otel.Tracer("").Start(context.Background(), msg.TopicPartition.String())
+	c, tr := otel.Tracer("").Start(c, msg.TopicPartition.String())
 	defer tr.End()
 
 	ctx := KafkaCtxPool.Get().(*KafkaCtx)
-	defer KafkaCtxPool.Put(ctx)
 	ctx.Fill(msg)
+	ctx.finish = done
+	defer ctx.release()
 
 	route, _ := t.router.Find(*msg.TopicPartition.Topic)
 
@@ -212,3 +279,8 @@ func (t *Kafka) Handle(msg *kafka.Message) {
 		t.counterError.Add(context.Background(), 1)
 	}
 }
+
+// Group returns a route group sharing the topic prefix and the middlewares
+func (t *Kafka) Group(prefix string, middlewares ...string) *Group {
+	return t.router.Group(prefix, middlewares...)
+}
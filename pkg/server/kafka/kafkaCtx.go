@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"sync"
+	"sync/atomic"
 )
 
 type KafkaCtx struct {
@@ -11,6 +12,9 @@ type KafkaCtx struct {
 	response IResponse
 	values   map[string]interface{}
 	done     chan struct{}
+
+	refs   atomic.Int32 // the handle func and the handlers outliving it, the context is pooled at zero
+	finish func()       // called once the context is released
 }
 
 var KafkaCtxPool = sync.Pool{
@@ -24,11 +28,81 @@ var KafkaCtxPool = sync.Pool{
 }
 
 func (t *KafkaCtx) Fill(msg *kafka.Message) {
+	t.refs.Store(1)
+	t.finish = nil
 	t.request.(*kafkaRequest).Fill(msg)
 	t.response.Clear()
 	t.values = make(map[string]interface{}, 10)
 }
 
+// hold keeps the context out of KafkaCtxPool until the returned func is called
+func (t *KafkaCtx) hold() func() {
+	t.refs.Add(1)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(t.release)
+	}
+}
+
+// release drops a reference, the last one returns the context to KafkaCtxPool
+func (t *KafkaCtx) release() {
+	if t.refs.Add(-1) > 0 {
+		return
+	}
+
+	if t.finish != nil {
+		t.finish()
+		t.finish = nil
+	}
+
+	KafkaCtxPool.Put(t)
+}
+
+// fork returns a copy of the context for a handler run by TimeoutMiddleware. The request is shared,
+// the response and the user values are copied so the handler never touches what the server reads.
+func (t *KafkaCtx) fork() *KafkaCtx {
+	f := &KafkaCtx{
+		request:  t.request,
+		response: copyResponse(t.response),
+		values:   make(map[string]interface{}, len(t.values)),
+	}
+
+	for k, v := range t.values {
+		f.values[k] = v
+	}
+
+	return f
+}
+
+// join takes the response and the user values of a fork whose handler has returned
+func (t *KafkaCtx) join(f *KafkaCtx) {
+	t.response = f.response
+	t.values = f.values
+}
+
+func copyResponse(r IResponse) IResponse {
+	res := &KafkaResponse{
+		Body:       append([]byte(nil), r.GetBody()...),
+		StatusCode: r.GetStatus(),
+		headers:    &kafkaHeader{make(map[string][]string, len(r.Header().GetAsMap()))},
+		cookie:     &KafkaCookie{data: make(map[string]cookieItem)},
+	}
+
+	for k, v := range r.Header().GetAsMap() {
+		res.headers.(*kafkaHeader).data[k] = append([]string(nil), v...)
+	}
+
+	if c, ok := r.Cookie().(*KafkaCookie); ok {
+		for k, v := range c.data {
+			res.cookie.(*KafkaCookie).data[k] = v
+		}
+	}
+
+	return res
+}
+
 func (t *KafkaCtx) GetRequest() IRequest {
 	return t.request
 }
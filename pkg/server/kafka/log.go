@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// forwardLogs writes librdkafka logs to the default slog logger, which the engine
+// points at its logger chain. It returns when logs is closed.
+func forwardLogs(name string, logs chan kafka.LogEvent) {
+	for ev := range logs {
+		slog.Default().LogAttrs(context.Background(), kafkaLevel(ev.Level), "kafka: "+ev.Message,
+			slog.String("kafka", name),
+			slog.String("client", ev.Name),
+			slog.String("tag", ev.Tag),
+		)
+	}
+}
+
+// kafkaLevel maps a syslog level used by librdkafka to slog
+func kafkaLevel(level int) slog.Level {
+	switch {
+	case level <= 3:
+		return slog.LevelError
+	case level == 4:
+		return slog.LevelWarn
+	case level <= 6:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
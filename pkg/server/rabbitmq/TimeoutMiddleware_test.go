@@ -0,0 +1,264 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/drain"
+	"go.opentelemetry.io/otel"
+)
+
+// TestTimeoutMiddleware_Load mixes timed out and fast messages, it is meant to run with -race
+func TestTimeoutMiddleware_Load(t *testing.T) {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, map[string]interface{}{"timeout": "50ms", "status": 503}); err != nil {
+		t.Fatal(err)
+	}
+
+	route := timeout.Invoke(func(_ context.Context, ctx ICtx) {
+		id, _ := strconv.Atoi(ctx.GetRequest().GetHeader().Get("id"))
+
+		if id%2 == 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		ctx.PushUserValue("id", id)
+		ctx.GetResponse().SetBody([]byte(strconv.Itoa(id)))
+	})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			ctx := RabbitmqCtxPool.Get().(*RabbitmqCtx)
+			defer ctx.release()
+			ctx.Fill(&amqp.Delivery{Headers: amqp.Table{"id": strconv.Itoa(id)}})
+
+			route(context.Background(), ctx)
+
+			status := ctx.GetResponse().GetStatus()
+			body := string(ctx.GetResponse().GetBody())
+
+			if id%2 == 0 && (status != 503 || body != "") {
+				t.Errorf("%d: want timed out, got %d %q", id, status, body)
+			}
+
+			if id%2 == 1 && (status != 200 || body != strconv.Itoa(id)) {
+				t.Errorf("%d: response of another message %d %q", id, status, body)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// logBuffer collects the logs written by handlers outliving the message
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (t *logBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.buf.Write(p)
+}
+
+func (t *logBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.buf.String()
+}
+
+func TestTimeoutMiddleware_PanicAfterTimeout(t *testing.T) {
+	var logs logBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(prev)
+
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, map[string]interface{}{"timeout": "20ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	route := timeout.Invoke(func(c context.Context, _ ICtx) {
+		<-c.Done()
+		time.Sleep(20 * time.Millisecond)
+		panic("late boom")
+	})
+
+	ctx := RabbitmqCtxPool.Get().(*RabbitmqCtx)
+	defer ctx.release()
+	ctx.Fill(&amqp.Delivery{})
+
+	route(context.Background(), ctx)
+
+	if status := ctx.GetResponse().GetStatus(); status != 504 {
+		t.Fatalf("want 504, got %d", status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+
+	for !strings.Contains(logs.String(), "late boom") {
+		if time.Now().After(deadline) {
+			t.Fatalf("panic after the timeout not logged: %q", logs.String())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// ackRecorder stands in for the channel acknowledging deliveries
+type ackRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (t *ackRecorder) Ack(_ uint64, _ bool) error {
+	return t.record("ack")
+}
+
+func (t *ackRecorder) Nack(_ uint64, _ bool, _ bool) error {
+	return t.record("nack")
+}
+
+func (t *ackRecorder) Reject(_ uint64, _ bool) error {
+	return t.record("reject")
+}
+
+func (t *ackRecorder) record(call string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.calls = append(t.calls, call)
+
+	return nil
+}
+
+func (t *ackRecorder) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return strings.Join(t.calls, ",")
+}
+
+// newDrainingServer returns a server without a connection routing the queue through a timeout
+func newDrainingServer(t *testing.T, handler RouteFunc) *Rabbitmq {
+	timeout := NewTimeoutMiddleware("timeout").(*TimeoutMiddleware)
+
+	if err := timeout.Init(nil, map[string]interface{}{"timeout": "20ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("rabbitmq")
+	s.config.Queue = "queue"
+	s.drain = drain.New()
+	s.router = NewRouter(map[string]IMiddleware{"timeout": timeout})
+	s.md = func(routeFunc RouteFunc) RouteFunc { return routeFunc }
+	s.counter, _ = otel.Meter("").Int64Counter("test.count")
+	s.counterError, _ = otel.Meter("").Int64Counter("test.count_error")
+	s.timeCounter, _ = otel.Meter("").Int64Histogram("test.time")
+
+	if err := s.PushRoute("queue", handler, []string{"timeout"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// TestRabbitmq_DrainWaitsForTimedOutHandler stops the server while a timed out handler is still running:
+// the message stays in flight and is acked only once the handler returns
+func TestRabbitmq_DrainWaitsForTimedOutHandler(t *testing.T) {
+	finish := make(chan struct{})
+
+	s := newDrainingServer(t, func(_ context.Context, _ ICtx) {
+		<-finish
+	})
+
+	acks := &ackRecorder{}
+	s.Handle(amqp.Delivery{Acknowledger: acks})
+
+	if n := s.drain.InFlight(); n != 1 {
+		t.Fatalf("timed out message must stay in flight, got %d", n)
+	}
+
+	if calls := acks.String(); calls != "" {
+		t.Fatalf("message settled before the handler returned: %q", calls)
+	}
+
+	stopped := make(chan int64, 1)
+
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stopped <- s.drain.Wait(c)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("drain returned while the handler is running")
+
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(finish)
+
+	if aborted := <-stopped; aborted != 0 {
+		t.Fatalf("want no aborted messages, got %d", aborted)
+	}
+
+	if calls := acks.String(); calls != "ack" {
+		t.Fatalf("want a single ack, got %q", calls)
+	}
+}
+
+// TestRabbitmq_DrainRequeuesAbortedTimedOutHandler lets the drain deadline pass while a timed out handler runs
+func TestRabbitmq_DrainRequeuesAbortedTimedOutHandler(t *testing.T) {
+	finish := make(chan struct{})
+
+	s := newDrainingServer(t, func(_ context.Context, _ ICtx) {
+		<-finish
+	})
+
+	acks := &ackRecorder{}
+	s.Handle(amqp.Delivery{Acknowledger: acks})
+
+	c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if aborted := s.drain.Wait(c); aborted != 1 {
+		t.Fatalf("want 1 aborted message, got %d", aborted)
+	}
+
+	close(finish)
+
+	deadline := time.Now().Add(time.Second)
+
+	for acks.String() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("message not settled after the handler returned")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if calls := acks.String(); calls != "nack" {
+		t.Fatalf("want the message requeued, got %q", calls)
+	}
+}
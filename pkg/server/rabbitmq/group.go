@@ -0,0 +1,45 @@
+package rabbitmq
+
+// Group registers routes whose queue names share a common prefix, e.g. "billing.", with a shared
+// middleware chain. The chain runs after base_middleware and before the middlewares of the route itself.
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []string
+}
+
+// Group returns a route group for prefix, middlewares are names registered in the server config
+func (t *Router) Group(prefix string, middlewares ...string) *Group {
+	return &Group{
+		router:      t,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// Group returns a nested group, its prefix and middlewares are appended to the parent ones
+func (t *Group) Group(prefix string, middlewares ...string) *Group {
+	md := make([]string, 0, len(t.middlewares)+len(middlewares))
+	md = append(md, t.middlewares...)
+	md = append(md, middlewares...)
+
+	return &Group{
+		router:      t.router,
+		prefix:      t.prefix + prefix,
+		middlewares: md,
+	}
+}
+
+// PushRoute adds a route for the queue name appended to the group prefix
+func (t *Group) PushRoute(path string, handler RouteFunc, middlewares []string) error {
+	md := make([]string, 0, len(t.middlewares)+len(middlewares))
+	md = append(md, t.middlewares...)
+	md = append(md, middlewares...)
+
+	return t.router.PushRoute(t.prefix+path, handler, md)
+}
+
+// Prefix returns the full queue prefix of the group
+func (t *Group) Prefix() string {
+	return t.prefix
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gitlab.com/devpro_studio/Paranoia/paranoia/drain"
 	"gitlab.com/devpro_studio/go_utils/decode"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
-	"sync"
+	"log/slog"
 	"time"
 )
 
@@ -17,16 +18,19 @@ type Rabbitmq struct {
 
 	config Config
 
-	router *Router
-	conn   *amqp.Connection
-	ch     *amqp.Channel
-	done   chan interface{}
-	w      sync.WaitGroup
-	md     func(RouteFunc) RouteFunc
-
-	counter      metric.Int64Counter
-	counterError metric.Int64Counter
-	timeCounter  metric.Int64Histogram
+	router  *Router
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	done    chan interface{}
+	stopped chan interface{}
+	started bool
+	drain   *drain.Drain
+	md      func(RouteFunc) RouteFunc
+
+	counter        metric.Int64Counter
+	counterError   metric.Int64Counter
+	timeCounter    metric.Int64Histogram
+	abortedCounter metric.Int64Counter
 }
 
 type Config struct {
@@ -35,6 +39,8 @@ type Config struct {
 	ConsumerName      string   `yaml:"consumer_name"`
 	LimitMessageCount int64    `yaml:"limit_message_count"`
 	BaseMiddleware    []string `yaml:"base_middleware"`
+
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // in-flight messages are canceled and requeued after it on stop, default 10s
 }
 
 func New(name string) *Rabbitmq {
@@ -72,7 +78,17 @@ func (t *Rabbitmq) Init(cfg map[string]interface{}) error {
 		return errors.New("queue is required")
 	}
 
+	if t.config.LimitMessageCount <= 0 {
+		t.config.LimitMessageCount = 100
+	}
+
+	if t.config.DrainTimeout <= 0 {
+		t.config.DrainTimeout = 10 * time.Second
+	}
+
 	t.done = make(chan interface{})
+	t.stopped = make(chan interface{})
+	t.drain = drain.New()
 
 	t.router = NewRouter(middlewares)
 
@@ -121,70 +137,109 @@ func (t *Rabbitmq) Init(cfg map[string]interface{}) error {
 	t.counter, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".count")
 	t.counterError, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".count_error")
 	t.timeCounter, _ = otel.Meter("").Int64Histogram("server_kafka." + t.name + ".time")
+	t.abortedCounter, _ = otel.Meter("").Int64Counter("server_kafka." + t.name + ".aborted")
 
 	return nil
 }
 
 func (t *Rabbitmq) Start() error {
+	msgs, err := t.ch.Consume(
+		t.config.Queue,        // очередь
+		t.config.ConsumerName, // consumer
+		false,                 // auto-ack
+		false,                 // exclusive
+		false,                 // no-local
+		false,                 // no-wait
+		nil,                   // args
+	)
+
+	if err != nil {
+		return err
+	}
+
+	t.started = true
+
 	go func() {
-		limited := make(chan interface{}, t.config.LimitMessageCount)
-		defer close(limited)
-
-		msgs, err := t.ch.Consume(
-			t.config.Queue,        // очередь
-			t.config.ConsumerName, // consumer
-			false,                 // auto-ack
-			false,                 // exclusive
-			false,                 // no-local
-			false,                 // no-wait
-			nil,                   // args
-		)
+		defer close(t.stopped)
 
-		if err != nil {
-			return
-		}
+		limited := make(chan interface{}, t.config.LimitMessageCount)
 
-	forLoop:
 		for {
 			select {
 			case <-t.done:
-				break forLoop
+				return
+
+			case limited <- nil:
+			}
+
+			var msg amqp.Delivery
+			var ok bool
+
+			select {
+			case <-t.done:
+				return
 
-			case msg := <-msgs:
-				if msg.Body == nil {
-					continue
+			case msg, ok = <-msgs:
+				if !ok {
+					// the consumer is canceled or the channel closed
+					return
 				}
+			}
 
-				t.w.Add(1)
-				limited <- nil
+			c, done, ok := t.drain.Begin(context.Background())
 
-				go func(delivery amqp.Delivery) {
-					defer t.w.Done()
-					t.Handle(delivery)
-					<-limited
-				}(msg)
+			if !ok {
+				_ = msg.Nack(false, true)
+				<-limited
+				return
 			}
-		}
 
-		t.w.Wait()
+			go func(delivery amqp.Delivery) {
+				t.handle(c, delivery, done)
+				<-limited
+			}(msg)
+		}
 	}()
 
 	return nil
 }
 
+// Stop drains the server: readiness fails, the consumer is canceled and in-flight messages are waited for
+// up to drain_timeout, then canceled and requeued
 func (t *Rabbitmq) Stop() error {
+	t.drain.SetNotReady()
+
+	// prefetched messages not yet handled are requeued when the channel closes
+	_ = t.ch.Cancel(t.config.ConsumerName, false)
 	close(t.done)
-	t.w.Wait()
+
+	if t.started {
+		<-t.stopped
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), t.config.DrainTimeout)
+	defer cancel()
+
+	if aborted := t.drain.Wait(c); aborted > 0 {
+		t.abortedCounter.Add(context.Background(), aborted)
+		slog.Default().Warn("rabbitmq: messages aborted on stop", slog.String("rabbitmq", t.name), slog.Int64("aborted", aborted))
+	}
 
 	if err := t.ch.Close(); err != nil {
 		return err
 	}
 
-	err := t.conn.Close()
+	return t.conn.Close()
+}
 
-	time.Sleep(time.Second)
+// Ready is false once the server starts draining
+func (t *Rabbitmq) Ready() bool {
+	return t.drain != nil && t.drain.Ready()
+}
 
-	return err
+// Aborted returns the number of messages canceled by the drain deadline on stop
+func (t *Rabbitmq) Aborted() int64 {
+	return t.drain.AbortedCount()
 }
 
 func (t *Rabbitmq) Name() string {
@@ -195,25 +250,51 @@ func (t *Rabbitmq) Type() string {
 	return "server"
 }
 
-func (t *Rabbitmq) PushRoute(path string, handler RouteFunc, middlewares []string) {
-	t.router.PushRoute(path, handler, middlewares)
+func (t *Rabbitmq) PushRoute(path string, handler RouteFunc, middlewares []string) error {
+	return t.router.PushRoute(path, handler, middlewares)
 }
 
 func (t *Rabbitmq) Handle(msg amqp.Delivery) {
+	c, done, ok := t.drain.Begin(context.Background())
+
+	if !ok {
+		_ = msg.Nack(false, true)
+		return
+	}
+
+	t.handle(c, msg, done)
+}
+
+// handle runs the route of msg. The message is acked and done is called once the context is released,
+// i.e. after a handler left running by TimeoutMiddleware returns.
+func (t *Rabbitmq) handle(c context.Context, msg amqp.Delivery, done func()) {
 	defer func(s time.Time) {
 		t.timeCounter.Record(context.Background(), time.Since(s).Milliseconds())
 	}(time.Now())
 	t.counter.Add(context.Background(), 1)
 
 	ctx := RabbitmqCtxPool.Get().(*RabbitmqCtx)
-	defer RabbitmqCtxPool.Put(ctx)
 	ctx.Fill(&msg)
 
+	ctx.finish = func() {
+		defer done()
+
+		if t.drain.Aborted(c) {
+			// another consumer handles the message again
+			_ = msg.Nack(false, true)
+			return
+		}
+
+		// Подтверждение сообщения
+		_ = msg.Ack(false)
+	}
+	defer ctx.release()
+
 	h := make(map[string]string, len(msg.Headers))
 	for k, v := range msg.Headers {
 		h[k] = v.(string)
 	}
-	consumerCtx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(h))
+	consumerCtx := otel.GetTextMapPropagator().Extract(c, propagation.MapCarrier(h))
 	consumerCtx, span := otel.Tracer("").Start(consumerCtx, t.config.Queue)
 	defer span.End()
 
@@ -228,7 +309,9 @@ func (t *Rabbitmq) Handle(msg amqp.Delivery) {
 	if ctx.GetResponse().GetStatus() >= 400 {
 		t.counterError.Add(context.Background(), 1)
 	}
+}
 
-	// Подтверждение сообщения
-	msg.Ack(false)
+// Group returns a route group sharing the queue prefix and the middlewares
+func (t *Rabbitmq) Group(prefix string, middlewares ...string) *Group {
+	return t.router.Group(prefix, middlewares...)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"sync"
+	"sync/atomic"
 )
 
 type RabbitmqCtx struct {
@@ -11,6 +12,9 @@ type RabbitmqCtx struct {
 	response IResponse
 	values   map[string]interface{}
 	done     chan struct{}
+
+	refs   atomic.Int32 // the handle func and the handlers outliving it, the context is pooled at zero
+	finish func()       // called once the context is released
 }
 
 var RabbitmqCtxPool = sync.Pool{
@@ -24,11 +28,81 @@ var RabbitmqCtxPool = sync.Pool{
 }
 
 func (t *RabbitmqCtx) Fill(msg *amqp.Delivery) {
+	t.refs.Store(1)
+	t.finish = nil
 	t.request.(*rabbitmqRequest).Fill(msg)
 	t.response.Clear()
 	t.values = make(map[string]interface{}, 10)
 }
 
+// hold keeps the context out of RabbitmqCtxPool until the returned func is called
+func (t *RabbitmqCtx) hold() func() {
+	t.refs.Add(1)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(t.release)
+	}
+}
+
+// release drops a reference, the last one returns the context to RabbitmqCtxPool
+func (t *RabbitmqCtx) release() {
+	if t.refs.Add(-1) > 0 {
+		return
+	}
+
+	if t.finish != nil {
+		t.finish()
+		t.finish = nil
+	}
+
+	RabbitmqCtxPool.Put(t)
+}
+
+// fork returns a copy of the context for a handler run by TimeoutMiddleware. The request is shared,
+// the response and the user values are copied so the handler never touches what the server reads.
+func (t *RabbitmqCtx) fork() *RabbitmqCtx {
+	f := &RabbitmqCtx{
+		request:  t.request,
+		response: copyResponse(t.response),
+		values:   make(map[string]interface{}, len(t.values)),
+	}
+
+	for k, v := range t.values {
+		f.values[k] = v
+	}
+
+	return f
+}
+
+// join takes the response and the user values of a fork whose handler has returned
+func (t *RabbitmqCtx) join(f *RabbitmqCtx) {
+	t.response = f.response
+	t.values = f.values
+}
+
+func copyResponse(r IResponse) IResponse {
+	res := &RabbitmqResponse{
+		Body:       append([]byte(nil), r.GetBody()...),
+		StatusCode: r.GetStatus(),
+		headers:    &rabbitmqHeader{make(map[string][]string, len(r.Header().GetAsMap()))},
+		cookie:     &RabbitmqCookie{data: make(map[string]cookieItem)},
+	}
+
+	for k, v := range r.Header().GetAsMap() {
+		res.headers.(*rabbitmqHeader).data[k] = append([]string(nil), v...)
+	}
+
+	if c, ok := r.Cookie().(*RabbitmqCookie); ok {
+		for k, v := range c.data {
+			res.cookie.(*RabbitmqCookie).data[k] = v
+		}
+	}
+
+	return res
+}
+
 func (t *RabbitmqCtx) GetRequest() IRequest {
 	return t.request
 }
@@ -190,8 +190,9 @@ func (t *Router) HandlerFromList(middlewares []IMiddleware) func(routeFunc Route
 			return next
 		}
 
-		handler := middlewares[len(middlewares)-1].Invoke(t.HandlerFromList(middlewares[:len(middlewares)-1])(next))
-		for i := len(middlewares) - 2; i >= 0; i-- {
+		// the first middleware is the outermost one
+		handler := next
+		for i := len(middlewares) - 1; i >= 0; i-- {
 			handler = middlewares[i].Invoke(handler)
 		}
 		return handler
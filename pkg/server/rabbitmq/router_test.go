@@ -0,0 +1,41 @@
+package rabbitmq
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// traceMiddleware records entering and leaving the handler it wraps
+type traceMiddleware struct {
+	name  string
+	calls *[]string
+}
+
+func (t *traceMiddleware) Invoke(next RouteFunc) RouteFunc {
+	return func(c context.Context, ctx ICtx) {
+		*t.calls = append(*t.calls, t.name+">")
+		next(c, ctx)
+		*t.calls = append(*t.calls, "<"+t.name)
+	}
+}
+
+func TestRouter_HandlerFromList(t *testing.T) {
+	var calls []string
+
+	r := NewRouter(map[string]IMiddleware{})
+	md := r.HandlerFromList([]IMiddleware{
+		&traceMiddleware{name: "a", calls: &calls},
+		&traceMiddleware{name: "b", calls: &calls},
+		&traceMiddleware{name: "c", calls: &calls},
+	})
+
+	md(func(_ context.Context, _ ICtx) {
+		calls = append(calls, "handler")
+	})(context.Background(), nil)
+
+	// each middleware runs once, the first one is the outermost
+	if got, want := strings.Join(calls, " "), "a> b> c> handler <c <b <a"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}